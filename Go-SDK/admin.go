@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRecentErrors bounds the error history kept for the admin endpoint
+const maxRecentErrors = 50
+
+// ErrorRecord is an error observed by the client
+type ErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// errorLog keeps the most recent errors of a client
+type errorLog struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+}
+
+func (l *errorLog) record(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, ErrorRecord{Time: time.Now(), Message: err.Error()})
+	if len(l.records) > maxRecentErrors {
+		l.records = l.records[len(l.records)-maxRecentErrors:]
+	}
+}
+
+func (l *errorLog) list() []ErrorRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ErrorRecord(nil), l.records...)
+}
+
+// AdminStatus is the JSON document served by the admin endpoint
+type AdminStatus struct {
+	AppName         string             `json:"app_name"`
+	BaseURL         string             `json:"base_url"`
+	Operators       []OperatorSnapshot `json:"operators"`
+	Index           int                `json:"index"`
+	ChainingHash    string             `json:"chaining_hash"`
+	LatestFinalized int                `json:"latest_finalized"`
+	Lag             int                `json:"lag"`
+	RecentErrors    []ErrorRecord      `json:"recent_errors"`
+	Backends        Backends           `json:"backends"`
+	// Breakers are the states of the endpoints balanced reads and endpoint
+	// pools skip after repeated failures, empty without either
+	Breakers []BreakerState `json:"breakers,omitempty"`
+}
+
+// BreakerState is the circuit breaker of one endpoint. An open breaker
+// skips the endpoint until OpenUntil; a closed one counts the failures in a
+// row, three of which open it.
+type BreakerState struct {
+	// Source is "balancer" for balanced reads and "pool" for endpoint pools
+	Source    string    `json:"source"`
+	Endpoint  string    `json:"endpoint"`
+	Open      bool      `json:"open"`
+	OpenUntil time.Time `json:"open_until,omitempty"`
+	Failures  int       `json:"failures"`
+}
+
+// breakerState reports the breaker of endpoint from its failures and cooldown
+func breakerState(source, endpoint string, failures map[string]int, downUntil map[string]time.Time, now time.Time) BreakerState {
+	state := BreakerState{Source: source, Endpoint: endpoint, Failures: failures[endpoint]}
+	if until := downUntil[endpoint]; now.Before(until) {
+		state.Open, state.OpenUntil = true, until
+	}
+	return state
+}
+
+// AdminStatus reports the current internals of the client
+func (z *Zellular) AdminStatus() AdminStatus {
+	index, chainingHash := z.progress.get()
+	latest := z.progress.latest()
+	status := AdminStatus{
+		AppName:         z.AppName,
+		BaseURL:         z.base(),
+		Index:           index,
+		ChainingHash:    chainingHash,
+		LatestFinalized: latest,
+		Backends:        ActiveBackends(),
+		RecentErrors:    z.errors.list(),
+	}
+	if latest > index {
+		status.Lag = latest - index
+	}
+	for _, operator := range z.operators() {
+		snapshot := OperatorSnapshot{
+			ID:         operator.ID,
+			OperatorID: operator.OperatorID,
+			Socket:     operator.Socket,
+			Stake:      operator.Stake,
+		}
+		if z.resolver != nil && operator.Socket != "" {
+			for _, addr := range z.resolver.Cached(socketHost(operator.Socket)) {
+				snapshot.Addresses = append(snapshot.Addresses, addr.String())
+			}
+		}
+		status.Operators = append(status.Operators, snapshot)
+	}
+	sort.Slice(status.Operators, func(i, j int) bool {
+		return status.Operators[i].ID < status.Operators[j].ID
+	})
+	now := time.Now()
+	if z.balancer != nil {
+		status.Breakers = append(status.Breakers, z.balancer.breakers(z.operators(), now)...)
+	}
+	if z.pools != nil {
+		status.Breakers = append(status.Breakers, z.pools.breakers(now)...)
+	}
+	return status
+}
+
+// AdminHandler returns a handler serving AdminStatus as JSON under
+// /debug/zellular, the captured HTTP exchanges under /debug/zellular/http
+// with WithHTTPCapture, plus profiling endpoints when WithAdminPprof is set
+func (z *Zellular) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/zellular", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(z.AdminStatus())
+	})
+	if z.transport.capture != nil {
+		mux.HandleFunc("/debug/zellular/http", z.serveExchanges)
+	}
+	if z.adminPprof {
+		mountPprof(mux)
+		mux.HandleFunc("/debug/zellular/runtime", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ReadRuntimeStats())
+		})
+	}
+	return mux
+}
+
+// ServeAdmin runs the admin server on addr, it should only be bound to a private interface
+func (z *Zellular) ServeAdmin(addr string) error {
+	return http.ListenAndServe(addr, z.AdminHandler())
+}
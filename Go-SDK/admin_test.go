@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAdminStatusReportsBreakers(t *testing.T) {
+	z := newZellular("app", "", 67, WithEndpointPools(
+		EndpointPool{Name: "primary", Endpoints: []string{"http://a", "http://b"}},
+	))
+	for i := 0; i < poolMaxFailures; i++ {
+		z.observeEndpoint("http://a", errors.New("unreachable"))
+	}
+	z.observeEndpoint("http://b", errors.New("unreachable"))
+
+	breakers := z.AdminStatus().Breakers
+	if len(breakers) != 2 {
+		t.Fatalf("breakers = %+v", breakers)
+	}
+	if a := breakers[0]; a.Source != "pool" || a.Endpoint != "http://a" || !a.Open || a.OpenUntil.IsZero() {
+		t.Fatalf("breaker of a = %+v", a)
+	}
+	if b := breakers[1]; b.Open || b.Failures != 1 {
+		t.Fatalf("breaker of b = %+v", b)
+	}
+}
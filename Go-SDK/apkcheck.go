@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// apkCheckTimeout bounds the RPC call of the on-chain APK check
+const apkCheckTimeout = 10 * time.Second
+
+// onChainAPK is the BLS APK registry the operator set is checked against
+type onChainAPK struct {
+	eth      *EthClient
+	registry string
+	quorum   uint8
+}
+
+// WithOnChainAPKCheck rejects any operator set whose aggregated G1 public
+// key differs from the one the BLS APK registry contract at registry holds
+// for quorum. The registry only stores the G1 aggregate, so each operator's
+// G2 key, which signatures are checked with, is also paired with its G1 key
+// and operatorIds are checked against the G1 keys; a subgraph substituting
+// either key is rejected.
+func WithOnChainAPKCheck(eth *EthClient, registry string, quorum uint8) Option {
+	return func(z *Zellular) {
+		z.apkCheck = &onChainAPK{eth: eth, registry: registry, quorum: quorum}
+	}
+}
+
+// WithEthClient gives the client an Ethereum RPC endpoint, with which the
+// registry of a WithNetwork profile is checked as by WithOnChainAPKCheck
+func WithEthClient(eth *EthClient) Option {
+	return func(z *Zellular) {
+		z.eth = eth
+		if z.apkCheck != nil && z.apkCheck.eth == nil {
+			z.apkCheck.eth = eth
+		}
+	}
+}
+
+// checkOnChainAPK fails closed if operators do not add up to the on-chain APK
+func (z *Zellular) checkOnChainAPK(operators map[string]Operator) error {
+	// a profile's registry is only checked once an EthClient is given
+	if z.apkCheck == nil || z.apkCheck.eth == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apkCheckTimeout)
+	defer cancel()
+	onChain, err := z.apkCheck.read(ctx)
+	if err != nil {
+		return fmt.Errorf("zellular: reading the on-chain APK: %w", err)
+	}
+	local, err := aggregateG1(operators)
+	if err != nil {
+		return err
+	}
+	if !local.Equal(&onChain) {
+		return fmt.Errorf("zellular: operator set does not match the APK of registry %s", z.apkCheck.registry)
+	}
+	return nil
+}
+
+// read calls getApk(uint8) on the registry
+func (a *onChainAPK) read(ctx context.Context) (bn254.G1Affine, error) {
+	var apk bn254.G1Affine
+	call := append(abiSelector("getApk(uint8)"), abiWord([]byte{a.quorum})...)
+	data, err := a.eth.Call(ctx, a.registry, call)
+	if err != nil {
+		return apk, err
+	}
+	if len(data) < 64 {
+		return apk, fmt.Errorf("zellular: short getApk result")
+	}
+	apk.X.SetBytes(data[:32])
+	apk.Y.SetBytes(data[32:64])
+	return apk, nil
+}
+
+// aggregateG1 sums the operators' G1 public keys, checking each against its
+// operatorId and G2 public key
+func aggregateG1(operators map[string]Operator) (bn254.G1Affine, error) {
+	var sum bn254.G1Jac
+	for id, operator := range operators {
+		if len(operator.PubkeyG1_X) != 1 || len(operator.PubkeyG1_Y) != 1 {
+			return bn254.G1Affine{}, fmt.Errorf("zellular: operator %s has a malformed G1 public key", id)
+		}
+		computed, err := ComputeOperatorID(operator.PubkeyG1_X[0], operator.PubkeyG1_Y[0])
+		if err != nil {
+			return bn254.G1Affine{}, err
+		}
+		if expected, err := NormalizeOperatorID(operator.OperatorID); err != nil || computed != expected {
+			return bn254.G1Affine{}, fmt.Errorf("zellular: operatorId of %s does not match its G1 public key", id)
+		}
+
+		var key bn254.G1Affine
+		if _, err := key.X.SetString(operator.PubkeyG1_X[0]); err != nil {
+			return bn254.G1Affine{}, err
+		}
+		if _, err := key.Y.SetString(operator.PubkeyG1_Y[0]); err != nil {
+			return bn254.G1Affine{}, err
+		}
+		if !key.IsOnCurve() {
+			return bn254.G1Affine{}, fmt.Errorf("zellular: G1 public key of %s is not on the curve", id)
+		}
+		if paired, err := pairedKeys(&key, &operator.PublicKeyG2); err != nil || !paired {
+			return bn254.G1Affine{}, fmt.Errorf("zellular: G2 public key of %s does not match its G1 public key", id)
+		}
+		sum.AddMixed(&key)
+	}
+	var apk bn254.G1Affine
+	apk.FromJacobian(&sum)
+	return apk, nil
+}
+
+// pairedKeys reports whether g1Key and g2Key share their secret, that is
+// e(g1Key, g2) == e(g1, g2Key), as the registry checks at registration
+func pairedKeys(g1Key *bn254.G1Affine, g2Key *bn254.G2Affine) (bool, error) {
+	_, _, g1, g2 := bn254.Generators()
+	var negated bn254.G1Affine
+	negated.Neg(&g1)
+	return bn254.PairingCheck(
+		[]bn254.G1Affine{*g1Key, negated},
+		[]bn254.G2Affine{g2, *g2Key},
+	)
+}
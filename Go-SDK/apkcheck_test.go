@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// keyedOperator is registryOperator with the G1 key and operatorId of the same secret
+func keyedOperator(id string, secret *big.Int) Operator {
+	operator := registryOperator(id, secret)
+	_, _, g1, _ := bn254.Generators()
+	var key bn254.G1Affine
+	key.ScalarMultiplication(&g1, secret)
+	operator.PubkeyG1_X = []string{key.X.String()}
+	operator.PubkeyG1_Y = []string{key.Y.String()}
+	operator.OperatorID, _ = ComputeOperatorID(key.X.String(), key.Y.String())
+	return operator
+}
+
+// apkNode answers every eth_call with apk
+func apkNode(apk bn254.G1Affine) *EthClient {
+	x, y := apk.X.Bytes(), apk.Y.Bytes()
+	result := "0x" + hex.EncodeToString(x[:]) + hex.EncodeToString(y[:])
+	tr := newTransport()
+	tr.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "result": %q}`, result)
+		return &http.Response{StatusCode: 200, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+	return &EthClient{URL: "http://eth", transport: tr}
+}
+
+func TestOnChainAPKCheckRejectsSwappedG2Key(t *testing.T) {
+	first := keyedOperator("0x0000000000000000000000000000000000000001", testSecret)
+	second := keyedOperator("0x0000000000000000000000000000000000000002", big.NewInt(7))
+	operators := map[string]Operator{first.ID: first, second.ID: second}
+	if err := decodePublicKeys(operators); err != nil {
+		t.Fatal(err)
+	}
+	apk, err := aggregateG1(operators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eth := apkNode(apk)
+	z := newZellular("app", "http://localhost:6001", 67, WithOnChainAPKCheck(eth, "0x0000000000000000000000000000000000000003", 0))
+	if err := z.setOperators(operators); err != nil {
+		t.Fatal(err)
+	}
+
+	// the G1 keys and so the APK are unchanged, only the signing key differs
+	swapped := registryOperator(second.ID, big.NewInt(11))
+	second.PubkeyG2_X, second.PubkeyG2_Y = swapped.PubkeyG2_X, swapped.PubkeyG2_Y
+	operators = map[string]Operator{first.ID: first, second.ID: second}
+	err = z.setOperators(operators)
+	if err == nil || !strings.Contains(err.Error(), "does not match its G1 public key") {
+		t.Fatalf("swapped G2 key was accepted: %v", err)
+	}
+}
+
+func TestNetworkProfileChecksAPKWithEthClient(t *testing.T) {
+	operator := keyedOperator("0x0000000000000000000000000000000000000001", testSecret)
+	operators := map[string]Operator{operator.ID: operator}
+	if err := decodePublicKeys(operators); err != nil {
+		t.Fatal(err)
+	}
+	RegisterNetwork(NetworkProfile{Name: "apk-test", APKRegistry: "0x0000000000000000000000000000000000000003", ThresholdPercent: 67})
+
+	// without an EthClient the profile's registry is not checked
+	z := newZellular("app", "http://localhost:6001", 0, WithNetwork("apk-test"))
+	if err := z.setOperators(operators); err != nil {
+		t.Fatal(err)
+	}
+
+	// an APK other than the operators' is rejected in either option order
+	_, _, g1, _ := bn254.Generators()
+	for _, opts := range [][]Option{
+		{WithNetwork("apk-test"), WithEthClient(apkNode(g1))},
+		{WithEthClient(apkNode(g1)), WithNetwork("apk-test")},
+	} {
+		z := newZellular("app", "http://localhost:6001", 0, opts...)
+		if err := z.setOperators(operators); err == nil {
+			t.Fatal("operator set not matching the registry APK was accepted")
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ObjectStore is the minimal object storage the archiver needs. S3, GCS or
+// any other bucket can be plugged in by implementing it; DirStore is the
+// local filesystem implementation. Get must return an error matching
+// fs.ErrNotExist for missing keys.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// DirStore stores objects as files below a root directory
+type DirStore struct {
+	Root string
+}
+
+// Put writes an object, replacing it atomically
+func (d DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(d.Root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Get reads an object
+func (d DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(d.Root, filepath.FromSlash(key)))
+}
+
+// ArchiveManifest lists the archived segments of an app in index order
+type ArchiveManifest struct {
+	AppName  string           `json:"app_name"`
+	Segments []ArchiveSegment `json:"segments"`
+}
+
+// ArchiveSegment describes one compressed range of batches. Previous and
+// ChainingHash are the chaining hashes before the first and after the last
+// batch of the segment, and SHA256 the digest of the compressed object.
+type ArchiveSegment struct {
+	Key          string `json:"key"`
+	First        int    `json:"first"`
+	Last         int    `json:"last"`
+	Previous     string `json:"previous_chaining_hash,omitempty"`
+	ChainingHash string `json:"chaining_hash"`
+	SHA256       string `json:"sha256"`
+}
+
+// Archiver uploads finalized batch ranges to object storage
+type Archiver struct {
+	// SegmentSize is the number of batches per compressed segment
+	SegmentSize int
+
+	z     *Zellular
+	store ObjectStore
+}
+
+// NewArchiver creates an archiver for z's app writing to store
+func NewArchiver(z *Zellular, store ObjectStore) *Archiver {
+	return &Archiver{SegmentSize: 1000, z: z, store: store}
+}
+
+func manifestKey(appName string) string {
+	return appName + "/manifest.json"
+}
+
+// Archive uploads the batches after index after up to and including index to.
+// chainingHash is the chaining hash at after ("" when starting from 0).
+// The manifest is rewritten after every segment so an interrupted run can
+// resume. Batches already archived are not uploaded again: the run carries
+// on after the last archived batch, and fails if it would leave a gap.
+func (a *Archiver) Archive(ctx context.Context, after, to int, chainingHash string) error {
+	manifest, err := readManifest(ctx, a.store, a.z.AppName)
+	if err != nil {
+		return err
+	}
+	if n := len(manifest.Segments); n > 0 {
+		tail := manifest.Segments[n-1]
+		switch {
+		case after > tail.Last:
+			return fmt.Errorf("zellular: archive of %s ends at batch %d, cannot archive from batch %d", a.z.AppName, tail.Last, after+1)
+		case after == tail.Last && chainingHash != tail.ChainingHash:
+			return fmt.Errorf("zellular: chaining hash at batch %d does not match the archive of %s", after, a.z.AppName)
+		}
+		after, chainingHash = tail.Last, tail.ChainingHash
+	}
+
+	stream := a.z.StreamFrom(after, chainingHash)
+	previous := chainingHash
+	var segment []walEntry
+	for index := after + 1; index <= to; index++ {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+		chainingHash = a.z.chain(chainingHash, batch.Payload)
+		segment = append(segment, walEntry{
+			Index:   batch.Index,
+			Hash:    a.z.batchHash(batch.Payload),
+			Payload: batch.Payload,
+			Proof:   batch.Proof,
+		})
+
+		if len(segment) == a.SegmentSize || index == to {
+			info, err := a.upload(ctx, segment, previous, chainingHash)
+			if err != nil {
+				return err
+			}
+			previous = chainingHash
+			manifest.Segments = append(manifest.Segments, info)
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				return err
+			}
+			if err := a.store.Put(ctx, manifestKey(a.z.AppName), data); err != nil {
+				return err
+			}
+			segment = segment[:0]
+		}
+	}
+	return nil
+}
+
+// upload compresses and stores one segment
+func (a *Archiver) upload(ctx context.Context, segment []walEntry, previous, chainingHash string) (ArchiveSegment, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(segment); err != nil {
+		return ArchiveSegment{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return ArchiveSegment{}, err
+	}
+
+	first, last := segment[0].Index, segment[len(segment)-1].Index
+	sum := sha256.Sum256(buf.Bytes())
+	info := ArchiveSegment{
+		Key:          fmt.Sprintf("%s/segment-%012d-%012d.json.gz", a.z.AppName, first, last),
+		First:        first,
+		Last:         last,
+		Previous:     previous,
+		ChainingHash: chainingHash,
+		SHA256:       hex.EncodeToString(sum[:]),
+	}
+	return info, a.store.Put(ctx, info.Key, buf.Bytes())
+}
+
+// readManifest loads the manifest of an app, returning an empty one if none exists yet
+func readManifest(ctx context.Context, store ObjectStore, appName string) (*ArchiveManifest, error) {
+	data, err := store.Get(ctx, manifestKey(appName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ArchiveManifest{AppName: appName}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest ArchiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// ArchiveReader serves historical batch ranges from an archive instead of live nodes
+type ArchiveReader struct {
+	z     *Zellular
+	store ObjectStore
+}
+
+// NewArchiveReader creates a reader for the archive of z's app in store,
+// hashing batches the way z does
+func NewArchiveReader(z *Zellular, store ObjectStore) *ArchiveReader {
+	return &ArchiveReader{z: z, store: store}
+}
+
+// Range calls fn for every archived batch with index in [from, to]. Segment
+// digests, batch hashes and chaining hashes are checked before the contents
+// of a segment are used, and the manifest must list consecutive segments,
+// each starting from the chaining hash the previous one ended with.
+func (r *ArchiveReader) Range(ctx context.Context, from, to int, fn func(Batch) error) error {
+	appName := r.z.AppName
+	manifest, err := readManifest(ctx, r.store, appName)
+	if err != nil {
+		return err
+	}
+
+	next := from
+	for i, info := range manifest.Segments {
+		if i > 0 {
+			previous := manifest.Segments[i-1]
+			if info.First != previous.Last+1 || info.Previous != previous.ChainingHash {
+				return fmt.Errorf("zellular: archive segment %s does not follow %s", info.Key, previous.Key)
+			}
+		}
+		if info.Last < next || info.First > to {
+			continue
+		}
+		entries, err := r.readSegment(ctx, info)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Index < next {
+				continue
+			}
+			if entry.Index > to {
+				break
+			}
+			if entry.Index != next {
+				return fmt.Errorf("zellular: archive of %s has no batch %d", appName, next)
+			}
+			if err := fn(Batch{Index: entry.Index, Payload: entry.Payload, Proof: entry.Proof}); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+	if next <= to {
+		return fmt.Errorf("zellular: archive of %s has no batch %d", appName, next)
+	}
+	return nil
+}
+
+// readSegment fetches, checks and decompresses one segment. Its entries
+// must be the batches from First to Last, chaining from Previous to
+// ChainingHash.
+func (r *ArchiveReader) readSegment(ctx context.Context, info ArchiveSegment) ([]walEntry, error) {
+	data, err := r.store.Get(ctx, info.Key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != info.SHA256 {
+		return nil, fmt.Errorf("zellular: archive segment %s failed integrity check", info.Key)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var entries []walEntry
+	if err := json.NewDecoder(zr).Decode(&entries); err != nil {
+		return nil, err
+	}
+	if len(entries) != info.Last-info.First+1 {
+		return nil, fmt.Errorf("zellular: archive segment %s holds %d batches, not %d", info.Key, len(entries), info.Last-info.First+1)
+	}
+	chainingHash := info.Previous
+	for i, entry := range entries {
+		if entry.Index != info.First+i {
+			return nil, fmt.Errorf("zellular: archive segment %s has batch %d where %d belongs", info.Key, entry.Index, info.First+i)
+		}
+		batchHash := r.z.batchHash(entry.Payload)
+		if batchHash != entry.Hash {
+			return nil, fmt.Errorf("zellular: archived batch %d failed integrity check", entry.Index)
+		}
+		chainingHash = r.z.chainHash(chainingHash, batchHash)
+	}
+	if chainingHash != info.ChainingHash {
+		return nil, fmt.Errorf("zellular: archive segment %s does not chain to its chaining hash", info.Key)
+	}
+	return entries, nil
+}
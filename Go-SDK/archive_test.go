@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveResumesWithoutDuplicates(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`, `["d"]`, `["e"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store := DirStore{Root: t.TempDir()}
+	archiver := NewArchiver(z, store)
+	archiver.SegmentSize = 2
+
+	if err := archiver.Archive(ctx, 0, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := archiver.Archive(ctx, 0, 5, ""); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := readManifest(ctx, store, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranges [][2]int
+	for _, segment := range manifest.Segments {
+		ranges = append(ranges, [2]int{segment.First, segment.Last})
+	}
+	if len(ranges) != 3 || ranges[0] != [2]int{1, 2} || ranges[1] != [2]int{3, 3} || ranges[2] != [2]int{4, 5} {
+		t.Fatalf("segments = %v", ranges)
+	}
+
+	var indexes []int
+	err = NewArchiveReader(z, store).Range(ctx, 2, 5, func(batch Batch) error {
+		indexes = append(indexes, batch.Index)
+		return nil
+	})
+	if err != nil || len(indexes) != 4 || indexes[0] != 2 || indexes[3] != 5 {
+		t.Fatalf("Range = %v, %v", indexes, err)
+	}
+}
+
+func TestArchiveReaderChecksChainingHashes(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store := DirStore{Root: t.TempDir()}
+	archiver := NewArchiver(z, store)
+	archiver.SegmentSize = 2
+	if err := archiver.Archive(ctx, 0, 3, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := readManifest(ctx, store, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Segments[1].Previous = z.chain("", `["x"]`)
+	data, _ := json.Marshal(manifest)
+	if err := store.Put(ctx, manifestKey("app"), data); err != nil {
+		t.Fatal(err)
+	}
+	err = NewArchiveReader(z, store).Range(ctx, 1, 3, func(Batch) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "does not follow") {
+		t.Fatalf("broken chain was not rejected: %v", err)
+	}
+}
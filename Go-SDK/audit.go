@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord documents one verification decision: its inputs, the
+// operator set it was made against, the threshold math and the outcome
+type AuditRecord struct {
+	Time             time.Time `json:"time"`
+	AppName          string    `json:"app_name"`
+	MessageHash      string    `json:"message_hash"`
+	Signature        string    `json:"signature"`
+	Nonsigners       []string  `json:"nonsigners"`
+	OperatorSetHash  string    `json:"operator_set_hash"`
+	TotalStake       float64   `json:"total_stake"`
+	NonsignerStake   float64   `json:"nonsigner_stake"`
+	ThresholdPercent float64   `json:"threshold_percent"`
+	Accepted         bool      `json:"accepted"`
+	Reason           string    `json:"reason"`
+}
+
+// AuditSink receives audit records; implementations must be append-only
+type AuditSink interface {
+	Record(AuditRecord) error
+}
+
+// WithAuditSink records every verification decision in sink
+func WithAuditSink(sink AuditSink) Option {
+	return func(z *Zellular) {
+		z.auditSink = sink
+	}
+}
+
+// FileAuditSink appends records as JSON lines to a file
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileAuditSink opens the audit log at path for appending
+func OpenFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+// Record appends a record and syncs it to disk
+func (s *FileAuditSink) Record(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the audit log
+func (s *FileAuditSink) Close() error {
+	return s.file.Close()
+}
+
+// audit hands a completed record to the configured sink
+func (z *Zellular) audit(record AuditRecord) {
+	if z.auditSink == nil {
+		return
+	}
+	record.Time = time.Now()
+	record.AppName = z.AppName
+	record.OperatorSetHash = OperatorSetDigest(z.operators())
+	record.ThresholdPercent = z.ThresholdPercent
+	if err := z.auditSink.Record(record); err != nil {
+		log.Printf("zellular: writing audit record failed: %v", err)
+		z.errors.record(err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"runtime"
+
+	"github.com/consensys/gnark-crypto/utils/cpu"
+)
+
+// Backends names the implementations the hot paths run on, as far as the
+// client can tell. The curve library chooses assembly on CPUs that support
+// it; building with the purego tag forces its portable Go code, e.g. to
+// compare results or work around a platform issue.
+type Backends struct {
+	// Hash is the xxh128 implementation used for batch and chaining hashes,
+	// always the portable Go one of xxh3.go
+	Hash string `json:"hash"`
+	// Keccak is the keccak256 library used for addresses and selectors,
+	// which picks its own implementation and does not report it
+	Keccak string `json:"keccak"`
+	// Curve is the BN254 field arithmetic used for pairings
+	Curve string `json:"curve"`
+}
+
+// ActiveBackends reports which implementations are in use on this machine
+func ActiveBackends() Backends {
+	b := Backends{Hash: "go", Keccak: "golang.org/x/crypto/sha3", Curve: "go"}
+	if purego {
+		return b
+	}
+	// gnark-crypto has BN254 field assembly for these, and on amd64 uses
+	// its ADX and BMI2 multiplication when the CPU supports both
+	switch runtime.GOARCH {
+	case "amd64":
+		b.Curve = "amd64 assembly"
+		if cpu.SupportADX {
+			b.Curve = "amd64 assembly (ADX, BMI2)"
+		}
+	case "arm64":
+		b.Curve = "arm64 assembly"
+	}
+	return b
+}
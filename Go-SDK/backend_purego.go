@@ -0,0 +1,7 @@
+//go:build purego
+
+package main
+
+// purego is set when building with the purego tag, which the curve library
+// honours by skipping its assembly
+const purego = true
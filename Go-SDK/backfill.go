@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// backfillChunk is a range of batches fetched by one backfill worker
+type backfillChunk struct {
+	batches []Batch
+	err     error
+}
+
+// BackfillStream hands out finalized batches like BatchStream, but first
+// fetches the history up to the latest finalized index with several
+// workers in parallel, then switches to following new batches from where
+// the backfill ended, without gaps or duplicates. Batches are verified as
+// by BatchStream before they are handed out.
+type BackfillStream struct {
+	z *Zellular
+	// after and chainingHash are the index and chaining hash of the last
+	// batch Next returned
+	after        int
+	chainingHash string
+	chain        verifiedChain
+
+	chunks  chan chan backfillChunk
+	current []Batch
+	live    *BatchStream
+	// backfill is done once the backfill finished or was stopped
+	backfill context.Context
+	cancel   context.CancelFunc
+}
+
+// Backfill returns a stream of the batches following index after that
+// fetches up to workers chunks of chunkSize batches at once until it has
+// caught up. Workers run until the backfill ends or ctx is done; Close
+// stops them early.
+func (z *Zellular) Backfill(ctx context.Context, after, workers, chunkSize int) (*BackfillStream, error) {
+	if workers < 1 || chunkSize < 1 {
+		return nil, fmt.Errorf("zellular: backfill needs at least one worker and a positive chunk size")
+	}
+	last, err := z.fetchLastFinalized(ctx, nodeFor(ctx, z.readEndpoint()))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &BackfillStream{
+		z:        z,
+		after:    after,
+		chain:    newVerifiedChain(z, after, ""),
+		chunks:   make(chan chan backfillChunk, workers),
+		backfill: ctx,
+		cancel:   cancel,
+	}
+	go s.dispatch(ctx, after, last.Index, chunkSize)
+	return s, nil
+}
+
+// dispatch starts a fetch per chunk, queueing result channels in order so
+// that at most cap(s.chunks) chunks are in flight or waiting
+func (s *BackfillStream) dispatch(ctx context.Context, after, last, chunkSize int) {
+	defer close(s.chunks)
+	for from := after + 1; from <= last; from += chunkSize {
+		to := from + chunkSize - 1
+		if to > last {
+			to = last
+		}
+		result := make(chan backfillChunk, 1)
+		select {
+		case s.chunks <- result:
+		case <-ctx.Done():
+			return
+		}
+		go func(from, to int) {
+			batches, err := s.z.fetchRange(ctx, from, to)
+			result <- backfillChunk{batches: batches, err: err}
+		}(from, to)
+	}
+}
+
+// fetchRange fetches the batches from index from to index to
+func (z *Zellular) fetchRange(ctx context.Context, from, to int) ([]Batch, error) {
+	batches := make([]Batch, 0, to-from+1)
+	for index := from; index <= to; {
+		page, err := z.fetchFinalized(ctx, index-1)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil || len(page.Batches) == 0 {
+			return nil, fmt.Errorf("zellular: batch %d is not finalized", index)
+		}
+		received := time.Now()
+		for _, payload := range page.Batches {
+			if index > to {
+				break
+			}
+			batch := Batch{Index: index, Payload: payload, Node: page.node, ReceivedAt: received}
+			if page.Finalized != nil {
+				if page.Finalized.Timestamp > 0 {
+					batch.FinalizedAt = time.Unix(page.Finalized.Timestamp, 0)
+				}
+				if page.Finalized.Index == index {
+					batch.Proof = page.Finalized.proof(z.AppName)
+				}
+			}
+			batches = append(batches, batch)
+			index++
+		}
+	}
+	return batches, nil
+}
+
+// Next returns the next batch, from the backfill until it is exhausted and
+// then from the live stream. After a failed backfill fetch the error is
+// returned once and the live stream takes over from the last batch.
+func (s *BackfillStream) Next(ctx context.Context) (Batch, error) {
+	for s.live == nil && len(s.current) == 0 {
+		if s.backfill.Err() != nil {
+			s.goLive()
+			break
+		}
+		var result chan backfillChunk
+		var ok bool
+		select {
+		case result, ok = <-s.chunks:
+		case <-ctx.Done():
+			return Batch{}, ctx.Err()
+		}
+		if !ok {
+			s.goLive()
+			break
+		}
+		var chunk backfillChunk
+		select {
+		case chunk = <-result:
+		case <-ctx.Done():
+			return Batch{}, ctx.Err()
+		}
+		if chunk.err != nil {
+			s.goLive()
+			return Batch{}, chunk.err
+		}
+		for _, batch := range chunk.batches {
+			verified, err := s.chain.add(batch, "")
+			if err != nil {
+				s.goLive()
+				return Batch{}, err
+			}
+			s.current = append(s.current, verified...)
+		}
+	}
+	if s.live != nil {
+		return s.live.Next(ctx)
+	}
+
+	batch := s.current[0]
+	s.current = s.current[1:]
+	s.after, s.chainingHash = batch.Index, batch.ChainingHash
+	batch.Sequence = atomic.AddUint64(&batchSequence, 1)
+	s.z.progress.set(batch.Index, batch.ChainingHash)
+	s.z.stats.finalized(batch.Payload)
+	return batch, nil
+}
+
+// Close stops the backfill workers. Next then carries on with the live
+// stream from the last batch it returned.
+func (s *BackfillStream) Close() {
+	s.cancel()
+}
+
+// goLive ends the backfill and follows new batches after the last one
+// returned, refetching any still awaiting verification
+func (s *BackfillStream) goLive() {
+	s.cancel()
+	s.current = nil
+	s.live = s.z.StreamFrom(s.after, s.chainingHash)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BalanceMode chooses how balanced reads pick an operator
+type BalanceMode int
+
+const (
+	// RoundRobin cycles through the healthy operators
+	RoundRobin BalanceMode = iota
+	// LeastLoaded picks the healthy operator with the fewest reads in flight
+	LeastLoaded
+)
+
+// readBalancer spreads page fetches over the operators' nodes
+type readBalancer struct {
+	mode      BalanceMode
+	mu        sync.Mutex
+	next      int
+	inflight  map[string]int
+	failures  map[string]int
+	downUntil map[string]time.Time
+}
+
+// WithBalancedReads spreads finalized page fetches over all operators with
+// a socket instead of pinning them to the base node. Operators failing
+// repeatedly are skipped for a while; submissions still go to the base node.
+func WithBalancedReads(mode BalanceMode) Option {
+	return func(z *Zellular) {
+		z.balancer = &readBalancer{
+			mode:      mode,
+			inflight:  make(map[string]int),
+			failures:  make(map[string]int),
+			downUntil: make(map[string]time.Time),
+		}
+	}
+}
+
+// readEndpoint returns the node the next page fetch should go to
+func (z *Zellular) readEndpoint() string {
+	if z.balancer == nil || z.pages != nil {
+		return z.base()
+	}
+	return z.balancer.pick(z.operators(), z.base(), z.reputation)
+}
+
+// pick chooses a healthy socket and counts a read in flight on it. With a
+// reputation, operators are drawn weighted by score instead of in turn.
+func (b *readBalancer) pick(operators map[string]Operator, fallback string, reputation *Reputation) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	healthy := make(map[string]Operator)
+	var sockets []string
+	for id, operator := range withSockets(operators) {
+		if now.After(b.downUntil[operator.Socket]) {
+			healthy[id] = operator
+			sockets = append(sockets, operator.Socket)
+		}
+	}
+	if len(sockets) == 0 {
+		return fallback
+	}
+	sort.Strings(sockets)
+
+	socket := sockets[b.next%len(sockets)]
+	b.next++
+	if reputation != nil {
+		socket = healthy[reputation.Select(healthy)].Socket
+	}
+	if b.mode == LeastLoaded {
+		for _, s := range sockets {
+			if b.inflight[s] < b.inflight[socket] {
+				socket = s
+			}
+		}
+	}
+	b.inflight[socket]++
+	return socket
+}
+
+// done records the end of a read started by pick
+func (b *readBalancer) done(socket string, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inflight[socket] > 0 {
+		b.inflight[socket]--
+	}
+	if err == nil {
+		b.failures[socket] = 0
+		return
+	}
+	b.failures[socket]++
+	if b.failures[socket] >= poolMaxFailures {
+		b.failures[socket] = 0
+		b.downUntil[socket] = time.Now().Add(poolCooldown)
+	}
+}
+
+// breakers reports the breaker of every operator socket
+func (b *readBalancer) breakers(operators map[string]Operator, now time.Time) []BreakerState {
+	var sockets []string
+	for _, operator := range withSockets(operators) {
+		sockets = append(sockets, operator.Socket)
+	}
+	sort.Strings(sockets)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make([]BreakerState, 0, len(sockets))
+	for _, socket := range sockets {
+		states = append(states, breakerState("balancer", socket, b.failures, b.downUntil, now))
+	}
+	return states
+}
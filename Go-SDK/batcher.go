@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Lane is the priority of a transaction queued in a Batcher
+type Lane int
+
+const (
+	// Bulk transactions are batched up to MaxBatch or MaxDelay and sent
+	// with limited concurrency
+	Bulk Lane = iota
+	// Urgent transactions are sent as soon as the batcher sees them, ahead
+	// of queued bulk batches and outside the bulk concurrency limit
+	Urgent
+)
+
+// ErrBatcherStopped is delivered to transactions still queued when Run returns
+var ErrBatcherStopped = errors.New("zellular: batcher stopped")
+
+// queuedTx is a transaction waiting in a lane
+type queuedTx struct {
+	tx     interface{}
+	queued time.Time
+	result chan error
+}
+
+// Batcher groups transactions into batches and sends them with Send. Urgent
+// transactions preempt queued bulk ones and are sent with PriorityHigh, while
+// bulk batches are sent with PriorityLow and at most BulkConcurrency at a
+// time, which keeps connection capacity free for urgent sends.
+type Batcher struct {
+	// MaxBatch is the largest number of transactions in one batch
+	MaxBatch int
+	// MaxDelay is how long a bulk transaction may wait for its batch to fill
+	MaxDelay time.Duration
+	// BulkConcurrency caps the bulk batches being sent at once
+	BulkConcurrency int
+
+	z      *Zellular
+	mu     sync.Mutex
+	lanes  [2][]queuedTx
+	notify chan struct{}
+}
+
+// NewBatcher returns a batcher sending through z; call Run to start it
+func (z *Zellular) NewBatcher() *Batcher {
+	return &Batcher{
+		MaxBatch:        100,
+		MaxDelay:        100 * time.Millisecond,
+		BulkConcurrency: 1,
+		z:               z,
+		notify:          make(chan struct{}, 1),
+	}
+}
+
+// Add queues tx in lane. The returned channel receives the result of
+// sending the batch tx ends up in.
+func (b *Batcher) Add(tx interface{}, lane Lane) <-chan error {
+	result := make(chan error, 1)
+	b.mu.Lock()
+	b.lanes[lane] = append(b.lanes[lane], queuedTx{tx: tx, queued: time.Now(), result: result})
+	b.mu.Unlock()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return result
+}
+
+// Run sends batches until ctx is done, then fails the transactions left in
+// the queues with ErrBatcherStopped
+func (b *Batcher) Run(ctx context.Context) error {
+	bulkSlots := make(chan struct{}, b.BulkConcurrency)
+	var sending sync.WaitGroup
+	defer func() {
+		sending.Wait()
+		b.mu.Lock()
+		for lane := range b.lanes {
+			for _, queued := range b.lanes[lane] {
+				queued.result <- ErrBatcherStopped
+			}
+			b.lanes[lane] = nil
+		}
+		b.mu.Unlock()
+	}()
+
+	for {
+		if batch := b.take(Urgent, 0); batch != nil {
+			sending.Add(1)
+			go func() {
+				defer sending.Done()
+				b.send(ctx, batch, PriorityHigh)
+			}()
+			continue
+		}
+
+		wait := b.MaxDelay
+		if batch := b.take(Bulk, b.MaxDelay); batch != nil {
+			select {
+			case bulkSlots <- struct{}{}:
+				sending.Add(1)
+				go func() {
+					defer func() { <-bulkSlots; sending.Done() }()
+					b.send(ctx, batch, PriorityLow)
+				}()
+				continue
+			default:
+				// every bulk slot is busy; put the batch back in front
+				b.requeue(batch)
+			}
+		} else if oldest, ok := b.oldest(Bulk); ok {
+			wait = b.MaxDelay - time.Since(oldest)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-b.notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// take removes the next batch from lane if it has MaxBatch transactions or
+// its oldest one has waited at least maxDelay
+func (b *Batcher) take(lane Lane, maxDelay time.Duration) []queuedTx {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := b.lanes[lane]
+	if len(queue) == 0 || (len(queue) < b.MaxBatch && time.Since(queue[0].queued) < maxDelay) {
+		return nil
+	}
+	n := len(queue)
+	if n > b.MaxBatch {
+		n = b.MaxBatch
+	}
+	batch := append([]queuedTx(nil), queue[:n]...)
+	b.lanes[lane] = queue[n:]
+	return batch
+}
+
+// requeue puts a bulk batch back at the head of its lane
+func (b *Batcher) requeue(batch []queuedTx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lanes[Bulk] = append(batch, b.lanes[Bulk]...)
+}
+
+// oldest returns when the oldest transaction of lane was queued
+func (b *Batcher) oldest(lane Lane) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lanes[lane]) == 0 {
+		return time.Time{}, false
+	}
+	return b.lanes[lane][0].queued, true
+}
+
+// send submits batch with priority and reports the result to its transactions
+func (b *Batcher) send(ctx context.Context, batch []queuedTx, priority RequestPriority) {
+	txs := make([]interface{}, len(batch))
+	for i, queued := range batch {
+		txs[i] = queued.tx
+	}
+	opts := callOptions(ctx)
+	opts.Priority = priority
+	err := b.z.Send(WithCallOptions(ctx, opts), txs)
+	for _, queued := range batch {
+		queued.result <- err
+	}
+	// a finished bulk send may free a slot for a waiting batch
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
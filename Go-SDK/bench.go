@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// BenchReport is the output of the bench command
+type BenchReport struct {
+	Node              string        `json:"node"`
+	Backends          Backends      `json:"backends"`
+	Batches           int           `json:"batches"`
+	ProofsVerified    int           `json:"proofs_verified"`
+	FetchVerifyTime   time.Duration `json:"fetch_verify_time"`
+	BatchesPerSecond  float64       `json:"batches_per_second"`
+	AllocsPerBatch    float64       `json:"allocs_per_batch"`
+	BytesPerBatch     float64       `json:"bytes_per_batch"`
+	PairingsPerSecond float64       `json:"pairings_per_second"`
+	// PreparedPairingsPerSecond uses lines precomputed as PreparedVerifier does
+	PreparedPairingsPerSecond float64         `json:"prepared_pairings_per_second"`
+	Operators                 []operatorProbe `json:"operators"`
+}
+
+// operatorProbe is a ProbeResult in printable form
+type operatorProbe struct {
+	OperatorID string        `json:"operator_id"`
+	Socket     string        `json:"socket"`
+	RTT        time.Duration `json:"rtt,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// benchFetchVerify streams the last n finalized batches and verifies every
+// finality proof among them
+func benchFetchVerify(ctx context.Context, z *Zellular, n int, report *BenchReport) error {
+	last, err := z.fetchLastFinalized(ctx, z.base())
+	if err != nil {
+		return err
+	}
+	after := last.Index - n
+	if after < 0 {
+		after = 0
+	}
+
+	stream := z.Stream(after)
+	var memBefore runtime.MemStats
+	runtime.ReadMemStats(&memBefore)
+	start := time.Now()
+	for report.Batches < last.Index-after {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+		report.Batches++
+		if batch.Proof != nil {
+			result, err := z.VerifyProof(*batch.Proof)
+			if err != nil {
+				return err
+			}
+			if !result.Valid() {
+				return fmt.Errorf("bench: proof of batch %d failed verification: %s", batch.Index, result.Reason)
+			}
+			report.ProofsVerified++
+		}
+	}
+	report.FetchVerifyTime = time.Since(start)
+	var memAfter runtime.MemStats
+	runtime.ReadMemStats(&memAfter)
+	if report.Batches > 0 {
+		report.AllocsPerBatch = float64(memAfter.Mallocs-memBefore.Mallocs) / float64(report.Batches)
+		report.BytesPerBatch = float64(memAfter.TotalAlloc-memBefore.TotalAlloc) / float64(report.Batches)
+	}
+	if seconds := report.FetchVerifyTime.Seconds(); seconds > 0 {
+		report.BatchesPerSecond = float64(report.Batches) / seconds
+	}
+	return nil
+}
+
+// benchPairings runs signature verifications on the local BLS backend for
+// duration and returns the pairings computed per second
+func benchPairings(duration time.Duration) (float64, error) {
+	_, _, g1, g2 := bn254.Generators()
+	message := []byte(hash("zellular bench"))
+	count := 0
+	start := time.Now()
+	for time.Since(start) < duration {
+		h := mapToG1(message)
+		if _, err := verifyBLS(&g2, &h, &g1); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	// each verification is a product of two pairings
+	return float64(2*count) / time.Since(start).Seconds(), nil
+}
+
+// benchPreparedPairings is benchPairings with precomputed G2 lines
+func benchPreparedPairings(duration time.Duration) (float64, error) {
+	_, _, g1, g2 := bn254.Generators()
+	lines := []pairingLines{bn254.PrecomputeLines(g2), bn254.PrecomputeLines(g2)}
+	message := []byte(hash("zellular bench"))
+	count := 0
+	start := time.Now()
+	for time.Since(start) < duration {
+		h := mapToG1(message)
+		if _, err := bn254.PairingCheckFixedQ([]bn254.G1Affine{g1, h}, lines); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return float64(2*count) / time.Since(start).Seconds(), nil
+}
+
+// benchCommand implements: zellular bench -node URL -app APP -batches N
+func benchCommand(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	node := flags.String("node", "", "base URL of the node to benchmark")
+	appName := flags.String("app", "simple_app", "app name")
+	batches := flags.Int("batches", 1000, "number of recent batches to fetch and verify")
+	pairingTime := flags.Duration("pairing-time", 3*time.Second, "duration of the BLS benchmark")
+	timeout := flags.Duration("timeout", 5*time.Second, "probe timeout per operator")
+	flags.Parse(args)
+	if *node == "" {
+		flags.Usage()
+		return errors.New("bench: -node is required")
+	}
+
+	ctx := context.Background()
+	z := NewZellular(*appName, *node, 67)
+	report := &BenchReport{Node: *node, Backends: ActiveBackends()}
+	if err := benchFetchVerify(ctx, z, *batches, report); err != nil {
+		return err
+	}
+	var err error
+	if report.PairingsPerSecond, err = benchPairings(*pairingTime); err != nil {
+		return err
+	}
+	if report.PreparedPairingsPerSecond, err = benchPreparedPairings(*pairingTime); err != nil {
+		return err
+	}
+	for _, result := range z.ProbeOperators(ctx, *timeout) {
+		probe := operatorProbe{OperatorID: result.OperatorID, Socket: result.Socket, RTT: result.RTT}
+		if result.Err != nil {
+			probe.Error = result.Err.Error()
+		}
+		report.Operators = append(report.Operators, probe)
+	}
+	return writeJSON(report)
+}
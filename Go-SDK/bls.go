@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+)
+
+// decodePublicKey parses the operator's BN254 G2 public key from the
+// decimal coordinates published by the registry, which lists each Fp2
+// coordinate imaginary part first
+func (o *Operator) decodePublicKey() error {
+	if len(o.PubkeyG2_X) != 2 || len(o.PubkeyG2_Y) != 2 {
+		return fmt.Errorf("zellular: operator %s has no G2 public key", o.ID)
+	}
+
+	var p bn254.G2Affine
+	coordinates := []struct {
+		dst *bn254.E2
+		src []string
+	}{{&p.X, o.PubkeyG2_X}, {&p.Y, o.PubkeyG2_Y}}
+	for _, c := range coordinates {
+		if _, err := c.dst.A0.SetString(c.src[1]); err != nil {
+			return fmt.Errorf("zellular: operator %s has a malformed G2 public key: %w", o.ID, err)
+		}
+		if _, err := c.dst.A1.SetString(c.src[0]); err != nil {
+			return fmt.Errorf("zellular: operator %s has a malformed G2 public key: %w", o.ID, err)
+		}
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return fmt.Errorf("zellular: operator %s has a G2 public key outside the subgroup", o.ID)
+	}
+	o.PublicKeyG2 = p
+	return nil
+}
+
+// decodeSignature parses a G1 signature given either as hex encoded
+// compressed or uncompressed bytes, or as decimal "1 x y" coordinates
+func decodeSignature(s string) (bn254.G1Affine, error) {
+	var signature bn254.G1Affine
+	s = strings.TrimSpace(s)
+
+	if fields := strings.Fields(s); len(fields) > 1 {
+		if len(fields) == 3 && fields[0] == "1" {
+			fields = fields[1:]
+		}
+		if len(fields) != 2 {
+			return signature, errors.New("zellular: malformed signature coordinates")
+		}
+		if _, err := signature.X.SetString(fields[0]); err != nil {
+			return signature, err
+		}
+		if _, err := signature.Y.SetString(fields[1]); err != nil {
+			return signature, err
+		}
+	} else {
+		data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return signature, err
+		}
+		if _, err := signature.SetBytes(data); err != nil {
+			return signature, err
+		}
+	}
+
+	if !signature.IsOnCurve() || !signature.IsInSubGroup() {
+		return signature, errors.New("zellular: signature is not in the G1 subgroup")
+	}
+	return signature, nil
+}
+
+// aggregatePublicKeys sums the G2 public keys of the operators
+func aggregatePublicKeys(operators map[string]Operator) bn254.G2Affine {
+	var sum bn254.G2Jac
+	for _, operator := range operators {
+		sum.AddMixed(&operator.PublicKeyG2)
+	}
+	var aggregated bn254.G2Affine
+	aggregated.FromJacobian(&sum)
+	return aggregated
+}
+
+// signersPublicKey is the aggregated public key without the nonsigners' keys
+func (z *Zellular) signersPublicKey(nonsigners []string) bn254.G2Affine {
+	operators, aggregated := z.OperatorSet()
+	var sum bn254.G2Jac
+	sum.FromAffine(&aggregated)
+	for _, nonsigner := range nonsigners {
+		var negated bn254.G2Affine
+		operator, _ := lookupOperator(operators, nonsigner)
+		negated.Neg(&operator.PublicKeyG2)
+		sum.AddMixed(&negated)
+	}
+	var publicKey bn254.G2Affine
+	publicKey.FromJacobian(&sum)
+	return publicKey
+}
+
+// mapToG1 hashes a message digest to G1 the way the nodes and the
+// EigenLayer BN254 library do: x starts at the digest read as a big-endian
+// integer mod p and is incremented until x³ + 3 is a square, whose root
+// (beta^((p+1)/4)) is y
+func mapToG1(digest []byte) bn254.G1Affine {
+	var x, beta, y, three fp.Element
+	x.SetBytes(digest)
+	three.SetUint64(3)
+	one := fp.One()
+	for {
+		beta.Square(&x).Mul(&beta, &x).Add(&beta, &three)
+		if y.Sqrt(&beta) != nil {
+			return bn254.G1Affine{X: x, Y: y}
+		}
+		x.Add(&x, &one)
+	}
+}
+
+// verifyBLS checks e(signature, g2) == e(h, publicKey), h being the message
+// point of the signed digest
+func verifyBLS(publicKey *bn254.G2Affine, h *bn254.G1Affine, signature *bn254.G1Affine) (bool, error) {
+	var negated bn254.G1Affine
+	negated.Neg(h)
+
+	_, _, _, g2 := bn254.Generators()
+	return bn254.PairingCheck(
+		[]bn254.G1Affine{*signature, negated},
+		[]bn254.G2Affine{g2, *publicKey},
+	)
+}
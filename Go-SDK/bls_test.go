@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// testDigest is xxh128("") in hex, the form nodes sign message digests in
+var testDigest = []byte("99aa06d3014798d86001c324468d497f")
+
+// testSecret signs the vectors below, which were computed with an
+// independent implementation of the EigenLayer BN254 hashToG1 and G1
+// arithmetic
+var testSecret, _ = new(big.Int).SetString("1234567890abcdef1234567890abcdef", 16)
+
+const (
+	digestPointX  = "3994971869687107546851625623160233802700514625523713910356037175714732849696"
+	digestPointY  = "10786846045023880508980026622009189454977734461869901340359451660349073348763"
+	testSignature = "1 13985777575220072541187490735993483489201824044848958113788895446292233527166 " +
+		"17185399128332025139213121210381812956610954436231437578071774930338142533600"
+)
+
+func TestMapToG1(t *testing.T) {
+	h := mapToG1(testDigest)
+	if h.X.String() != digestPointX || h.Y.String() != digestPointY {
+		t.Fatalf("mapToG1 = (%s, %s)", h.X.String(), h.Y.String())
+	}
+	if !h.IsOnCurve() {
+		t.Fatal("mapToG1 point is not on the curve")
+	}
+
+	// "zellular" as an integer is below p and x³ + 3 is not a square there,
+	// so this exercises the increment
+	h = mapToG1([]byte("zellular"))
+	if h.X.String() != "8819574658357289332" {
+		t.Fatalf("mapToG1 did not increment x, got %s", h.X.String())
+	}
+}
+
+// registryOperator returns an operator with secret's public key laid out
+// the way the registry publishes it, imaginary parts first
+func registryOperator(id string, secret *big.Int) Operator {
+	_, _, _, g2 := bn254.Generators()
+	var publicKey bn254.G2Affine
+	publicKey.ScalarMultiplication(&g2, secret)
+	return Operator{
+		ID:         id,
+		PubkeyG2_X: []string{publicKey.X.A1.String(), publicKey.X.A0.String()},
+		PubkeyG2_Y: []string{publicKey.Y.A1.String(), publicKey.Y.A0.String()},
+		Stake:      1,
+	}
+}
+
+func TestVerifyBLS(t *testing.T) {
+	z := newZellular("app", "http://localhost:6001", 67)
+	operators := map[string]Operator{
+		"0x0000000000000000000000000000000000000001": registryOperator("0x0000000000000000000000000000000000000001", testSecret),
+	}
+	if err := z.setOperators(operators); err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := decodeSignature(testSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := z.checkSignature(nil, testDigest, &sig)
+	if err != nil || !valid {
+		t.Fatalf("checkSignature = %v, %v", valid, err)
+	}
+
+	valid, err = z.checkSignature(nil, []byte("00000000000000000000000000000000"), &sig)
+	if err != nil || valid {
+		t.Fatalf("signature verified over another digest: %v, %v", valid, err)
+	}
+
+	other := registryOperator("0x0000000000000000000000000000000000000002", big.NewInt(7))
+	if err := z.setOperators(map[string]Operator{other.ID: other}); err != nil {
+		t.Fatal(err)
+	}
+	valid, err = z.checkSignature(nil, testDigest, &sig)
+	if err != nil || valid {
+		t.Fatalf("signature verified under another key: %v, %v", valid, err)
+	}
+}
+
+func TestDecodePublicKeyRejectsSwappedCoordinates(t *testing.T) {
+	operator := registryOperator("0x0000000000000000000000000000000000000001", testSecret)
+	operator.PubkeyG2_X[0], operator.PubkeyG2_X[1] = operator.PubkeyG2_X[1], operator.PubkeyG2_X[0]
+	if err := operator.decodePublicKey(); err == nil {
+		t.Fatal("decoded a G2 key with real and imaginary parts swapped")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestPriority is the urgency hint sent with a request
+type RequestPriority int
+
+const (
+	// PriorityDefault sends no hint
+	PriorityDefault RequestPriority = iota
+	// PriorityHigh marks interactive requests
+	PriorityHigh
+	// PriorityLow marks background work such as backfills
+	PriorityLow
+)
+
+// CallOptions adjust individual calls without changing method signatures;
+// attach them to the call's context with WithCallOptions
+type CallOptions struct {
+	// Node sends the call to this base URL instead of the current base node
+	Node string
+	// Priority is sent as an RFC 9218 Priority header
+	Priority RequestPriority
+	// Timeout bounds each HTTP request the call makes, retries included
+	Timeout time.Duration
+	// DryRun makes Send validate the batch without submitting it
+	DryRun bool
+	// IdempotencyKey is sent as an Idempotency-Key header so a node can
+	// recognize a repeated submission
+	IdempotencyKey string
+}
+
+type callOptionsKey struct{}
+
+// WithCallOptions returns a context carrying opts for the calls made with it
+func WithCallOptions(ctx context.Context, opts CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey{}, opts)
+}
+
+// callOptions returns the options attached to ctx
+func callOptions(ctx context.Context) CallOptions {
+	opts, _ := ctx.Value(callOptionsKey{}).(CallOptions)
+	return opts
+}
+
+// nodeFor returns the node a call with ctx should go to, or fallback
+func nodeFor(ctx context.Context, fallback string) string {
+	if node := callOptions(ctx).Node; node != "" {
+		return node
+	}
+	return fallback
+}
+
+// header returns the Priority header value, empty for PriorityDefault
+func (p RequestPriority) header() string {
+	switch p {
+	case PriorityHigh:
+		return "u=1"
+	case PriorityLow:
+		return "u=5"
+	default:
+		return ""
+	}
+}
+
+// cancelOnClose releases a per-request timeout once the body is consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// withTimeout applies the call's Timeout to a request, returning a cancel
+// to run if the request fails and a function arming it on the response body
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc, func(*http.Response)) {
+	timeout := callOptions(ctx).Timeout
+	if timeout <= 0 {
+		return ctx, func() {}, func(*http.Response) {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, func(resp *http.Response) {
+		resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxCapturedBody bounds how much of each body an Exchange keeps
+const maxCapturedBody = 4096
+
+// Exchange is a captured HTTP request and its response
+type Exchange struct {
+	Time         time.Time     `json:"time"`
+	Method       string        `json:"method"`
+	URL          string        `json:"url"`
+	Status       int           `json:"status,omitempty"`
+	Duration     time.Duration `json:"duration"`
+	RequestBody  string        `json:"request_body,omitempty"`
+	ResponseBody string        `json:"response_body,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// exchangeLog keeps the last exchanges of a transport
+type exchangeLog struct {
+	mu        sync.Mutex
+	size      int
+	exchanges []Exchange
+}
+
+// WithHTTPCapture keeps the last n HTTP exchanges with truncated bodies,
+// available from CapturedExchanges and the admin endpoint. It is meant for
+// debugging.
+func WithHTTPCapture(n int) Option {
+	return func(z *Zellular) {
+		z.transport.capture = &exchangeLog{size: n}
+	}
+}
+
+// CapturedExchanges returns the captured exchanges, oldest first
+func (z *Zellular) CapturedExchanges() []Exchange {
+	if z.transport.capture == nil {
+		return nil
+	}
+	l := z.transport.capture
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Exchange(nil), l.exchanges...)
+}
+
+// record captures one attempt of req. The first maxCapturedBody bytes of
+// the response body are read ahead and put back in front of the rest, so
+// the caller still consumes the whole body as it streams in.
+func (l *exchangeLog) record(req *http.Request, resp *http.Response, err error, start time.Time) {
+	exchange := Exchange{Time: start, Method: req.Method, URL: req.URL.String(), Duration: time.Since(start)}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			exchange.RequestBody = readTruncated(body)
+			body.Close()
+		}
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	if resp != nil {
+		exchange.Status = resp.StatusCode
+		body, readErr := ioutil.ReadAll(io.LimitReader(resp.Body, maxCapturedBody))
+		if readErr != nil {
+			exchange.Error = readErr.Error()
+		}
+		resp.Body = &capturedBody{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		exchange.ResponseBody = string(body)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exchanges = append(l.exchanges, exchange)
+	if len(l.exchanges) > l.size {
+		l.exchanges = l.exchanges[len(l.exchanges)-l.size:]
+	}
+}
+
+// capturedBody is a response body whose beginning was read ahead
+type capturedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// readTruncated reads at most maxCapturedBody bytes of r
+func readTruncated(r io.Reader) string {
+	body, _ := ioutil.ReadAll(io.LimitReader(r, maxCapturedBody))
+	return string(body)
+}
+
+// serveExchanges writes the captured exchanges as JSON
+func (z *Zellular) serveExchanges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(z.CapturedExchanges())
+}
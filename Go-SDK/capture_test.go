@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestCaptureReadsOnlyTheKeptBody(t *testing.T) {
+	payload := strings.Repeat("x", 3*maxCapturedBody)
+	body := &countingReader{Reader: strings.NewReader(payload)}
+	req, _ := http.NewRequest(http.MethodGet, "http://node/", nil)
+	resp := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(body)}
+
+	l := &exchangeLog{size: 1}
+	l.record(req, resp, nil, time.Now())
+	if body.n > maxCapturedBody {
+		t.Fatalf("capture read %d bytes ahead", body.n)
+	}
+	if exchange := l.exchanges[0]; len(exchange.ResponseBody) != maxCapturedBody {
+		t.Fatalf("captured %d bytes", len(exchange.ResponseBody))
+	}
+	read, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(read) != payload {
+		t.Fatalf("caller read %d bytes, %v", len(read), err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CensorshipEvent reports a base node whose submissions kept missing the
+// finalized stream while other batches were finalized, and the node the
+// client rotated to
+type CensorshipEvent struct {
+	Node   string
+	Missed int
+	Next   string
+}
+
+// EventKind implements Event
+func (e *CensorshipEvent) EventKind() string {
+	return "censorship"
+}
+
+// submitted is a batch sent by Send awaiting finalization
+type submitted struct {
+	node string
+	at   time.Time
+}
+
+// CensorshipMonitor checks that batches sent through Send show up in the
+// finalized stream within Window. A batch is only held against its node
+// when other batches were finalized after it was sent, so a stalled
+// network does not count as censorship.
+type CensorshipMonitor struct {
+	// Window is how long a submitted batch may take to be finalized
+	Window time.Duration
+	// Misses is how many overdue batches make a node suspect
+	Misses int
+	// ProbeTimeout bounds the probes choosing the node to rotate to
+	ProbeTimeout time.Duration
+
+	z       *Zellular
+	mu      sync.Mutex
+	pending map[string]submitted
+	misses  map[string]int
+	// progress is when the last batch that was not ours was finalized
+	progress time.Time
+}
+
+// MonitorCensorship tracks every batch z sends from now on; Run must be
+// called to watch the finalized stream
+func (z *Zellular) MonitorCensorship(window time.Duration, misses int) *CensorshipMonitor {
+	m := &CensorshipMonitor{
+		Window:       window,
+		Misses:       misses,
+		ProbeTimeout: 2 * time.Second,
+		z:            z,
+		pending:      make(map[string]submitted),
+		misses:       make(map[string]int),
+	}
+	z.censorship = m
+	return m
+}
+
+// submitted records a batch accepted by node
+func (m *CensorshipMonitor) submitted(node string, payload []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[hash(string(payload))] = submitted{node: node, at: time.Now()}
+}
+
+// Run watches the finalized batches and checks the pending submissions
+// until ctx is done or watching fails. It does not disturb the client's
+// streams: their progress, statistics and WAL are left alone.
+func (m *CensorshipMonitor) Run(ctx context.Context) error {
+	last, err := m.z.fetchLastFinalized(ctx, m.z.base())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- m.z.watchFinalized(ctx, last.Index, time.Second, func(batch Batch) bool {
+			m.finalized(batch)
+			return false
+		})
+	}()
+
+	ticker := time.NewTicker(m.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+// interval is how often Run checks the pending submissions, a quarter of
+// Window but at least a millisecond
+func (m *CensorshipMonitor) interval() time.Duration {
+	if interval := m.Window / 4; interval >= time.Millisecond {
+		return interval
+	}
+	return time.Millisecond
+}
+
+// finalized settles a submission or records the network making progress
+func (m *CensorshipMonitor) finalized(batch Batch) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := hash(batch.Payload)
+	if s, ok := m.pending[key]; ok {
+		delete(m.pending, key)
+		m.misses[s.node] = 0
+		return
+	}
+	m.progress = time.Now()
+}
+
+// check counts overdue submissions against their nodes and rotates away
+// from the base node once it reaches Misses
+func (m *CensorshipMonitor) check(ctx context.Context) {
+	m.mu.Lock()
+	deadline := time.Now().Add(-m.Window)
+	for key, s := range m.pending {
+		if s.at.After(deadline) || !m.progress.After(s.at) {
+			continue
+		}
+		delete(m.pending, key)
+		m.misses[s.node]++
+	}
+	base := m.z.base()
+	missed := m.misses[base]
+	if missed < m.Misses {
+		m.mu.Unlock()
+		return
+	}
+	m.misses[base] = 0
+	m.mu.Unlock()
+
+	event := &CensorshipEvent{Node: base, Missed: missed}
+	var reachable []string
+	for _, result := range m.z.ProbeOperators(ctx, m.ProbeTimeout) {
+		if result.Err == nil && result.Socket != base {
+			reachable = append(reachable, result.Socket)
+		}
+	}
+	event.Next = m.z.chooseNode(reachable)
+	m.z.emit(event)
+	if event.Next != "" {
+		m.z.setBase(event.Next)
+	}
+}
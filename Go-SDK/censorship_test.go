@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchFinalizedLeavesProgressAlone(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`, `["b"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var payloads []string
+	err := z.watchFinalized(ctx, 0, time.Millisecond, func(batch Batch) bool {
+		payloads = append(payloads, batch.Payload)
+		return len(payloads) == 2
+	})
+	if err != nil || len(payloads) != 2 || payloads[1] != `["b"]` {
+		t.Fatalf("watchFinalized = %v, %v", payloads, err)
+	}
+	if index, _ := z.progress.get(); index != 0 {
+		t.Fatalf("watching moved the client's progress to %d", index)
+	}
+}
+
+func TestCensorshipMonitorTinyWindow(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := z.MonitorCensorship(time.Nanosecond, 1).Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v", err)
+	}
+}
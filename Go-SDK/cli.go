@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// runCommand dispatches the CLI subcommands
+func runCommand(args []string) error {
+	switch args[0] {
+	case "reverify":
+		return reverifyCommand(args[1:])
+	case "operators":
+		return operatorsCommand(args[1:])
+	case "proof":
+		return proofCommand(args[1:])
+	case "bench":
+		return benchCommand(args[1:])
+	case "tail":
+		return tailCommand(args[1:])
+	case "get":
+		return getCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// loadSnapshot reads the operator set from a VerifierState exported with ExportState
+func loadSnapshot(path string) (map[string]Operator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state VerifierState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state.operatorSet(), nil
+}
+
+// reverifyCommand implements: zellular reverify -archive DIR -app APP -snapshot FILE -from N -to M
+func reverifyCommand(args []string) error {
+	flags := flag.NewFlagSet("reverify", flag.ExitOnError)
+	archiveDir := flags.String("archive", "", "archive directory")
+	appName := flags.String("app", "", "app name")
+	snapshot := flags.String("snapshot", "", "operator snapshot exported with ExportState")
+	threshold := flags.Float64("threshold", 67, "threshold percent")
+	from := flags.Int("from", 1, "first index")
+	to := flags.Int("to", 0, "last index")
+	chainingHash := flags.String("chaining-hash", "", "chaining hash before -from")
+	flags.Parse(args)
+	if *archiveDir == "" || *appName == "" || *snapshot == "" || *to < *from {
+		flags.Usage()
+		return errors.New("reverify: -archive, -app, -snapshot and a valid -from/-to range are required")
+	}
+
+	operators, err := loadSnapshot(*snapshot)
+	if err != nil {
+		return err
+	}
+	verifier := NewOfflineVerifier(*appName, operators, *threshold)
+	archive := NewArchiveReader(verifier, DirStore{Root: *archiveDir})
+	report, err := verifier.Reverify(context.Background(), archive, *from, *to, *chainingHash)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(report); err != nil {
+		return err
+	}
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("reverify: %d failures", len(report.Failures))
+	}
+	return nil
+}
+
+// operatorsCommand dispatches the operators subcommands
+func operatorsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "doctor" {
+		return errors.New("usage: zellular operators doctor [-app APP] [-timeout D]")
+	}
+	return doctorCommand(args[1:])
+}
+
+// doctorCommand implements: zellular operators doctor -app APP -timeout D
+func doctorCommand(args []string) error {
+	flags := flag.NewFlagSet("operators doctor", flag.ExitOnError)
+	appName := flags.String("app", "simple_app", "app name used to probe the nodes")
+	timeout := flags.Duration("timeout", 5*time.Second, "probe timeout per operator")
+	flags.Parse(args)
+
+	entries, err := queryRegistry(defaultTransport, subgraphURL, operatorsQuery)
+	if err != nil {
+		return err
+	}
+	z := newZellular(*appName, "", 67)
+	diagnoses := z.DiagnoseOperators(context.Background(), entries, *timeout)
+
+	if err := writeJSON(diagnoses); err != nil {
+		return err
+	}
+	unhealthy := 0
+	for _, diagnosis := range diagnoses {
+		if !diagnosis.Healthy() {
+			unhealthy++
+		}
+	}
+	if unhealthy > 0 {
+		return fmt.Errorf("operators doctor: %d of %d operators have issues", unhealthy, len(diagnoses))
+	}
+	return nil
+}
+
+// proofCommand dispatches the proof subcommands
+func proofCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "inspect":
+			return proofInspectCommand(args[1:])
+		case "verify":
+			return proofVerifyCommand(args[1:])
+		}
+	}
+	return errors.New("usage: zellular proof inspect FILE | zellular proof verify -snapshot FILE [-threshold P] FILE")
+}
+
+// loadProof reads a FinalityProof exported as JSON
+func loadProof(path string) (FinalityProof, error) {
+	var proof FinalityProof
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return proof, err
+	}
+	err = json.Unmarshal(data, &proof)
+	return proof, err
+}
+
+// proofInspectCommand implements: zellular proof inspect FILE
+func proofInspectCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: zellular proof inspect FILE")
+	}
+	proof, err := loadProof(args[0])
+	if err != nil {
+		return err
+	}
+	return writeJSON(struct {
+		FinalityProof
+		Message string `json:"message"`
+	}{proof, proof.Message()})
+}
+
+// proofVerifyCommand implements: zellular proof verify -snapshot FILE -threshold P FILE
+func proofVerifyCommand(args []string) error {
+	flags := flag.NewFlagSet("proof verify", flag.ExitOnError)
+	snapshot := flags.String("snapshot", "", "operator snapshot exported with ExportState")
+	threshold := flags.Float64("threshold", 67, "threshold percent")
+	flags.Parse(args)
+	if *snapshot == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("proof verify: -snapshot and a proof file are required")
+	}
+
+	proof, err := loadProof(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	operators, err := loadSnapshot(*snapshot)
+	if err != nil {
+		return err
+	}
+	result, err := NewOfflineVerifier(proof.AppName, operators, *threshold).VerifyProof(proof)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(struct {
+		Status string       `json:"status"`
+		Reason string       `json:"reason,omitempty"`
+		Quorum QuorumReport `json:"quorum"`
+	}{result.Status.String(), result.Reason, result.Quorum}); err != nil {
+		return err
+	}
+	if !result.Valid() {
+		return fmt.Errorf("proof verify: %s", result.Status)
+	}
+	return nil
+}
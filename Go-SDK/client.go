@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// mockPollInterval is how often a MockClient subscription checks for batches
+const mockPollInterval = 10 * time.Millisecond
+
+// Client is what applications need from Zellular, so the real client, the
+// sandbox (itself a *Zellular) and MockClient can be swapped for each other
+type Client interface {
+	// Send submits a batch of transactions
+	Send(ctx context.Context, txs interface{}) error
+	// Subscribe returns the finalized batches following index after
+	Subscribe(after int) Subscription
+	// VerifyProof checks a finality proof
+	VerifyProof(p FinalityProof) (VerificationResult, error)
+	// NodeInfo describes the node the client reads from
+	NodeInfo(ctx context.Context) (NodeInfo, error)
+}
+
+// Subscription yields finalized batches in index order
+type Subscription interface {
+	Next(ctx context.Context) (Batch, error)
+}
+
+// NodeInfo describes a node serving an app
+type NodeInfo struct {
+	Node            string `json:"node"`
+	AppName         string `json:"app_name"`
+	Network         string `json:"network,omitempty"`
+	LatestFinalized int    `json:"latest_finalized"`
+	Operators       int    `json:"operators"`
+}
+
+var _ Client = (*Zellular)(nil)
+
+// Subscribe implements Client with Stream
+func (z *Zellular) Subscribe(after int) Subscription {
+	return z.Stream(after)
+}
+
+// NodeInfo asks the base node, or the node in the call options, for its
+// latest finalized batch
+func (z *Zellular) NodeInfo(ctx context.Context) (NodeInfo, error) {
+	node := nodeFor(ctx, z.base())
+	info := NodeInfo{Node: node, AppName: z.AppName, Network: z.networkID, Operators: len(z.operators())}
+	marker, err := z.fetchLastFinalized(ctx, node)
+	if err != nil {
+		return info, err
+	}
+	info.LatestFinalized = marker.Index
+	return info, nil
+}
+
+// MockClient is a Client for tests. Sent batches are recorded and, unless
+// SendFunc is set, appended to Batches, which subscriptions serve; proofs
+// are accepted unless VerifyFunc says otherwise.
+type MockClient struct {
+	mu sync.Mutex
+	// Batches are the finalized batches, Batches[i] having index i+1
+	Batches    []string
+	Sent       []interface{}
+	SendFunc   func(ctx context.Context, txs interface{}) error
+	VerifyFunc func(p FinalityProof) (VerificationResult, error)
+	Info       NodeInfo
+}
+
+var _ Client = (*MockClient)(nil)
+
+// Send implements Client
+func (m *MockClient) Send(ctx context.Context, txs interface{}) error {
+	m.mu.Lock()
+	m.Sent = append(m.Sent, txs)
+	m.mu.Unlock()
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, txs)
+	}
+	payload, _, err := encodeBatch(txs, 0)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.Batches = append(m.Batches, string(payload))
+	m.mu.Unlock()
+	return nil
+}
+
+// Subscribe implements Client; Next blocks until a batch is available or ctx is done
+func (m *MockClient) Subscribe(after int) Subscription {
+	return &mockSubscription{m: m, after: after}
+}
+
+// VerifyProof implements Client
+func (m *MockClient) VerifyProof(p FinalityProof) (VerificationResult, error) {
+	if m.VerifyFunc != nil {
+		return m.VerifyFunc(p)
+	}
+	return VerificationResult{Status: Verified}, nil
+}
+
+// NodeInfo implements Client, filling LatestFinalized from Batches
+func (m *MockClient) NodeInfo(ctx context.Context) (NodeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info := m.Info
+	info.LatestFinalized = len(m.Batches)
+	return info, nil
+}
+
+// mockSubscription polls a MockClient's Batches
+type mockSubscription struct {
+	m     *MockClient
+	after int
+}
+
+func (s *mockSubscription) Next(ctx context.Context) (Batch, error) {
+	for {
+		s.m.mu.Lock()
+		if s.after < len(s.m.Batches) {
+			s.after++
+			batch := Batch{Index: s.after, Payload: s.m.Batches[s.after-1]}
+			s.m.mu.Unlock()
+			return batch, nil
+		}
+		s.m.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return Batch{}, ctx.Err()
+		case <-time.After(mockPollInterval):
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+)
+
+// ChunkScheme verifies a chunk of an erasure-coded batch against the
+// batch's commitment
+type ChunkScheme interface {
+	// Name is the scheme named by BatchCommitment.Scheme, e.g. "kzg"
+	Name() string
+	// VerifyChunk returns an error describing why chunk does not open commitment
+	VerifyChunk(commitment BatchCommitment, chunk Chunk) error
+}
+
+// WithChunkScheme verifies the chunks of commitments naming scheme with it.
+// The Merkle scheme is registered by default.
+func WithChunkScheme(scheme ChunkScheme) Option {
+	return func(z *Zellular) {
+		chunkSchemes := make(map[string]ChunkScheme, len(z.chunkSchemes)+1)
+		for name, s := range z.chunkSchemes {
+			chunkSchemes[name] = s
+		}
+		chunkSchemes[scheme.Name()] = scheme
+		z.chunkSchemes = chunkSchemes
+	}
+}
+
+// chunkScheme returns the registered scheme called name
+func (z *Zellular) chunkScheme(name string) (ChunkScheme, error) {
+	if name == "" {
+		name = "merkle"
+	}
+	if scheme, ok := z.chunkSchemes[name]; ok {
+		return scheme, nil
+	}
+	if name == "merkle" {
+		return MerkleScheme{}, nil
+	}
+	return nil, fmt.Errorf("zellular: no chunk scheme %q registered", name)
+}
+
+// MerkleScheme commits to chunks with the root of a binary SHA-256 tree
+// whose leaves and inner nodes are prefixed with 0 and 1 as in RFC 6962.
+// A chunk's proof lists the sibling hashes from its leaf up.
+type MerkleScheme struct{}
+
+// Name implements ChunkScheme
+func (MerkleScheme) Name() string {
+	return "merkle"
+}
+
+// VerifyChunk implements ChunkScheme
+func (MerkleScheme) VerifyChunk(commitment BatchCommitment, chunk Chunk) error {
+	if !verifyMerkleProof(commitment.Root, chunk.Data, chunk.Index, chunk.Proof) {
+		return errors.New("invalid merkle proof")
+	}
+	return nil
+}
+
+// verifyMerkleProof checks data against root as leaf index of a MerkleScheme tree
+func verifyMerkleProof(root string, data []byte, index int, proof []string) bool {
+	node := sha256.Sum256(append([]byte{0}, data...))
+	for _, sibling := range proof {
+		s, err := hex.DecodeString(sibling)
+		if err != nil || len(s) != sha256.Size {
+			return false
+		}
+		h := sha256.New()
+		h.Write([]byte{1})
+		if index%2 == 0 {
+			h.Write(node[:])
+			h.Write(s)
+		} else {
+			h.Write(s)
+			h.Write(node[:])
+		}
+		copy(node[:], h.Sum(nil))
+		index /= 2
+	}
+	return index == 0 && hex.EncodeToString(node[:]) == root
+}
+
+// KZGScheme commits to chunks with a KZG commitment over BLS12-381 to the
+// polynomial taking chunk i at the i-th power of the generator of the
+// domain of size Chunks, which must be a power of two. Each chunk is one
+// canonical 32-byte field element; its proof is the hex compressed G1
+// opening proof. Root is the hex compressed G1 commitment.
+type KZGScheme struct {
+	VerifyingKey kzg.VerifyingKey
+}
+
+// LoadKZGSetup reads the verifying key from a trusted setup SRS file in
+// gnark-crypto's serialization
+func LoadKZGSetup(path string) (*KZGScheme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var srs kzg.SRS
+	if _, err := srs.ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("zellular: reading KZG setup %s: %w", path, err)
+	}
+	return &KZGScheme{VerifyingKey: srs.Vk}, nil
+}
+
+// Name implements ChunkScheme
+func (s *KZGScheme) Name() string {
+	return "kzg"
+}
+
+// VerifyChunk implements ChunkScheme
+func (s *KZGScheme) VerifyChunk(commitment BatchCommitment, chunk Chunk) error {
+	if commitment.Chunks&(commitment.Chunks-1) != 0 {
+		return fmt.Errorf("chunk count %d is not a power of two", commitment.Chunks)
+	}
+	if chunk.Index < 0 || chunk.Index >= commitment.Chunks {
+		return fmt.Errorf("chunk index %d out of range", chunk.Index)
+	}
+	if len(chunk.Proof) != 1 {
+		return errors.New("kzg proof must be a single opening proof")
+	}
+	var digest kzg.Digest
+	if err := decodeG1Hex(&digest, commitment.Root); err != nil {
+		return fmt.Errorf("malformed commitment: %w", err)
+	}
+	var proof kzg.OpeningProof
+	if err := decodeG1Hex(&proof.H, chunk.Proof[0]); err != nil {
+		return fmt.Errorf("malformed opening proof: %w", err)
+	}
+	if err := proof.ClaimedValue.SetBytesCanonical(chunk.Data); err != nil {
+		return fmt.Errorf("chunk is not a field element: %w", err)
+	}
+	domain := fft.NewDomain(uint64(commitment.Chunks))
+	var point fr.Element
+	point.Exp(domain.Generator, big.NewInt(int64(chunk.Index)))
+	return kzg.Verify(&digest, &proof, point, s.VerifyingKey)
+}
+
+// decodeG1Hex decodes a hex compressed G1 point, checking it is in the subgroup
+func decodeG1Hex(p *bls12381.G1Affine, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	_, err = p.SetBytes(b)
+	return err
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+)
+
+func TestMerkleSchemeOpenings(t *testing.T) {
+	root, chunks := merkleChunks([4][]byte{[]byte("c0"), []byte("c1"), []byte("c2"), []byte("c3")})
+	commitment := BatchCommitment{Root: root, Chunks: 4, DataChunks: 2}
+	for _, chunk := range chunks {
+		if err := (MerkleScheme{}).VerifyChunk(commitment, chunk); err != nil {
+			t.Fatalf("chunk %d: %v", chunk.Index, err)
+		}
+	}
+
+	tampered := chunks[1]
+	tampered.Data = []byte("cx")
+	moved := chunks[1]
+	moved.Index = 3
+	for _, chunk := range []Chunk{tampered, moved} {
+		if err := (MerkleScheme{}).VerifyChunk(commitment, chunk); err == nil {
+			t.Fatalf("chunk %+v verified", chunk)
+		}
+	}
+}
+
+func TestKZGSchemeOpenings(t *testing.T) {
+	// a toy setup; real deployments load a ceremony's with LoadKZGSetup
+	srs, err := kzg.NewSRS(4, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := &KZGScheme{VerifyingKey: srs.Vk}
+	polynomial := make([]fr.Element, 4)
+	for i := range polynomial {
+		polynomial[i].SetUint64(uint64(7*i + 3))
+	}
+	digest, err := kzg.Commit(polynomial, srs.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := digest.Bytes()
+	commitment := BatchCommitment{Scheme: "kzg", Root: hex.EncodeToString(root[:]), Chunks: 4, DataChunks: 4}
+
+	domain := fft.NewDomain(4)
+	var chunks []Chunk
+	for i := 0; i < 4; i++ {
+		var point fr.Element
+		point.Exp(domain.Generator, big.NewInt(int64(i)))
+		proof, err := kzg.Open(polynomial, point, srs.Pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, h := proof.ClaimedValue.Bytes(), proof.H.Bytes()
+		chunks = append(chunks, Chunk{Index: i, Data: value[:], Proof: []string{hex.EncodeToString(h[:])}})
+	}
+	for _, chunk := range chunks {
+		if err := scheme.VerifyChunk(commitment, chunk); err != nil {
+			t.Fatalf("chunk %d: %v", chunk.Index, err)
+		}
+	}
+
+	tampered := chunks[2]
+	var value fr.Element
+	value.SetBytes(tampered.Data)
+	value.Add(&value, new(fr.Element).SetOne())
+	b := value.Bytes()
+	tampered.Data = b[:]
+	moved := chunks[2]
+	moved.Index = 1
+	unreduced := chunks[0]
+	unreduced.Data = fr.Modulus().Bytes()
+	for _, chunk := range []Chunk{tampered, moved, unreduced} {
+		if err := scheme.VerifyChunk(commitment, chunk); err == nil {
+			t.Fatalf("chunk %+v verified", chunk)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"expvar"
+	"time"
+)
+
+// captureCoefficient publishes the latest CaptureCoefficient as "zellular_capture_coefficient"
+var captureCoefficient = expvar.NewInt("zellular_capture_coefficient")
+
+// ConcentrationEvent warns that the threshold can be met by fewer
+// operators than the configured minimum
+type ConcentrationEvent struct {
+	AppName            string
+	CaptureCoefficient int
+	MinOperators       int
+	// Coalition is the smallest set of operators able to finalize alone
+	Coalition []OperatorStake
+	Time      time.Time
+}
+
+// EventKind implements Event
+func (e *ConcentrationEvent) EventKind() string {
+	return "stake_concentration"
+}
+
+// WithConcentrationAlert raises a ConcentrationEvent whenever the operator
+// set is loaded and fewer than minOperators can together meet the threshold
+func WithConcentrationAlert(minOperators int) Option {
+	return func(z *Zellular) {
+		z.minCoalition = minOperators
+	}
+}
+
+// checkConcentration publishes the capture coefficient and alerts if it is too small
+func (z *Zellular) checkConcentration() {
+	info := z.NetworkInfo()
+	captureCoefficient.Set(int64(info.CaptureCoefficient))
+	if z.minCoalition == 0 || info.CaptureCoefficient == 0 || info.CaptureCoefficient >= z.minCoalition {
+		return
+	}
+	z.emit(&ConcentrationEvent{
+		AppName:            z.AppName,
+		CaptureCoefficient: info.CaptureCoefficient,
+		MinOperators:       z.minCoalition,
+		Coalition:          info.Distribution[:info.CaptureCoefficient],
+		Time:               time.Now(),
+	})
+}
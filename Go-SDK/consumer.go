@@ -0,0 +1,548 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Checkpoint is the position a consumer resumes from
+type Checkpoint struct {
+	Index        int    `json:"index"`
+	ChainingHash string `json:"chaining_hash"`
+	// Seq counts the saves of the checkpoint
+	Seq uint64 `json:"seq,omitempty"`
+	// Epoch is the leader election epoch of the replica that saved the
+	// checkpoint, zero without an Elector
+	Epoch uint64 `json:"epoch,omitempty"`
+	// Checksum detects corrupted checkpoints; checkpoints saved before it
+	// was introduced have none
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// checksum returns the checksum of cp as a checkpoint of appName
+func (cp Checkpoint) checksum(appName string) string {
+	fields := fmt.Sprintf("%s|%d|%s|%d", appName, cp.Index, cp.ChainingHash, cp.Seq)
+	if cp.Epoch > 0 {
+		fields += fmt.Sprintf("|%d", cp.Epoch)
+	}
+	sum := sha256.Sum256([]byte(fields))
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkpointKey returns the key of one of the two slots checkpoints are
+// written to alternately, so a torn or corrupted write leaves the previous
+// checkpoint intact
+func checkpointKey(appName string, slot uint64) string {
+	if slot == 0 {
+		return "checkpoints/" + appName
+	}
+	return "checkpoints/" + appName + ".1"
+}
+
+// loadCheckpoint reads the latest intact checkpoint of an app, the zero
+// checkpoint if none was saved. A checkpoint of a later election epoch is
+// newer whatever its Seq. A corrupted slot is skipped in favour of the
+// other, older one.
+func loadCheckpoint(store KVStore, appName string) (Checkpoint, error) {
+	var latest Checkpoint
+	found := false
+	var corrupted []error
+	for slot := uint64(0); slot < 2; slot++ {
+		data, err := store.Get(checkpointKey(appName, slot))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Checkpoint{}, err
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			corrupted = append(corrupted, fmt.Errorf("slot %d: %v", slot, err))
+			continue
+		}
+		if cp.Checksum != "" && cp.Checksum != cp.checksum(appName) {
+			corrupted = append(corrupted, fmt.Errorf("slot %d: checksum mismatch", slot))
+			continue
+		}
+		if !found || cp.Epoch > latest.Epoch || cp.Epoch == latest.Epoch && cp.Seq > latest.Seq {
+			latest, found = cp, true
+		}
+	}
+	if len(corrupted) > 0 {
+		if !found {
+			return Checkpoint{}, fmt.Errorf("zellular: every checkpoint of %s is corrupted: %v", appName, corrupted)
+		}
+		log.Printf("zellular: recovered checkpoint %d of %s after corruption: %v", latest.Index, appName, corrupted)
+	}
+	return latest, nil
+}
+
+// saveCheckpoint writes cp as the next checkpoint, advancing its Seq
+func saveCheckpoint(store KVStore, appName string, cp *Checkpoint) error {
+	key, data, err := nextCheckpoint(appName, cp)
+	if err != nil {
+		return err
+	}
+	return store.Put(key, data)
+}
+
+// nextCheckpoint advances the Seq of cp and returns the slot and encoding
+// to save it under
+func nextCheckpoint(appName string, cp *Checkpoint) (string, []byte, error) {
+	cp.Seq++
+	cp.Checksum = cp.checksum(appName)
+	data, err := json.Marshal(cp)
+	return checkpointKey(appName, cp.Seq%2), data, err
+}
+
+// save writes the consumer's checkpoint. With an Elector the write is
+// fenced: it fails with ErrFenced unless the replica still holds the lease
+// of the epoch it was elected in and no later epoch saved a checkpoint, and
+// on a CASStore the slot is only replaced if unchanged since that check.
+func (c *Consumer) save(cp *Checkpoint) error {
+	if c.Elector == nil {
+		return saveCheckpoint(c.store, c.z.AppName, cp)
+	}
+	if err := c.Elector.fence(c.epoch); err != nil {
+		return err
+	}
+	stored, err := loadCheckpoint(c.store, c.z.AppName)
+	if err != nil {
+		return err
+	}
+	if stored.Epoch > c.epoch {
+		return ErrFenced
+	}
+	cp.Epoch = c.epoch
+	if stored.Seq > cp.Seq {
+		cp.Seq = stored.Seq
+	}
+	key, data, err := nextCheckpoint(c.z.AppName, cp)
+	if err != nil {
+		return err
+	}
+	cas, ok := c.store.(CASStore)
+	if !ok {
+		return c.store.Put(key, data)
+	}
+	old, err := c.store.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		old, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	var previous Checkpoint
+	if old != nil && json.Unmarshal(old, &previous) == nil && previous.Epoch > c.epoch {
+		return ErrFenced
+	}
+	swapped, err := cas.CompareAndSwap(key, old, data)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return ErrFenced
+	}
+	return nil
+}
+
+// CheckpointAheadError is returned when the stored checkpoint is past the
+// last finalized index of every node that answered
+type CheckpointAheadError struct {
+	Checkpoint Checkpoint
+	Latest     int
+}
+
+func (e *CheckpointAheadError) Error() string {
+	return fmt.Sprintf("zellular: checkpoint %d is ahead of the latest finalized index %d; use ResetTo to move it back", e.Checkpoint.Index, e.Latest)
+}
+
+// CheckpointResetEvent records a checkpoint moved by ResetTo
+type CheckpointResetEvent struct {
+	AppName string
+	From    Checkpoint
+	To      Checkpoint
+	Time    time.Time
+}
+
+// EventKind implements Event
+func (e *CheckpointResetEvent) EventKind() string {
+	return "checkpoint_reset"
+}
+
+// Handler processes one finalized batch
+type Handler func(ctx context.Context, batch Batch) error
+
+// Validator checks that a batch makes sense to the application. It only
+// sees batches whose finalization the stream verified, unless the client
+// was created WithoutVerification.
+type Validator func(batch Batch) error
+
+// InvalidBatchPolicy decides what a consumer does with a batch its Validator rejects
+type InvalidBatchPolicy int
+
+const (
+	// HaltOnInvalid stops the consumer without advancing the checkpoint
+	HaltOnInvalid InvalidBatchPolicy = iota
+	// SkipInvalid logs the batch and moves past it without handling it
+	SkipInvalid
+	// DeadLetterInvalid hands the batch to the consumer's DeadLetters store
+	// and moves past it
+	DeadLetterInvalid
+)
+
+// Ordering controls whether a consumer may run handlers concurrently
+type Ordering int
+
+const (
+	// Ordered hands batches to the handler one at a time, in index order
+	Ordered Ordering = iota
+	// Unordered runs up to MaxInFlight handlers at once; they may finish in
+	// any order, but the checkpoint only advances past batches whose
+	// handlers and all predecessors' handlers have returned
+	Unordered
+)
+
+// Backpressure decides what an Unordered consumer does when MaxInFlight
+// handlers are already running
+type Backpressure int
+
+const (
+	// BlockWhenFull waits for a handler to return before fetching more
+	BlockWhenFull Backpressure = iota
+	// FailWhenFull stops the consumer with ErrHandlerBehind
+	FailWhenFull
+)
+
+// ErrHandlerBehind is returned under FailWhenFull when every handler slot is busy
+var ErrHandlerBehind = errors.New("zellular: batch handlers are falling behind")
+
+// ValidationError is returned when a consumer halts on an invalid batch
+type ValidationError struct {
+	Batch Batch
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("zellular: batch %d rejected by validator: %v", e.Batch.Index, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Consumer hands finalized batches to a Handler and checkpoints its position
+// in a KVStore after each one, so a restarted consumer resumes where it left off.
+// Pause holds the consumer before its next batch until Resume.
+type Consumer struct {
+	pauseGate
+
+	Handler Handler
+	// Validator, when set, is called after the batch's finalization was
+	// verified and before the batch is handled and the checkpoint advances
+	Validator Validator
+	OnInvalid InvalidBatchPolicy
+	// DeadLetters receives rejected batches under DeadLetterInvalid
+	DeadLetters DeadLetterStore
+	// Ordering defaults to Ordered
+	Ordering Ordering
+	// MaxInFlight caps concurrent handler invocations under Unordered
+	MaxInFlight int
+	// Backpressure applies once MaxInFlight handlers are running
+	Backpressure Backpressure
+	// Filter, when set, limits the batches the consumer validates and handles
+	Filter BatchFilter
+	// Elector, when set, limits processing to the elected replica and
+	// fences off checkpoint writes of replicas that lost the election
+	Elector *LeaderElector
+
+	z     *Zellular
+	store KVStore
+	// epoch is the election epoch of the current leadership term
+	epoch uint64
+}
+
+// NewConsumer creates a consumer of z's app checkpointing into store
+func (z *Zellular) NewConsumer(store KVStore, handler Handler) *Consumer {
+	return &Consumer{Handler: handler, z: z, store: store}
+}
+
+// ResetTo moves the stored checkpoint to index and the chaining hash after
+// it, an escape hatch for checkpoints that are ahead of the network or
+// otherwise unusable. It must not be called while Run is running. The
+// reset is logged and raised as a CheckpointResetEvent.
+func (c *Consumer) ResetTo(index int, chainingHash string) error {
+	from, err := loadCheckpoint(c.store, c.z.AppName)
+	if err != nil {
+		log.Printf("zellular: resetting unreadable checkpoint of %s: %v", c.z.AppName, err)
+	}
+	to := Checkpoint{Index: index, ChainingHash: chainingHash, Seq: from.Seq}
+	if err := saveCheckpoint(c.store, c.z.AppName, &to); err != nil {
+		return err
+	}
+	log.Printf("zellular: checkpoint of %s reset from %d to %d", c.z.AppName, from.Index, index)
+	c.z.emit(&CheckpointResetEvent{AppName: c.z.AppName, From: from, To: to, Time: time.Now()})
+	return nil
+}
+
+// checkAhead fails if cp is past every node's last finalized index
+func (c *Consumer) checkAhead(ctx context.Context, cp Checkpoint) error {
+	if cp.Index == 0 {
+		return nil
+	}
+	indexes, _ := c.z.LastFinalizedAll(ctx)
+	if len(indexes) == 0 {
+		return nil
+	}
+	latest := 0
+	for _, index := range indexes {
+		if index > latest {
+			latest = index
+		}
+	}
+	if cp.Index > latest {
+		return &CheckpointAheadError{Checkpoint: cp, Latest: latest}
+	}
+	return nil
+}
+
+// stream returns a stream resuming at cp
+func (c *Consumer) stream(cp Checkpoint) *BatchStream {
+	stream := c.z.StreamFrom(cp.Index, cp.ChainingHash)
+	stream.Filter = c.Filter
+	return stream
+}
+
+// Run consumes batches until ctx is done, the handler fails or a batch is
+// rejected under HaltOnInvalid. With an Elector, only the leader handles
+// batches and checkpoints; a standby tails the stream read-only and takes
+// over from the stored checkpoint when it is elected.
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.Elector == nil {
+		return c.run(ctx)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go c.Elector.Campaign(ctx)
+
+	for {
+		leader, epoch, changed := c.Elector.watch()
+		termCtx, endTerm := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+			case <-termCtx.Done():
+			}
+			endTerm()
+		}()
+		var err error
+		if leader {
+			c.epoch = epoch
+			err = c.run(termCtx)
+		} else {
+			err = c.tail(termCtx)
+		}
+		endTerm()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrFenced) {
+			// another replica took over; stand by until elected again
+			c.Elector.setLeader(false, 0)
+			continue
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+}
+
+// tail follows the stream from the stored checkpoint without handling or
+// checkpointing anything
+func (c *Consumer) tail(ctx context.Context) error {
+	cp, err := loadCheckpoint(c.store, c.z.AppName)
+	if err != nil {
+		return err
+	}
+	stream := c.stream(cp)
+	for {
+		if _, err := stream.Next(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// run consumes batches from the stored checkpoint
+func (c *Consumer) run(ctx context.Context) error {
+	cp, err := loadCheckpoint(c.store, c.z.AppName)
+	if err != nil {
+		return err
+	}
+	if err := c.checkAhead(ctx, cp); err != nil {
+		return err
+	}
+	stream := c.stream(cp)
+	if c.Ordering == Unordered && c.MaxInFlight > 1 {
+		return c.runUnordered(ctx, stream, cp)
+	}
+
+	for {
+		if err := c.wait(ctx); err != nil {
+			return err
+		}
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if err := c.process(ctx, batch); err != nil {
+			return err
+		}
+		cp.Index, cp.ChainingHash = batch.Index, batch.ChainingHash
+		if err := c.save(&cp); err != nil {
+			return err
+		}
+	}
+}
+
+// handledBatch is the outcome of one concurrent handler invocation
+type handledBatch struct {
+	batch Batch
+	err   error
+}
+
+// runUnordered runs up to MaxInFlight handlers at once, checkpointing the
+// highest index below which every batch has been handled
+func (c *Consumer) runUnordered(ctx context.Context, stream *BatchStream, cp Checkpoint) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan handledBatch, c.MaxInFlight)
+	// pending holds the indices taken from the stream, oldest first, and
+	// done the batches among them whose handlers returned
+	var pending []int
+	done := make(map[int]Batch)
+	inflight := 0
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	complete := func(r handledBatch) {
+		if r.err != nil {
+			fail(r.err)
+			return
+		}
+		done[r.batch.Index] = r.batch
+		advanced := false
+		for len(pending) > 0 {
+			batch, ok := done[pending[0]]
+			if !ok {
+				break
+			}
+			delete(done, batch.Index)
+			pending = pending[1:]
+			cp.Index, cp.ChainingHash = batch.Index, batch.ChainingHash
+			advanced = true
+		}
+		if advanced && firstErr == nil {
+			if err := c.save(&cp); err != nil {
+				fail(err)
+			}
+		}
+	}
+
+	for firstErr == nil {
+		if err := c.wait(ctx); err != nil {
+			fail(err)
+			break
+		}
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			fail(err)
+			break
+		}
+		handle, err := c.validate(batch)
+		if err != nil {
+			fail(err)
+			break
+		}
+		pending = append(pending, batch.Index)
+		if !handle {
+			complete(handledBatch{batch: batch})
+			continue
+		}
+		for inflight == c.MaxInFlight && firstErr == nil {
+			if c.Backpressure == FailWhenFull {
+				fail(ErrHandlerBehind)
+				break
+			}
+			inflight--
+			complete(<-results)
+		}
+		if firstErr != nil {
+			break
+		}
+		inflight++
+		go func(batch Batch) {
+			results <- handledBatch{batch: batch, err: c.Handler(ctx, batch)}
+		}(batch)
+	drain:
+		for {
+			select {
+			case r := <-results:
+				inflight--
+				complete(r)
+			default:
+				break drain
+			}
+		}
+	}
+
+	for ; inflight > 0; inflight-- {
+		complete(<-results)
+	}
+	return firstErr
+}
+
+// process validates and handles one batch
+func (c *Consumer) process(ctx context.Context, batch Batch) error {
+	handle, err := c.validate(batch)
+	if err != nil || !handle {
+		return err
+	}
+	return c.Handler(ctx, batch)
+}
+
+// validate runs the Validator and applies OnInvalid, reporting whether the
+// batch should be handled
+func (c *Consumer) validate(batch Batch) (bool, error) {
+	if c.Validator == nil {
+		return true, nil
+	}
+	err := c.Validator(batch)
+	if err == nil {
+		return true, nil
+	}
+	switch c.OnInvalid {
+	case HaltOnInvalid:
+		return false, &ValidationError{Batch: batch, Err: err}
+	case DeadLetterInvalid:
+		if c.DeadLetters == nil {
+			return false, &ValidationError{Batch: batch, Err: err}
+		}
+		if err := c.DeadLetters.Put(newDeadLetter(c.z.AppName, batch, err)); err != nil {
+			return false, fmt.Errorf("zellular: dead-lettering batch %d: %w", batch.Index, err)
+		}
+		return false, nil
+	}
+	log.Printf("zellular: skipping batch %d of %s: %v", batch.Index, c.z.AppName, err)
+	return false, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConsumerValidatesOnlyVerifiedBatches(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`})
+	sandbox.batches[0].payload = `["x"]`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumer := z.NewConsumer(NewMemoryKVStore(), func(ctx context.Context, batch Batch) error {
+		t.Errorf("handled unverified batch %d", batch.Index)
+		return nil
+	})
+	consumer.Validator = func(batch Batch) error {
+		t.Errorf("validated unverified batch %d", batch.Index)
+		return nil
+	}
+	if err := consumer.Run(ctx); err == nil || err == context.DeadlineExceeded {
+		t.Fatalf("Run = %v, want a verification error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// Credential is a header carrying an API key or token, such as
+// Authorization: Bearer <token>
+type Credential struct {
+	Header string
+	Value  *Secret
+}
+
+// credentials keeps the read and submission credentials apart. Read
+// credentials go with reads from nodes and batch submissions carry only the
+// submit credential, so a read-only deployment holding just the read
+// credential cannot submit on behalf of the app. Requests to other hosts,
+// such as the subgraph, discovery or an Ethereum RPC, carry neither.
+type credentials struct {
+	read   *Credential
+	submit *Credential
+}
+
+// WithReadCredential authenticates queries and streams sent to nodes
+func WithReadCredential(header string, value *Secret) Option {
+	return func(z *Zellular) {
+		z.transport.credentials.read = &Credential{Header: header, Value: value}
+	}
+}
+
+// WithSubmitCredential authenticates batch submissions, and only them
+func WithSubmitCredential(header string, value *Secret) Option {
+	return func(z *Zellular) {
+		z.transport.credentials.submit = &Credential{Header: header, Value: value}
+	}
+}
+
+// requestRole tells which credential, if any, a request carries
+type requestRole int
+
+const (
+	noRole requestRole = iota
+	readRole
+	submitRole
+)
+
+// roleKey is the context key of a request's role
+type roleKey struct{}
+
+// withReadRole marks ctx as belonging to a read from a node
+func withReadRole(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roleKey{}, readRole)
+}
+
+// withSubmitRole marks ctx as belonging to a batch submission
+func withSubmitRole(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roleKey{}, submitRole)
+}
+
+// apply sets the credential for the role of ctx on req. Requests without a
+// role are not sent to nodes and get no credential.
+func (c credentials) apply(ctx context.Context, req *http.Request) {
+	var credential *Credential
+	switch role, _ := ctx.Value(roleKey{}).(requestRole); role {
+	case readRole:
+		credential = c.read
+	case submitRole:
+		credential = c.submit
+	}
+	if credential != nil && credential.Value != nil {
+		req.Header.Set(credential.Header, string(credential.Value.Bytes()))
+	}
+}
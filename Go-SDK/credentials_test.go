@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCredentialsFollowRole(t *testing.T) {
+	c := credentials{
+		read:   &Credential{Header: "X-Read", Value: NewSecret([]byte("r"))},
+		submit: &Credential{Header: "X-Submit", Value: NewSecret([]byte("s"))},
+	}
+	for _, tc := range []struct {
+		name         string
+		ctx          context.Context
+		read, submit string
+	}{
+		{"subgraph", context.Background(), "", ""},
+		{"node read", withReadRole(context.Background()), "r", ""},
+		{"submission", withSubmitRole(context.Background()), "", "s"},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		c.apply(tc.ctx, req)
+		if req.Header.Get("X-Read") != tc.read || req.Header.Get("X-Submit") != tc.submit {
+			t.Errorf("%s: got read %q submit %q", tc.name, req.Header.Get("X-Read"), req.Header.Get("X-Submit"))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// cursorVersion prefixes cursor tokens so their encoding can change
+const cursorVersion = "v1."
+
+// Cursor is a stream position: the last batch released and the chaining
+// hash after it, with the app, network and operator set epoch it was taken in
+type Cursor struct {
+	AppName      string `json:"app"`
+	Network      string `json:"network,omitempty"`
+	Index        int    `json:"index"`
+	ChainingHash string `json:"chaining_hash,omitempty"`
+	// Epoch is the digest of the operator set when the cursor was taken
+	Epoch string `json:"epoch,omitempty"`
+}
+
+// Token encodes the cursor as an opaque string safe to store anywhere
+func (c Cursor) Token() string {
+	data, _ := json.Marshal(c)
+	return cursorVersion + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token produced by Cursor.Token
+func ParseCursor(token string) (Cursor, error) {
+	var c Cursor
+	if !strings.HasPrefix(token, cursorVersion) {
+		return c, errors.New("zellular: unsupported cursor token")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, cursorVersion))
+	if err != nil {
+		return c, fmt.Errorf("zellular: malformed cursor token: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("zellular: malformed cursor token: %w", err)
+	}
+	return c, nil
+}
+
+// Cursor returns the position after the last batch Next returned
+func (s *BatchStream) Cursor() Cursor {
+	return Cursor{
+		AppName:      s.z.AppName,
+		Network:      s.z.networkID,
+		Index:        s.released,
+		ChainingHash: s.releasedHash,
+		Epoch:        s.z.OperatorSetDigest(),
+	}
+}
+
+// StreamFromCursor resumes a stream from a cursor token of the same app
+// and network. Cursors without a chaining hash resume like Stream.
+func (z *Zellular) StreamFromCursor(token string) (*BatchStream, error) {
+	c, err := ParseCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if c.AppName != z.AppName {
+		return nil, fmt.Errorf("zellular: cursor is for app %s", c.AppName)
+	}
+	if err := z.checkNetwork(c.Network); err != nil {
+		return nil, err
+	}
+	if c.ChainingHash == "" {
+		return z.Stream(c.Index), nil
+	}
+	return z.StreamFrom(c.Index, c.ChainingHash), nil
+}
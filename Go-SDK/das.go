@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// BatchCommitment is the finalized payload of an erasure-coded batch: the
+// commitment to its coded chunks and how many of them reconstruct it
+type BatchCommitment struct {
+	// Scheme names the ChunkScheme of Root, "merkle" when empty
+	Scheme     string `json:"scheme,omitempty"`
+	Root       string `json:"root"`
+	Chunks     int    `json:"chunks"`
+	DataChunks int    `json:"data_chunks"`
+}
+
+// Chunk is one coded chunk of a batch with its proof against the commitment
+type Chunk struct {
+	Index int      `json:"index"`
+	Data  []byte   `json:"data"`
+	Proof []string `json:"proof"`
+}
+
+// AvailabilityReport is the outcome of sampling a batch's chunks
+type AvailabilityReport struct {
+	Index      int
+	Commitment BatchCommitment
+	Samples    int
+	Verified   int
+	// Failures maps operators to the samples they failed to serve or
+	// served with an invalid proof
+	Failures map[string]int
+	// Confidence is the probability that a batch whose chunks could not
+	// be reconstructed would have failed at least one of the samples
+	Confidence float64
+}
+
+// SampleAvailability checks that the erasure-coded batch at index can be
+// reconstructed by fetching samples random chunks from different operators
+// and verifying them against the batch's finalized commitment, without
+// downloading the batch.
+func (z *Zellular) SampleAvailability(ctx context.Context, index, samples int) (*AvailabilityReport, error) {
+	if samples < 1 {
+		return nil, fmt.Errorf("zellular: sampling needs at least one sample, got %d", samples)
+	}
+	inclusion, err := z.GetBatch(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	result, err := inclusion.Verify(z)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Valid() {
+		return nil, fmt.Errorf("zellular: batch %d failed verification: %s", index, result.Reason)
+	}
+	var commitment BatchCommitment
+	if err := json.Unmarshal([]byte(inclusion.Batch.Payload), &commitment); err != nil || commitment.Root == "" {
+		return nil, fmt.Errorf("zellular: batch %d carries no chunk commitment", index)
+	}
+	if commitment.DataChunks < 1 || commitment.Chunks < commitment.DataChunks {
+		return nil, fmt.Errorf("zellular: batch %d has an invalid chunk commitment", index)
+	}
+
+	var sockets []string
+	for _, operator := range withSockets(z.operators()) {
+		sockets = append(sockets, operator.Socket)
+	}
+	if len(sockets) == 0 {
+		return nil, errors.New("zellular: no operator to sample from")
+	}
+	// operators serving a withheld batch must not be able to predict which
+	// chunks are sampled or who is asked for them
+	sort.Strings(sockets)
+	order, err := randomPerm(len(sockets))
+	if err != nil {
+		return nil, err
+	}
+	if samples > commitment.Chunks {
+		samples = commitment.Chunks
+	}
+	chunks, err := randomPerm(commitment.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	chunks = chunks[:samples]
+
+	report := &AvailabilityReport{Index: index, Commitment: commitment, Samples: samples, Failures: make(map[string]int)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(socket string, chunk int) {
+			defer wg.Done()
+			err := z.sampleChunk(ctx, socket, index, chunk, commitment)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				z.errors.record(err)
+				report.Failures[z.operatorAt(socket)]++
+				return
+			}
+			report.Verified++
+		}(sockets[order[i%len(sockets)]], chunk)
+	}
+	wg.Wait()
+
+	// a batch that cannot be reconstructed misses more than Chunks-DataChunks
+	// chunks, so each distinct sample finds a served chunk with probability
+	// below DataChunks/Chunks
+	if report.Verified == report.Samples {
+		report.Confidence = 1 - math.Pow(float64(commitment.DataChunks)/float64(commitment.Chunks), float64(report.Verified))
+	}
+	return report, nil
+}
+
+// randomPerm returns a permutation of [0, n) drawn from crypto/rand
+func randomPerm(n int) ([]int, error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j.Int64()] = perm[j.Int64()], perm[i]
+	}
+	return perm, nil
+}
+
+// sampleChunk fetches a chunk from socket and verifies it against the commitment
+func (z *Zellular) sampleChunk(ctx context.Context, socket string, index, chunk int, commitment BatchCommitment) error {
+	url := fmt.Sprintf("%s/node/%s/batches/%d/chunks/%d", socket, z.AppName, index, chunk)
+	resp, err := z.transport.do(withReadRole(ctx), http.MethodGet, url, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("zellular: chunk %d of batch %d from %s: status %d", chunk, index, socket, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data *Chunk `json:"data"`
+	}
+	if err := z.decode(url, body, &response); err != nil {
+		return err
+	}
+	if response.Data == nil || response.Data.Index != chunk {
+		return fmt.Errorf("zellular: %s did not serve chunk %d of batch %d", socket, chunk, index)
+	}
+	scheme, err := z.chunkScheme(commitment.Scheme)
+	if err != nil {
+		return err
+	}
+	if err := scheme.VerifyChunk(commitment, *response.Data); err != nil {
+		z.observeBadData(socket)
+		return fmt.Errorf("zellular: chunk %d of batch %d from %s: %w", chunk, index, socket, err)
+	}
+	return nil
+}
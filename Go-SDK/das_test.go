@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSampleAvailabilityRejectsNoSamples(t *testing.T) {
+	z := newZellular("app", "http://localhost:6001", 67)
+	for _, samples := range []int{0, -1} {
+		if _, err := z.SampleAvailability(context.Background(), 1, samples); err == nil {
+			t.Fatalf("%d samples were accepted", samples)
+		}
+	}
+}
+
+// merkleChunks returns the MerkleScheme root of four chunks and each chunk with its proof
+func merkleChunks(data [4][]byte) (string, []Chunk) {
+	var leaves [4][sha256.Size]byte
+	for i, d := range data {
+		leaves[i] = sha256.Sum256(append([]byte{0}, d...))
+	}
+	inner := func(left, right [sha256.Size]byte) [sha256.Size]byte {
+		return sha256.Sum256(append(append([]byte{1}, left[:]...), right[:]...))
+	}
+	nodes := [2][sha256.Size]byte{inner(leaves[0], leaves[1]), inner(leaves[2], leaves[3])}
+	root := inner(nodes[0], nodes[1])
+
+	chunks := make([]Chunk, len(data))
+	for i, d := range data {
+		sibling, parent := leaves[i^1], nodes[i/2^1]
+		chunks[i] = Chunk{Index: i, Data: d, Proof: []string{hex.EncodeToString(sibling[:]), hex.EncodeToString(parent[:])}}
+	}
+	return hex.EncodeToString(root[:]), chunks
+}
+
+// chunkSandbox finalizes a batch committing to chunks and serves them from its operator
+func chunkSandbox(t *testing.T, root string, chunks []Chunk) *Zellular {
+	t.Helper()
+	z, sandbox := sandboxWith(t, nil)
+	commitment, err := json.Marshal(BatchCommitment{Root: root, Chunks: len(chunks), DataChunks: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// commitments are JSON objects, which submit rejects
+	sandbox.batches = append(sandbox.batches, sandboxBatch{
+		payload:      string(commitment),
+		submitted:    time.Now(),
+		chainingHash: z.chain("", string(commitment)),
+	})
+
+	WithHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		for _, chunk := range chunks {
+			if req.URL.Path != fmt.Sprintf("/node/app/batches/1/chunks/%d", chunk.Index) {
+				continue
+			}
+			body, err := json.Marshal(map[string]interface{}{"data": chunk})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(body)), Request: req}, nil
+		}
+		return sandbox.RoundTrip(req)
+	})})(z)
+	return z
+}
+
+func TestSampleAvailabilityVerifiesMerkleChunks(t *testing.T) {
+	root, chunks := merkleChunks([4][]byte{[]byte("c0"), []byte("c1"), []byte("c2"), []byte("c3")})
+	z := chunkSandbox(t, root, chunks)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, err := z.SampleAvailability(ctx, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Samples != 3 || report.Verified != 3 || len(report.Failures) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if want := 1 - 0.125; report.Confidence != want {
+		t.Fatalf("confidence = %v, want %v", report.Confidence, want)
+	}
+}
+
+func TestSampleAvailabilityReportsTamperedChunk(t *testing.T) {
+	root, chunks := merkleChunks([4][]byte{[]byte("c0"), []byte("c1"), []byte("c2"), []byte("c3")})
+	chunks[2].Data = []byte("withheld")
+	z := chunkSandbox(t, root, chunks)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report, err := z.SampleAvailability(ctx, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verified != 3 || report.Failures[sandboxOperator] != 1 || report.Confidence != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+}
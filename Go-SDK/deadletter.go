@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DeadLetter is a batch the validator rejected, kept with enough context to
+// investigate and replay it
+type DeadLetter struct {
+	AppName      string         `json:"app_name"`
+	Index        int            `json:"index"`
+	Payload      string         `json:"payload"`
+	ChainingHash string         `json:"chaining_hash"`
+	Node         string         `json:"node"`
+	Proof        *FinalityProof `json:"proof,omitempty"`
+	Error        string         `json:"error"`
+	Time         time.Time      `json:"time"`
+}
+
+// DeadLetterStore receives batches routed away by DeadLetterInvalid
+type DeadLetterStore interface {
+	Put(DeadLetter) error
+}
+
+// KVDeadLetterStore keeps dead letters in a KVStore, one key per batch
+type KVDeadLetterStore struct {
+	Store KVStore
+}
+
+func deadLetterKey(appName string, index int) string {
+	return fmt.Sprintf("deadletters/%s/%d", appName, index)
+}
+
+// Put stores a dead letter
+func (s KVDeadLetterStore) Put(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(deadLetterKey(letter.AppName, letter.Index), data)
+}
+
+// Get returns the dead letter of a batch, ErrNotFound if it was not rejected
+func (s KVDeadLetterStore) Get(appName string, index int) (DeadLetter, error) {
+	var letter DeadLetter
+	data, err := s.Store.Get(deadLetterKey(appName, index))
+	if err != nil {
+		return letter, err
+	}
+	err = json.Unmarshal(data, &letter)
+	return letter, err
+}
+
+func newDeadLetter(appName string, batch Batch, err error) DeadLetter {
+	return DeadLetter{
+		AppName:      appName,
+		Index:        batch.Index,
+		Payload:      batch.Payload,
+		ChainingHash: batch.ChainingHash,
+		Node:         batch.Node,
+		Proof:        batch.Proof,
+		Error:        err.Error(),
+		Time:         time.Now(),
+	}
+}
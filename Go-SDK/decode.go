@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxSnippet bounds how much of a response body is quoted in a SchemaError
+const maxSnippet = 512
+
+// SchemaError reports a node response that does not match the expected schema
+type SchemaError struct {
+	Endpoint string
+	// Field is the offending field when known
+	Field string
+	// Detail describes the mismatch, e.g. an unknown field or a wrong type
+	Detail string
+	// Body is the beginning of the response
+	Body string
+	Err  error
+}
+
+func (e *SchemaError) Error() string {
+	msg := fmt.Sprintf("zellular: unexpected response from %s: %s", e.Endpoint, e.Detail)
+	if e.Field != "" {
+		msg += " (field " + e.Field + ")"
+	}
+	return msg
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// WithStrictDecoding fails on node responses containing unknown fields or
+// unexpected types instead of ignoring them, to catch API drift in staging
+func WithStrictDecoding() Option {
+	return func(z *Zellular) {
+		z.strict = true
+	}
+}
+
+// decode unmarshals a node response, rejecting unknown fields in strict mode
+func (z *Zellular) decode(endpoint string, body []byte, v interface{}) error {
+	if !z.strict {
+		return json.Unmarshal(body, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+	return schemaError(endpoint, body, err)
+}
+
+// schemaError describes a decoding failure of the response beginning with body
+func schemaError(endpoint string, body []byte, err error) *SchemaError {
+	schemaErr := &SchemaError{Endpoint: endpoint, Detail: err.Error(), Err: err}
+	if len(body) > maxSnippet {
+		schemaErr.Body = string(body[:maxSnippet])
+	} else {
+		schemaErr.Body = string(body)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		schemaErr.Field = typeErr.Field
+		schemaErr.Detail = fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	return schemaErr
+}
+
+// snippet keeps the first maxSnippet bytes written to it
+type snippet struct {
+	bytes.Buffer
+}
+
+func (s *snippet) Write(p []byte) (int, error) {
+	if room := maxSnippet - s.Len(); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		s.Buffer.Write(p[:room])
+	}
+	return len(p), nil
+}
+
+// decodePage decodes a finalized batches response as it is read from r,
+// keeping no more than limit batches when limit is positive. Batches past
+// the limit are decoded one at a time and dropped. Unknown fields are
+// skipped, or rejected in strict mode.
+func (z *Zellular) decodePage(endpoint string, r io.Reader, limit int) (*finalizedPage, error) {
+	var head snippet
+	decoder := json.NewDecoder(io.TeeReader(r, &head))
+	page, err := z.walkPage(decoder, limit)
+	if err == nil {
+		return page, nil
+	}
+	if !z.strict {
+		return nil, err
+	}
+	return nil, schemaError(endpoint, head.Bytes(), err)
+}
+
+// walkPage reads {"data": page} from decoder
+func (z *Zellular) walkPage(decoder *json.Decoder, limit int) (*finalizedPage, error) {
+	var page *finalizedPage
+	err := z.walkObject(decoder, func(key string) error {
+		if key != "data" {
+			return z.unknownField(decoder, key)
+		}
+		token, err := decoder.Token()
+		if err != nil || token == nil {
+			return err
+		}
+		if token != json.Delim('{') {
+			return fmt.Errorf("json: data is %v, not an object", token)
+		}
+		page = &finalizedPage{}
+		return z.walkFields(decoder, func(key string) error {
+			switch key {
+			case "batches":
+				return walkBatches(decoder, limit, &page.Batches)
+			case "finalized":
+				return decoder.Decode(&page.Finalized)
+			case "first_chaining_hash":
+				return decoder.Decode(&page.FirstChainingHash)
+			case "network":
+				return decoder.Decode(&page.Network)
+			}
+			return z.unknownField(decoder, "data."+key)
+		})
+	})
+	return page, err
+}
+
+// walkObject reads an object from decoder, calling field for each key with
+// the decoder positioned at its value
+func (z *Zellular) walkObject(decoder *json.Decoder, field func(key string) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("json: response is %v, not an object", token)
+	}
+	return z.walkFields(decoder, field)
+}
+
+// walkFields reads the fields of an object whose opening brace was read
+func (z *Zellular) walkFields(decoder *json.Decoder, field func(key string) error) error {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(token.(string)); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// unknownField skips the value of an unknown field, or rejects it in strict mode
+func (z *Zellular) unknownField(decoder *json.Decoder, name string) error {
+	if z.strict {
+		return fmt.Errorf("json: unknown field %q", name)
+	}
+	var skipped json.RawMessage
+	return decoder.Decode(&skipped)
+}
+
+// walkBatches reads the batches array, keeping no more than limit of them
+func walkBatches(decoder *json.Decoder, limit int, batches *[]string) error {
+	token, err := decoder.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("json: batches is %v, not an array", token)
+	}
+	for decoder.More() {
+		if limit > 0 && len(*batches) >= limit {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return err
+			}
+			continue
+		}
+		var batch string
+		if err := decoder.Decode(&batch); err != nil {
+			return err
+		}
+		*batches = append(*batches, batch)
+	}
+	_, err = decoder.Token()
+	return err
+}
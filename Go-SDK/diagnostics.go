@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the process runtime
+type RuntimeStats struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+// ReadRuntimeStats samples the current runtime statistics
+func ReadRuntimeStats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return RuntimeStats{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapObjects:  m.HeapObjects,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	}
+}
+
+var publishRuntimeStats sync.Once
+
+// WithAdminPprof mounts the pprof handlers and expvar metrics, including the
+// runtime stats as "zellular_runtime", on the admin server
+func WithAdminPprof() Option {
+	return func(z *Zellular) {
+		z.adminPprof = true
+		publishRuntimeStats.Do(func() {
+			expvar.Publish("zellular_runtime", expvar.Func(func() interface{} {
+				return ReadRuntimeStats()
+			}))
+		})
+	}
+}
+
+// mountPprof registers the profiling and metrics handlers on mux
+func mountPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+}
+
+// LogRuntimeStats logs runtime statistics every interval until ctx is done
+func LogRuntimeStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := ReadRuntimeStats()
+			log.Printf("zellular: goroutines=%d heap=%d objects=%d gc=%d pause=%s",
+				stats.Goroutines, stats.HeapAlloc, stats.HeapObjects, stats.NumGC,
+				time.Duration(stats.PauseTotalNs))
+		}
+	}
+}
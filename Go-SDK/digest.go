@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// OperatorSetDigest is a deterministic SHA-256 digest of an operator set:
+// every operator's address, operatorId, compressed G2 public key and
+// stake, in address order. Two sets have the same digest only if they
+// would verify signatures identically.
+func OperatorSetDigest(operators map[string]Operator) string {
+	ids := make([]string, 0, len(operators))
+	for id := range operators {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		operator := operators[id]
+		key := operator.PublicKeyG2.Bytes()
+		fmt.Fprintf(h, "%s\n%s\n%x\n%s\n", id, operator.OperatorID, key[:], strconv.FormatFloat(operator.Stake, 'g', -1, 64))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// OperatorSetDigest returns the digest of z's current operator set
+func (z *Zellular) OperatorSetDigest() string {
+	return OperatorSetDigest(z.operators())
+}
+
+// WithPinnedOperatorSet refuses any operator set whose digest differs from
+// digest, so a compromised or mistaken registry source cannot swap keys or
+// stakes without the caller noticing
+func WithPinnedOperatorSet(digest string) Option {
+	return func(z *Zellular) {
+		z.pinnedDigest = digest
+	}
+}
+
+// checkPinned fails if operators do not match the pinned digest
+func (z *Zellular) checkPinned(operators map[string]Operator) error {
+	if z.pinnedDigest == "" {
+		return nil
+	}
+	if digest := OperatorSetDigest(operators); digest != z.pinnedDigest {
+		return fmt.Errorf("zellular: operator set digest %s does not match the pinned %s", digest, z.pinnedDigest)
+	}
+	return nil
+}
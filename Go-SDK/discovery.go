@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/mdns"
+)
+
+// Discovery finds operators for networks that are not published to the subgraph
+type Discovery interface {
+	Discover(ctx context.Context) (map[string]Operator, error)
+}
+
+// WithDiscovery loads operators from d instead of the subgraph
+func WithDiscovery(d Discovery) Option {
+	return func(z *Zellular) {
+		z.discovery = d
+	}
+}
+
+// SocketDiscovery finds where operators listen on networks whose nodes do
+// not publish their sockets to the registry. It only supplies sockets:
+// discovery answers are not authenticated, so keys and stakes always come
+// from the registry.
+type SocketDiscovery interface {
+	// DiscoverSockets returns the sockets it found by operator ID
+	DiscoverSockets(ctx context.Context) (map[string]string, error)
+}
+
+// WithSocketDiscovery takes the sockets of registry operators from d.
+// Operators d finds that are not in the registry are ignored.
+func WithSocketDiscovery(d SocketDiscovery) Option {
+	return func(z *Zellular) {
+		z.sockets = d
+	}
+}
+
+// SRVDiscovery resolves operator sockets from DNS SRV records, e.g.
+// _zellular._tcp.example.org. Each target publishes a TXT record with an
+// "id=" field naming its operator; targets without one are skipped.
+type SRVDiscovery struct {
+	Service string
+	Proto   string
+	Domain  string
+	// Scheme is the URL scheme of the sockets, https when empty
+	Scheme string
+
+	Resolver *net.Resolver
+}
+
+// DiscoverSockets looks up the SRV records and the operator ID of every target
+func (d SRVDiscovery) DiscoverSockets(ctx context.Context) (map[string]string, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, d.Service, d.Proto, d.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := make(map[string]string, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		txt, err := resolver.LookupTXT(ctx, host)
+		if err != nil {
+			continue
+		}
+		if id := operatorIDField(txt); id != "" {
+			sockets[id] = d.scheme() + "://" + net.JoinHostPort(host, strconv.Itoa(int(record.Port)))
+		}
+	}
+	return sockets, nil
+}
+
+func (d SRVDiscovery) scheme() string {
+	if d.Scheme == "" {
+		return "https"
+	}
+	return d.Scheme
+}
+
+// MDNSDiscovery finds operator sockets announced as a service such as
+// "_zellular._tcp" on the local network. Announcements carry the same
+// "id=" field as the TXT records used by SRVDiscovery.
+type MDNSDiscovery struct {
+	Service string
+	Timeout time.Duration
+	// Scheme is the URL scheme of the sockets, http when empty
+	Scheme string
+}
+
+// DiscoverSockets browses the local network for Timeout and returns the
+// socket of every operator that answered
+func (d MDNSDiscovery) DiscoverSockets(ctx context.Context) (map[string]string, error) {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = 3 * time.Second
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	scheme := d.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	entries := make(chan *mdns.ServiceEntry, 64)
+	errc := make(chan error, 1)
+	go func() {
+		params := mdns.DefaultParams(d.Service)
+		params.Timeout = timeout
+		params.Entries = entries
+		errc <- mdns.Query(params)
+		close(entries)
+	}()
+
+	sockets := make(map[string]string)
+	for entry := range entries {
+		addr := entry.AddrV4
+		if addr == nil {
+			addr = entry.AddrV6
+		}
+		id := operatorIDField(entry.InfoFields)
+		if addr == nil || id == "" {
+			continue
+		}
+		sockets[id] = scheme + "://" + net.JoinHostPort(addr.String(), strconv.Itoa(entry.Port))
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return sockets, nil
+}
+
+// operatorIDField returns the operator named by the "id=" field of
+// "key=value" metadata, empty without one
+func operatorIDField(fields []string) string {
+	for _, field := range fields {
+		if key, value, ok := strings.Cut(field, "="); ok && key == "id" {
+			return value
+		}
+	}
+	return ""
+}
+
+// discoverOperators loads operators from the configured discovery backend
+// or the subgraph, takes their sockets from the socket discovery when one is
+// configured and resolves them when a resolver is configured
+func (z *Zellular) discoverOperators() (map[string]Operator, error) {
+	var operators map[string]Operator
+	var err error
+	if z.discovery != nil {
+		operators, err = z.discovery.Discover(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("zellular: operator discovery failed: %w", err)
+		}
+	} else if operators, err = fetchOperators(z.transport); err != nil {
+		return nil, err
+	}
+
+	if z.sockets != nil {
+		sockets, err := z.sockets.DiscoverSockets(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("zellular: socket discovery failed: %w", err)
+		}
+		for id, socket := range sockets {
+			operator, ok := lookupOperator(operators, id)
+			if !ok {
+				continue
+			}
+			if operator.Socket, err = NormalizeSocket(socket); err == nil {
+				operators[operator.ID] = operator
+			}
+		}
+	}
+
+	if z.resolver != nil {
+		z.resolver.resolveSockets(context.Background(), operators)
+	}
+	return operators, nil
+}
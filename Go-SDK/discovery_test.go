@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+type staticDiscovery map[string]Operator
+
+func (d staticDiscovery) Discover(ctx context.Context) (map[string]Operator, error) {
+	operators := make(map[string]Operator, len(d))
+	for id, operator := range d {
+		operators[id] = operator
+	}
+	return operators, nil
+}
+
+type staticSockets map[string]string
+
+func (d staticSockets) DiscoverSockets(ctx context.Context) (map[string]string, error) {
+	return d, nil
+}
+
+func TestSocketDiscoveryOnlySuppliesSockets(t *testing.T) {
+	const id = "0x0000000000000000000000000000000000000001"
+	operator := registryOperator(id, testSecret)
+	operator.Stake = 5
+	z := newZellular("app", "", 67,
+		WithDiscovery(staticDiscovery{id: operator}),
+		WithSocketDiscovery(staticSockets{
+			id: "http://10.0.0.1:6001",
+			"0x0000000000000000000000000000000000000002": "http://10.0.0.2:6001",
+		}))
+
+	operators, err := z.discoverOperators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operators) != 1 {
+		t.Fatalf("discovery added operators missing from the registry: %v", operators)
+	}
+	got := operators[id]
+	if got.Socket != "http://10.0.0.1:6001" || got.Stake != 5 || got.PubkeyG2_X[0] != operator.PubkeyG2_X[0] {
+		t.Fatalf("operator = %+v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OperatorIssue is one problem found with a registry entry
+type OperatorIssue struct {
+	// Check is "address", "operator_id", "public_key", "stake", "socket" or "reachability"
+	Check   string `json:"check"`
+	Problem string `json:"problem"`
+}
+
+// OperatorDiagnosis is the result of checking one registry entry
+type OperatorDiagnosis struct {
+	OperatorID string          `json:"operator_id"`
+	Socket     string          `json:"socket"`
+	RTT        time.Duration   `json:"rtt,omitempty"`
+	Issues     []OperatorIssue `json:"issues,omitempty"`
+}
+
+// Healthy reports whether no issue was found
+func (d OperatorDiagnosis) Healthy() bool {
+	return len(d.Issues) == 0
+}
+
+// ValidateOperator checks a registry entry without contacting it: address
+// and operatorId formats, that the operatorId matches the G1 key, that the
+// G2 key decodes, that the stake is a positive number and that the socket
+// is a usable URL
+func ValidateOperator(operator Operator) []OperatorIssue {
+	var issues []OperatorIssue
+	add := func(check string, err error) {
+		issues = append(issues, OperatorIssue{Check: check, Problem: err.Error()})
+	}
+
+	if _, err := NormalizeAddress(operator.ID); err != nil {
+		add("address", err)
+	}
+	if id, err := NormalizeOperatorID(operator.OperatorID); err != nil {
+		add("operator_id", err)
+	} else if len(operator.PubkeyG1_X) != 1 || len(operator.PubkeyG1_Y) != 1 {
+		issues = append(issues, OperatorIssue{Check: "public_key", Problem: "G1 public key must have one X and one Y coordinate"})
+	} else if computed, err := ComputeOperatorID(operator.PubkeyG1_X[0], operator.PubkeyG1_Y[0]); err != nil {
+		add("public_key", err)
+	} else if computed != id {
+		issues = append(issues, OperatorIssue{Check: "operator_id", Problem: "operatorId does not match the G1 public key"})
+	}
+	if err := operator.decodePublicKey(); err != nil {
+		add("public_key", err)
+	}
+	switch {
+	case math.IsNaN(operator.Stake) || math.IsInf(operator.Stake, 0):
+		issues = append(issues, OperatorIssue{Check: "stake", Problem: "stake is not a finite number"})
+	case operator.Stake < 0:
+		issues = append(issues, OperatorIssue{Check: "stake", Problem: "stake is negative"})
+	case operator.Stake == 0:
+		issues = append(issues, OperatorIssue{Check: "stake", Problem: "operator has no stake"})
+	}
+	if _, err := NormalizeSocket(operator.Socket); err != nil {
+		add("socket", err)
+	}
+	return issues
+}
+
+// DiagnoseOperators validates every entry and probes the sockets that are
+// valid, returning the diagnoses with unhealthy operators first
+func (z *Zellular) DiagnoseOperators(ctx context.Context, operators []Operator, timeout time.Duration) []OperatorDiagnosis {
+	diagnoses := make([]OperatorDiagnosis, len(operators))
+	var wg sync.WaitGroup
+	for i, operator := range operators {
+		diagnoses[i] = OperatorDiagnosis{
+			OperatorID: operator.ID,
+			Socket:     operator.Socket,
+			Issues:     ValidateOperator(operator),
+		}
+		socket, err := NormalizeSocket(operator.Socket)
+		if err != nil || socket == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(d *OperatorDiagnosis, socket string) {
+			defer wg.Done()
+			rtt, err := z.probe(ctx, socket, timeout)
+			if err != nil {
+				d.Issues = append(d.Issues, OperatorIssue{Check: "reachability", Problem: err.Error()})
+				return
+			}
+			d.RTT = rtt
+		}(&diagnoses[i], socket)
+	}
+	wg.Wait()
+
+	sort.SliceStable(diagnoses, func(i, j int) bool {
+		return !diagnoses[i].Healthy() && diagnoses[j].Healthy()
+	})
+	return diagnoses
+}
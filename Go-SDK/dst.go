@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"golang.org/x/crypto/sha3"
+)
+
+// DomainSeparation ties signatures and chaining hashes to one network, app
+// and purpose so they cannot be replayed in another context. Without it the
+// client uses the undifferentiated tags the nodes have always used; enable
+// it only on networks whose nodes separate domains the same way.
+type DomainSeparation struct {
+	// Network identifies the Zellular network, e.g. "mainnet"; the
+	// WithNetworkID network when empty
+	Network string
+	// SignatureTag, when set, replaces the derived tag mixed into signed digests
+	SignatureTag []byte
+	// ChainingTag, when set, replaces the derived chaining hash prefix
+	ChainingTag string
+}
+
+// WithDomainSeparation mixes a tag derived from d and the app name into the
+// digests hashed to G1 and prefixes chaining hash inputs with another
+func WithDomainSeparation(d DomainSeparation) Option {
+	return func(z *Zellular) {
+		z.domain = &d
+	}
+}
+
+// domainPrefix is the part of the derived tags naming the network and app
+func (z *Zellular) domainPrefix() string {
+	network := z.domain.Network
+	if network == "" {
+		network = z.networkID
+	}
+	return "ZELLULAR-V1-" + network + "-" + z.AppName + "-"
+}
+
+// messagePoint hashes the digest of a finalization message to G1. BN254
+// hash-to-G1 takes no tag, so with domain separation the point is that of
+// keccak256(tag || digest).
+func (z *Zellular) messagePoint(digest []byte) bn254.G1Affine {
+	if z.domain == nil {
+		return mapToG1(digest)
+	}
+	tag := z.domain.SignatureTag
+	if tag == nil {
+		tag = []byte(z.domainPrefix() + "FINALITY")
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(tag)
+	h.Write(digest)
+	return mapToG1(h.Sum(nil))
+}
+
+// chainingTag returns the prefix of chaining hash inputs, "" without domain separation
+func (z *Zellular) chainingTag() string {
+	if z.domain == nil {
+		return ""
+	}
+	if z.domain.ChainingTag != "" {
+		return z.domain.ChainingTag
+	}
+	return z.domainPrefix() + "CHAINING"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// ecdsaSignatureSize is the size of an Ethereum style r || s || v signature
+const ecdsaSignatureSize = 65
+
+// ECDSAScheme verifies finality signed by each operator with its secp256k1
+// Ethereum key instead of an aggregated BLS signature. The signature is the
+// hex encoded concatenation of the signers' 65 byte r || s || v signatures
+// of keccak256(digest), in any order, and an operator's ID is the address
+// of its key.
+type ECDSAScheme struct{}
+
+// Name implements SignatureScheme
+func (ECDSAScheme) Name() string {
+	return "ecdsa"
+}
+
+// Verify recovers the address behind every signature and checks that each
+// signer signed exactly once
+func (ECDSAScheme) Verify(signers []Operator, digest []byte, signature string) error {
+	raw, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+	if len(raw)%ecdsaSignatureSize != 0 {
+		return fmt.Errorf("malformed signature: %d bytes is not a multiple of %d", len(raw), ecdsaSignatureSize)
+	}
+
+	expected := make(map[string]bool, len(signers))
+	for _, signer := range signers {
+		address, err := NormalizeAddress(signer.ID)
+		if err != nil {
+			return fmt.Errorf("signer %s has no address: %v", signer.ID, err)
+		}
+		expected[address] = true
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(digest)
+	messageHash := h.Sum(nil)
+
+	signed := make(map[string]bool, len(signers))
+	for offset := 0; offset < len(raw); offset += ecdsaSignatureSize {
+		address, err := recoverAddress(raw[offset:offset+ecdsaSignatureSize], messageHash)
+		if err != nil {
+			return err
+		}
+		if !expected[address] {
+			return fmt.Errorf("signature by %s, which is not a signer", address)
+		}
+		if signed[address] {
+			return fmt.Errorf("repeated signature by %s", address)
+		}
+		signed[address] = true
+	}
+	for address := range expected {
+		if !signed[address] {
+			return fmt.Errorf("no signature by signer %s", address)
+		}
+	}
+	return nil
+}
+
+// recoverAddress returns the address of the key that made an r || s || v signature
+func recoverAddress(signature, messageHash []byte) (string, error) {
+	// RecoverCompact expects v || r || s with v offset by 27
+	compact := make([]byte, ecdsaSignatureSize)
+	v := signature[64]
+	if v < 27 {
+		v += 27
+	}
+	compact[0] = v
+	copy(compact[1:], signature[:64])
+	publicKey, _, err := ecdsa.RecoverCompact(compact, messageHash)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature: %v", err)
+	}
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(publicKey.SerializeUncompressed()[1:])
+	return "0x" + hex.EncodeToString(h.Sum(nil)[12:]), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// signatures of keccak256(ecdsaDigest) by the keys 1, 2 and 3, whose
+// addresses are the well known ones below
+const (
+	ecdsaDigest     = "zellular ecdsa test digest"
+	ecdsaSignature1 = "9d1abaec9f5715a15c7628244170951e0f85e87f68ca5393d3f9fc3fa23a69c83842d132d6ed3eace4db255a1607fd10a0c0d1cf5667968b8f27f6be21efe0051c"
+	ecdsaSignature2 = "70b55404702ffa86ecfa4e88e0f354004a0965a5eea5fbbd297436001ae920df2106061cd84f6153a9fd8b5f166495de9f19a331b2966a5e9f718b25981545641c"
+	ecdsaSignature3 = "1fb966918db3af46c37234b6a4b043719886d6a05859ba32f72742d6141f7ae64e8c3576d4774ddb4ed077d5f331399d2c0522b1bed904beb9c076746a6689ad1c"
+)
+
+var ecdsaSigners = []Operator{
+	{ID: "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"},
+	{ID: "0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF"},
+}
+
+func TestECDSARecoversSigners(t *testing.T) {
+	for _, signature := range []string{ecdsaSignature1 + ecdsaSignature2, "0x" + ecdsaSignature2 + ecdsaSignature1} {
+		if err := (ECDSAScheme{}).Verify(ecdsaSigners, []byte(ecdsaDigest), signature); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (ECDSAScheme{}).Verify(ecdsaSigners, []byte("another digest"), ecdsaSignature1+ecdsaSignature2); err == nil {
+		t.Fatal("signatures of another digest were accepted")
+	}
+}
+
+func TestECDSARejectsBadSigners(t *testing.T) {
+	for _, c := range []struct {
+		signature string
+		reason    string
+	}{
+		{ecdsaSignature1 + ecdsaSignature1, "repeated signature by 0x7e5f4552091a69125d5dfcb7b8c2659029395bdf"},
+		{ecdsaSignature1 + ecdsaSignature2 + ecdsaSignature3, "0x6813eb9362372eef6200f3b1dbc3f819671cba69, which is not a signer"},
+		{ecdsaSignature1, "no signature by signer 0x2b5ad5c4795c026514f8317c7a215e218dccd6cf"},
+		{ecdsaSignature1 + ecdsaSignature2[2:], "not a multiple of 65"},
+	} {
+		err := (ECDSAScheme{}).Verify(ecdsaSigners, []byte(ecdsaDigest), c.signature)
+		if err == nil || !strings.Contains(err.Error(), c.reason) {
+			t.Fatalf("error = %v, want %q", err, c.reason)
+		}
+	}
+}
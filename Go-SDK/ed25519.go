@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Ed25519Scheme verifies finality signed by each operator with an Ed25519
+// key, for test networks and embedded deployments where pairings are too
+// heavy. Ed25519 signatures do not reveal their signer, so the signature is
+// the hex encoded concatenation of the signers' 64 byte signatures of
+// digest ordered by operator ID. Combine it with a quorum policy such as
+// MinSigners or DualQuorum to threshold by count as well as stake.
+type Ed25519Scheme struct {
+	// Keys maps operator IDs to their public keys
+	Keys map[string]ed25519.PublicKey
+}
+
+// Name implements SignatureScheme
+func (Ed25519Scheme) Name() string {
+	return "ed25519"
+}
+
+// Verify checks one signature per signer, in signer order
+func (s Ed25519Scheme) Verify(signers []Operator, digest []byte, signature string) error {
+	raw, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %v", err)
+	}
+	if len(raw) != len(signers)*ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature: %d bytes for %d signers", len(raw), len(signers))
+	}
+	for i, signer := range signers {
+		key, ok := s.Keys[signer.ID]
+		if !ok || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("no Ed25519 key for signer %s", signer.ID)
+		}
+		if !ed25519.Verify(key, digest, raw[i*ed25519.SignatureSize:(i+1)*ed25519.SignatureSize]) {
+			return fmt.Errorf("invalid signature by %s", signer.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestEd25519ChecksSignerOrder(t *testing.T) {
+	digest := []byte("zellular ed25519 test digest")
+	scheme := Ed25519Scheme{Keys: make(map[string]ed25519.PublicKey)}
+	var signers []Operator
+	var signatures [][]byte
+	for i, id := range []string{"0x01", "0x02"} {
+		key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{byte(i + 1)}, ed25519.SeedSize))
+		scheme.Keys[id] = key.Public().(ed25519.PublicKey)
+		signers = append(signers, Operator{ID: id})
+		signatures = append(signatures, ed25519.Sign(key, digest))
+	}
+
+	inOrder := hex.EncodeToString(append(append([]byte(nil), signatures[0]...), signatures[1]...))
+	if err := scheme.Verify(signers, digest, inOrder); err != nil {
+		t.Fatal(err)
+	}
+	swapped := hex.EncodeToString(append(append([]byte(nil), signatures[1]...), signatures[0]...))
+	if err := scheme.Verify(signers, digest, swapped); err == nil || !strings.Contains(err.Error(), "invalid signature by 0x01") {
+		t.Fatalf("signatures out of signer order: %v", err)
+	}
+	if err := scheme.Verify(signers, digest, inOrder[:len(inOrder)-2]); err == nil {
+		t.Fatal("truncated signature was accepted")
+	}
+	if err := scheme.Verify(append(signers, Operator{ID: "0x03"}), digest, inOrder+inOrder[:128]); err == nil || !strings.Contains(err.Error(), "no Ed25519 key for signer 0x03") {
+		t.Fatalf("signer without a key: %v", err)
+	}
+}
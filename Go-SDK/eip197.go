@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+)
+
+// Operator keys and signatures are on BN254, so proofs are encoded for the
+// EIP-196/197 precompiles that verify BN254 pairings rather than the
+// BLS12-381 ones of EIP-2537.
+
+// Sizes of points in the EIP-196/197 precompile encoding, where every base
+// field element is a 32 byte big-endian word
+const (
+	EIP197G1Size = 2 * fp.Bytes
+	EIP197G2Size = 4 * fp.Bytes
+)
+
+// EncodeG1EIP197 encodes p as x || y, all zeros for the point at infinity
+func EncodeG1EIP197(p *bn254.G1Affine) []byte {
+	out := make([]byte, 0, EIP197G1Size)
+	out = appendFieldEIP197(out, &p.X)
+	return appendFieldEIP197(out, &p.Y)
+}
+
+// EncodeG2EIP197 encodes p as x.c1 || x.c0 || y.c1 || y.c0, the imaginary
+// part of each coordinate first as the ecPairing precompile expects
+func EncodeG2EIP197(p *bn254.G2Affine) []byte {
+	out := make([]byte, 0, EIP197G2Size)
+	out = appendFieldEIP197(out, &p.X.A1)
+	out = appendFieldEIP197(out, &p.X.A0)
+	out = appendFieldEIP197(out, &p.Y.A1)
+	return appendFieldEIP197(out, &p.Y.A0)
+}
+
+// DecodeG1EIP197 decodes a G1 point, rejecting non canonical encodings and
+// points off the curve as the precompiles do
+func DecodeG1EIP197(b []byte) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(b) != EIP197G1Size {
+		return p, fmt.Errorf("zellular: EIP-197 G1 point must be %d bytes, got %d", EIP197G1Size, len(b))
+	}
+	if err := decodeFieldsEIP197(b, &p.X, &p.Y); err != nil {
+		return p, err
+	}
+	if p.X.IsZero() && p.Y.IsZero() {
+		return p, nil
+	}
+	if !p.IsOnCurve() {
+		return p, errors.New("zellular: EIP-197 G1 point is not on the curve")
+	}
+	return p, nil
+}
+
+// DecodeG2EIP197 decodes a G2 point with the checks of DecodeG1EIP197 and
+// the subgroup check of the pairing precompile
+func DecodeG2EIP197(b []byte) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(b) != EIP197G2Size {
+		return p, fmt.Errorf("zellular: EIP-197 G2 point must be %d bytes, got %d", EIP197G2Size, len(b))
+	}
+	if err := decodeFieldsEIP197(b, &p.X.A1, &p.X.A0, &p.Y.A1, &p.Y.A0); err != nil {
+		return p, err
+	}
+	if p.X.IsZero() && p.Y.IsZero() {
+		return p, nil
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, errors.New("zellular: EIP-197 G2 point is not in the subgroup")
+	}
+	return p, nil
+}
+
+// EIP197Proof is a finality proof in the form a contract verifies with the
+// ecPairing precompile: e(Signature, -G2) * e(MessagePoint, PublicKey) == 1
+type EIP197Proof struct {
+	Signature []byte
+	// PublicKey is the aggregated key of the signers valid at the proof's index
+	PublicKey []byte
+	// MessagePoint is the message digest hashed to G1
+	MessagePoint []byte
+}
+
+// EIP197Proof encodes the signature of p, the signers' public key and the
+// hashed message. Operators that rotated keys since p was signed contribute
+// the keys valid at p.Index. It does not verify p.
+func (z *Zellular) EIP197Proof(p FinalityProof) (EIP197Proof, error) {
+	signature, err := decodeSignature(p.Signature)
+	if err != nil {
+		return EIP197Proof{}, err
+	}
+	h := z.messagePoint([]byte(hash(p.Message())))
+	publicKey := z.signersPublicKey(p.Nonsigners)
+	if z.keys.differsAt(p.Index, z.operators()) {
+		publicKey = z.signersPublicKeyAt(p.Index, p.Nonsigners)
+	}
+	return EIP197Proof{
+		Signature:    EncodeG1EIP197(&signature),
+		PublicKey:    EncodeG2EIP197(&publicKey),
+		MessagePoint: EncodeG1EIP197(&h),
+	}, nil
+}
+
+func appendFieldEIP197(out []byte, e *fp.Element) []byte {
+	b := e.Bytes()
+	return append(out, b[:]...)
+}
+
+// decodeFieldsEIP197 reads consecutive 32 byte field elements from b
+func decodeFieldsEIP197(b []byte, elements ...*fp.Element) error {
+	for i, e := range elements {
+		if err := e.SetBytesCanonical(b[i*fp.Bytes : (i+1)*fp.Bytes]); err != nil {
+			return fmt.Errorf("zellular: EIP-197 field element is not canonical: %w", err)
+		}
+	}
+	return nil
+}
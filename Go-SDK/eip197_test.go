@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+func TestEIP197RoundTrip(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+	scalar := big.NewInt(123456789)
+	var p1 bn254.G1Affine
+	p1.ScalarMultiplication(&g1, scalar)
+	var p2 bn254.G2Affine
+	p2.ScalarMultiplication(&g2, scalar)
+
+	for _, p := range []bn254.G1Affine{p1, {}} {
+		decoded, err := DecodeG1EIP197(EncodeG1EIP197(&p))
+		if err != nil || !decoded.Equal(&p) {
+			t.Fatalf("G1 %v decoded to %v, %v", p, decoded, err)
+		}
+	}
+	for _, p := range []bn254.G2Affine{p2, {}} {
+		decoded, err := DecodeG2EIP197(EncodeG2EIP197(&p))
+		if err != nil || !decoded.Equal(&p) {
+			t.Fatalf("G2 %v decoded to %v, %v", p, decoded, err)
+		}
+	}
+
+	// the generator is (1, 2), and G2 coordinates start with the imaginary part
+	if encoded := EncodeG1EIP197(&g1); encoded[31] != 1 || encoded[63] != 2 {
+		t.Fatalf("G1 generator encoded as %x", encoded)
+	}
+	if encoded := EncodeG2EIP197(&g2); !bytes.Equal(encoded[:32], g2.X.A1.Marshal()) {
+		t.Fatalf("G2 generator encoded as %x", encoded)
+	}
+}
+
+func TestEIP197RejectsNonCanonical(t *testing.T) {
+	_, _, g1, g2 := bn254.Generators()
+
+	// x + p encodes the same field element as x
+	modulus, _ := new(big.Int).SetString("21888242871839275222246405745257275088696311157297823662689037894645226208583", 10)
+	unreduced := EncodeG1EIP197(&g1)
+	new(big.Int).Add(modulus, big.NewInt(1)).FillBytes(unreduced[:32])
+	if _, err := DecodeG1EIP197(unreduced); err == nil {
+		t.Fatal("unreduced G1 coordinate was accepted")
+	}
+	encoded := EncodeG2EIP197(&g2)
+	for i := range encoded[:32] {
+		encoded[i] = 0xff
+	}
+	if _, err := DecodeG2EIP197(encoded); err == nil {
+		t.Fatal("unreduced G2 coordinate was accepted")
+	}
+
+	offCurve := EncodeG1EIP197(&g1)
+	offCurve[63] = 3
+	if _, err := DecodeG1EIP197(offCurve); err == nil {
+		t.Fatal("G1 point off the curve was accepted")
+	}
+	if _, err := DecodeG1EIP197(offCurve[:63]); err == nil {
+		t.Fatal("short G1 encoding was accepted")
+	}
+}
+
+func TestEIP197ProofUsesKeysAtIndex(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	proofs := sandboxProofs(z, sandbox, 1)
+	rotateSandboxKey(t, z, sandbox, 3)
+
+	encoded, err := z.EIP197Proof(proofs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := DecodeG1EIP197(encoded.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := DecodeG1EIP197(encoded.MessagePoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := DecodeG2EIP197(encoded.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, g2 := bn254.Generators()
+	g2.Neg(&g2)
+	// the check a contract runs with the ecPairing precompile
+	ok, err := bn254.PairingCheck([]bn254.G1Affine{signature, h}, []bn254.G2Affine{g2, publicKey})
+	if err != nil || !ok {
+		t.Fatalf("pairing check of the encoded proof = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrFenced is returned for a checkpoint write by a replica that is no
+// longer the leader of the epoch it was elected in
+var ErrFenced = errors.New("zellular: checkpoint write fenced off by a newer leader")
+
+// lease is the record replicas compete for. Epoch increases every time the
+// lease changes hands and fences off writes of earlier leaders.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+	Epoch   uint64    `json:"epoch,omitempty"`
+}
+
+// LeaderElector campaigns for a lease in a CASStore so that, of several
+// replicas sharing the store, at most one considers itself leader at a time
+type LeaderElector struct {
+	Store CASStore
+	Key   string
+	// ID identifies this replica and must be unique among them
+	ID string
+	// TTL is how long a lease lasts without renewal; it is renewed every TTL/3
+	TTL time.Duration
+
+	mu      sync.Mutex
+	leader  bool
+	epoch   uint64
+	changed chan struct{}
+}
+
+// NewLeaderElector creates an elector for the lease stored under key
+func NewLeaderElector(store CASStore, key, id string, ttl time.Duration) *LeaderElector {
+	return &LeaderElector{Store: store, Key: key, ID: id, TTL: ttl, changed: make(chan struct{})}
+}
+
+// IsLeader reports whether this replica holds the lease
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// watch returns the current role, the epoch of the lease when leader and a
+// channel closed when either changes
+func (e *LeaderElector) watch() (bool, uint64, <-chan struct{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader, e.epoch, e.changed
+}
+
+// setLeader records the role and, for a leader, the epoch of its lease
+func (e *LeaderElector) setLeader(leader bool, epoch uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !leader {
+		epoch = 0
+	}
+	if e.leader == leader && e.epoch == epoch {
+		return
+	}
+	e.leader, e.epoch = leader, epoch
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// fence fails with ErrFenced unless this replica still holds an unexpired
+// lease of epoch in the store
+func (e *LeaderElector) fence(epoch uint64) error {
+	current, err := e.Store.Get(e.Key)
+	if errors.Is(err, ErrNotFound) {
+		return ErrFenced
+	}
+	if err != nil {
+		return err
+	}
+	var held lease
+	if err := json.Unmarshal(current, &held); err != nil {
+		return err
+	}
+	if held.Holder != e.ID || held.Epoch != epoch || !time.Now().Before(held.Expires) {
+		return ErrFenced
+	}
+	return nil
+}
+
+// Campaign acquires and renews the lease until ctx is done, then resigns
+func (e *LeaderElector) Campaign(ctx context.Context) error {
+	ticker := time.NewTicker(e.TTL / 3)
+	defer ticker.Stop()
+	for {
+		e.renew()
+		select {
+		case <-ctx.Done():
+			if err := e.Resign(); err != nil {
+				log.Printf("zellular: resigning leadership failed: %v", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew takes the lease if it is free, expired or already ours. Any failure
+// steps down, since the lease may expire before the next attempt.
+func (e *LeaderElector) renew() {
+	current, err := e.Store.Get(e.Key)
+	if errors.Is(err, ErrNotFound) {
+		current, err = nil, nil
+	}
+	if err != nil {
+		log.Printf("zellular: reading leader lease failed: %v", err)
+		e.setLeader(false, 0)
+		return
+	}
+	now := time.Now()
+	var held lease
+	if current != nil {
+		if err := json.Unmarshal(current, &held); err == nil && held.Holder != e.ID && now.Before(held.Expires) {
+			e.setLeader(false, 0)
+			return
+		}
+	}
+	// a lease taken over, even an expired one of our own, starts a new
+	// epoch so writes made under the old one can be told apart
+	epoch := held.Epoch
+	if held.Holder != e.ID || !now.Before(held.Expires) {
+		epoch++
+	}
+
+	next, err := json.Marshal(lease{Holder: e.ID, Expires: now.Add(e.TTL), Epoch: epoch})
+	if err != nil {
+		e.setLeader(false, 0)
+		return
+	}
+	ok, err := e.Store.CompareAndSwap(e.Key, current, next)
+	if err != nil {
+		log.Printf("zellular: renewing leader lease failed: %v", err)
+	}
+	e.setLeader(err == nil && ok, epoch)
+}
+
+// Resign releases the lease if this replica holds it
+func (e *LeaderElector) Resign() error {
+	if !e.IsLeader() {
+		return nil
+	}
+	e.setLeader(false, 0)
+	current, err := e.Store.Get(e.Key)
+	if err != nil {
+		return err
+	}
+	var held lease
+	if err := json.Unmarshal(current, &held); err != nil || held.Holder != e.ID {
+		return err
+	}
+	expired, err := json.Marshal(lease{Holder: e.ID, Epoch: held.Epoch})
+	if err != nil {
+		return err
+	}
+	_, err = e.Store.CompareAndSwap(e.Key, current, expired)
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCheckpointWritesAreFenced(t *testing.T) {
+	store := NewMemoryKVStore()
+	a := NewLeaderElector(store, "lease", "a", 20*time.Millisecond)
+	b := NewLeaderElector(store, "lease", "b", time.Minute)
+	z := newZellular("app", "http://localhost:6001", 67)
+
+	a.renew()
+	leader, epochA, _ := a.watch()
+	if !leader || epochA != 1 {
+		t.Fatalf("a: leader %v epoch %d", leader, epochA)
+	}
+	stale := &Consumer{Elector: a, z: z, store: store, epoch: epochA}
+	if err := stale.save(&Checkpoint{Index: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	b.renew()
+	leader, epochB, _ := b.watch()
+	if !leader || epochB != 2 {
+		t.Fatalf("b: leader %v epoch %d", leader, epochB)
+	}
+	if err := stale.save(&Checkpoint{Index: 2}); !errors.Is(err, ErrFenced) {
+		t.Fatalf("write of the previous leader: %v", err)
+	}
+	current := &Consumer{Elector: b, z: z, store: store, epoch: epochB}
+	if err := current.save(&Checkpoint{Index: 3}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := loadCheckpoint(store, "app")
+	if err != nil || cp.Index != 3 || cp.Epoch != 2 {
+		t.Fatalf("checkpoint = %+v, %v", cp, err)
+	}
+}
+
+func TestFileKVStoreWaitsForBusyLock(t *testing.T) {
+	store := FileKVStore{Dir: t.TempDir()}
+	lock := store.path("key") + ".lock"
+	if err := ioutil.WriteFile(lock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.Remove(lock)
+	}()
+
+	swapped, err := store.CompareAndSwap("key", nil, []byte("value"))
+	if err != nil || !swapped {
+		t.Fatalf("CompareAndSwap = %v, %v", swapped, err)
+	}
+}
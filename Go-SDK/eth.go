@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// EthClient is a minimal Ethereum JSON-RPC client for read-only contract calls
+type EthClient struct {
+	URL string
+
+	transport *transport
+	id        int64
+}
+
+// NewEthClient creates a client for the JSON-RPC endpoint at url
+func NewEthClient(url string) *EthClient {
+	return &EthClient{URL: url, transport: defaultTransport}
+}
+
+// Call performs eth_call of data against the contract at to on the latest block
+func (c *EthClient) Call(ctx context.Context, to string, data []byte) ([]byte, error) {
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      atomic.AddInt64(&c.id, 1),
+		"method":  "eth_call",
+		"params": []interface{}{
+			map[string]string{"to": to, "data": "0x" + hex.EncodeToString(data)},
+			"latest",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.transport.do(ctx, http.MethodPost, c.URL, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("zellular: eth_call to %s failed: %s (%d)", to, response.Error.Message, response.Error.Code)
+	}
+	return hex.DecodeString(strings.TrimPrefix(response.Result, "0x"))
+}
+
+// abiSelector returns the 4 byte selector of a function signature such as "balanceOf(address)"
+func abiSelector(signature string) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(signature))
+	return h.Sum(nil)[:4]
+}
+
+// abiAddress encodes an address as an ABI word
+func abiAddress(address string) ([]byte, error) {
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	b, _ := hex.DecodeString(normalized[2:])
+	return abiWord(b), nil
+}
+
+// abiWord left pads b to a 32 byte ABI word
+func abiWord(b []byte) []byte {
+	word := make([]byte, 32)
+	copy(word[32-len(b):], b)
+	return word
+}
+
+// abiString decodes a single dynamic string return value
+func abiString(data []byte) (string, error) {
+	if len(data) < 64 {
+		return "", fmt.Errorf("zellular: short ABI string")
+	}
+	offset := abiInt(data[:32])
+	if offset+32 > len(data) {
+		return "", fmt.Errorf("zellular: invalid ABI string offset")
+	}
+	length := abiInt(data[offset : offset+32])
+	if offset+32+length > len(data) {
+		return "", fmt.Errorf("zellular: invalid ABI string length")
+	}
+	return string(data[offset+32 : offset+32+length]), nil
+}
+
+// abiInt reads a small unsigned integer from an ABI word
+func abiInt(word []byte) int {
+	n := 0
+	for _, b := range word[24:] {
+		n = n<<8 | int(b)
+	}
+	return n
+}
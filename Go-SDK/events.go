@@ -0,0 +1,24 @@
+package main
+
+// Event is a notification the client raises for the application
+type Event interface {
+	EventKind() string
+}
+
+// EventHandler receives events; it is called synchronously and must not block
+type EventHandler func(Event)
+
+// WithEventHandler adds a handler for the events the client raises
+func WithEventHandler(handler EventHandler) Option {
+	return func(z *Zellular) {
+		z.eventHandlers = append(z.eventHandlers, handler)
+	}
+}
+
+// emit records event in the journal and hands it to every registered handler
+func (z *Zellular) emit(event Event) {
+	z.journal.add(event)
+	for _, handler := range z.eventHandlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "strings"
+
+// BatchFilter selects the batches a stream hands out. Filtering happens in
+// the client: the node API has no filter parameters, so every batch is
+// still downloaded and chained, but rejected batches are never buffered,
+// written to the WAL or passed to handlers.
+type BatchFilter func(batch Batch) bool
+
+// EveryNth selects batches whose index is a multiple of n
+func EveryNth(n int) BatchFilter {
+	return func(batch Batch) bool {
+		return n <= 1 || batch.Index%n == 0
+	}
+}
+
+// PayloadPrefix selects batches whose payload starts with prefix
+func PayloadPrefix(prefix string) BatchFilter {
+	return func(batch Batch) bool {
+		return strings.HasPrefix(batch.Payload, prefix)
+	}
+}
+
+// AllFilters selects batches every filter selects
+func AllFilters(filters ...BatchFilter) BatchFilter {
+	return func(batch Batch) bool {
+		for _, filter := range filters {
+			if !filter(batch) {
+				return false
+			}
+		}
+		return true
+	}
+}
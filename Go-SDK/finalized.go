@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// finalizedPage is one response of the finalized batches endpoint
+type finalizedPage struct {
+	Batches           []string         `json:"batches"`
+	Finalized         *FinalizedRecord `json:"finalized"`
+	FirstChainingHash string           `json:"first_chaining_hash"`
+	Network           string           `json:"network,omitempty"`
+
+	// node is the base URL or peer that served the page
+	node string
+}
+
+// FinalizedRecord is the finalized batch a node reports with a page of
+// batches or as its latest one, with the aggregated signature of the
+// operators that locked it and the operators that did not sign
+type FinalizedRecord struct {
+	Index        int    `json:"index"`
+	Hash         string `json:"hash"`
+	ChainingHash string `json:"chaining_hash"`
+	Timestamp    int64  `json:"timestamp,omitempty"`
+	Network      string `json:"network,omitempty"`
+
+	FinalizationSignature string   `json:"finalization_signature"`
+	Nonsigners            []string `json:"nonsigners"`
+}
+
+// Proof returns the finality proof of the record for appName
+func (m *FinalizedRecord) Proof(appName string) FinalityProof {
+	return *m.proof(appName)
+}
+
+// proof returns the finality proof carried by the record
+func (m *FinalizedRecord) proof(appName string) *FinalityProof {
+	return &FinalityProof{
+		AppName:      appName,
+		Index:        m.Index,
+		Hash:         m.Hash,
+		ChainingHash: m.ChainingHash,
+		Signature:    m.FinalizationSignature,
+		Nonsigners:   m.Nonsigners,
+		Network:      m.Network,
+	}
+}
+
+// fetchFinalized requests the page of finalized batches following index after.
+// A nil page means the node has nothing to serve yet.
+func (z *Zellular) fetchFinalized(ctx context.Context, after int) (*finalizedPage, error) {
+	return z.fetchFinalizedLimit(ctx, after, 0)
+}
+
+// fetchFinalizedLimit is fetchFinalized keeping no more than limit batches
+// of the page, every batch when limit is not positive
+func (z *Zellular) fetchFinalizedLimit(ctx context.Context, after, limit int) (*finalizedPage, error) {
+	start := time.Now()
+	baseURL := nodeFor(ctx, z.readEndpoint())
+	page, err := z.requestFinalized(ctx, baseURL, after, limit)
+	if z.pages == nil {
+		z.balancer.done(baseURL, err)
+		z.observeEndpoint(baseURL, err)
+	}
+	if z.reputation != nil {
+		if id := z.operatorAt(baseURL); id != "" {
+			z.reputation.ObserveRequest(id, time.Since(start), err)
+		}
+	}
+	if err == nil && page != nil {
+		err = z.checkNetwork(page.Network)
+	}
+	if err != nil {
+		z.errors.record(err)
+		return nil, err
+	}
+	if page != nil && page.Finalized != nil {
+		z.progress.observe(page.Finalized.Index)
+	}
+	if page != nil && page.node == "" {
+		page.node = baseURL
+	}
+	return page, nil
+}
+
+// watchFinalized calls fn with every verified batch finalized after index
+// after until fn returns true, ctx is done or a page cannot be fetched.
+// Unlike a stream it records no progress, statistics, endpoint health or
+// WAL entries, so watching leaves the client's own streams undisturbed.
+func (z *Zellular) watchFinalized(ctx context.Context, after int, pollInterval time.Duration, fn func(Batch) bool) error {
+	chain := newVerifiedChain(z, after, "")
+	for {
+		page, err := z.requestFinalized(ctx, nodeFor(ctx, z.base()), after, 0)
+		if err == nil && page != nil {
+			err = z.checkNetwork(page.Network)
+		}
+		if err != nil {
+			return err
+		}
+		if page == nil || len(page.Batches) == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		for i, payload := range page.Batches {
+			after++
+			batch := Batch{Index: after, Payload: payload, Node: page.node}
+			if page.Finalized != nil && page.Finalized.Index == after {
+				batch.Proof = page.Finalized.proof(z.AppName)
+			}
+			first := ""
+			if i == 0 {
+				first = page.FirstChainingHash
+			}
+			verified, err := chain.add(batch, first)
+			if err != nil {
+				return err
+			}
+			for _, batch := range verified {
+				if fn(batch) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// pageFetcher retrieves finalized pages over a transport other than the node HTTP API
+type pageFetcher interface {
+	fetchPage(ctx context.Context, appName string, after int) (*finalizedPage, error)
+}
+
+// requestFinalized requests a page from the node at baseURL, or through the
+// configured page fetcher, keeping no more than limit batches of it when
+// limit is positive
+func (z *Zellular) requestFinalized(ctx context.Context, baseURL string, after, limit int) (*finalizedPage, error) {
+	if z.pages == nil {
+		return z.requestFinalizedFrom(ctx, baseURL, after, limit)
+	}
+	page, err := z.pages.fetchPage(ctx, z.AppName, after)
+	if err == nil && page != nil && limit > 0 && len(page.Batches) > limit {
+		page.Batches = page.Batches[:limit]
+	}
+	return page, err
+}
+
+// requestFinalizedFrom requests a page from the node at baseURL over HTTP.
+// The response is decoded as it is read, so batches past limit are never
+// held in memory.
+func (z *Zellular) requestFinalizedFrom(ctx context.Context, baseURL string, after, limit int) (*finalizedPage, error) {
+	url := baseURL + "/node/" + z.AppName + "/batches/finalized?after=" + strconv.Itoa(after)
+	resp, err := z.transport.do(withReadRole(ctx), http.MethodGet, url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	page, err := z.decodePage(url, resp.Body, limit)
+	if err != nil {
+		return nil, err
+	}
+	if page != nil {
+		page.node = baseURL
+	}
+	return page, nil
+}
+
+// fetchLastFinalized requests the last finalized batch marker of the node at baseURL
+func (z *Zellular) fetchLastFinalized(ctx context.Context, baseURL string) (*FinalizedRecord, error) {
+	url := fmt.Sprintf("%s/node/%s/batches/finalized/last", baseURL, z.AppName)
+	resp, err := z.transport.do(withReadRole(ctx), http.MethodGet, url, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data *FinalizedRecord `json:"data"`
+	}
+	if err := z.decode(url, body, &response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("zellular: %s has no finalized batch for %s", baseURL, z.AppName)
+	}
+	return response.Data, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FollowerStatus is what a follower knows about the primary's progress
+type FollowerStatus struct {
+	// Checkpoint is the primary's latest published checkpoint
+	Checkpoint Checkpoint
+	// VerifiedIndex is the highest finalized index whose signature the
+	// follower checked itself
+	VerifiedIndex int
+	// Matched is the last checkpoint of the primary whose chaining hash
+	// the follower found equal to the one a verified finalization proves
+	// at its index
+	Matched Checkpoint
+	// Err is the last verification failure, nil if none
+	Err error
+}
+
+// Verified reports whether the last checkpoint the follower checked, Matched,
+// had the chaining hash a verified finalization proves at its index, and
+// the primary has not gone back before it since. Checkpoints the primary
+// published after Matched are checked on the next VerifyInterval.
+func (s FollowerStatus) Verified() bool {
+	return s.Err == nil && s.Matched.ChainingHash != "" && s.Checkpoint.Index >= s.Matched.Index
+}
+
+// Follower tracks the checkpoints a primary Consumer publishes to a shared
+// store without handling batches itself. Instead of verifying every batch
+// it periodically verifies the finalization covering the primary's current
+// checkpoint in the background and that the checkpoint agrees with it.
+type Follower struct {
+	PollInterval   time.Duration
+	VerifyInterval time.Duration
+
+	z      *Zellular
+	store  KVStore
+	mu     sync.Mutex
+	status FollowerStatus
+}
+
+// NewFollower creates a follower of the checkpoints of z's app in store
+func (z *Zellular) NewFollower(store KVStore) *Follower {
+	return &Follower{
+		PollInterval:   time.Second,
+		VerifyInterval: time.Minute,
+		z:              z,
+		store:          store,
+	}
+}
+
+// Checkpoint returns the primary's latest checkpoint
+func (f *Follower) Checkpoint() Checkpoint {
+	return f.Status().Checkpoint
+}
+
+// Status returns the follower's view of the primary
+func (f *Follower) Status() FollowerStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+// Run polls the store and verifies in the background until ctx is done
+func (f *Follower) Run(ctx context.Context) error {
+	go f.verifyLoop(ctx)
+	for {
+		cp, err := loadCheckpoint(f.store, f.z.AppName)
+		if err != nil {
+			f.z.errors.record(err)
+		} else {
+			f.mu.Lock()
+			f.status.Checkpoint = cp
+			f.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.PollInterval):
+		}
+	}
+}
+
+func (f *Follower) verifyLoop(ctx context.Context) {
+	for {
+		if err := f.verify(ctx); err != nil {
+			f.z.errors.record(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.VerifyInterval):
+		}
+	}
+}
+
+// verify fetches the finalization covering the primary's checkpoint,
+// verifies it and compares the chaining hash it proves at the checkpoint's
+// index with the checkpoint's
+func (f *Follower) verify(ctx context.Context) error {
+	cp := f.Status().Checkpoint
+	if cp.Index < 1 || cp.ChainingHash == "" {
+		return nil
+	}
+	inclusion, err := f.z.GetBatch(ctx, cp.Index)
+	if err != nil {
+		return err
+	}
+	result, err := inclusion.Verify(f.z)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !result.Valid() {
+		f.status.Err = fmt.Errorf("zellular: finalization of batch %d failed verification: %s", cp.Index, result.Reason)
+		return f.status.Err
+	}
+	if inclusion.Proof.Index > f.status.VerifiedIndex {
+		f.status.VerifiedIndex = inclusion.Proof.Index
+	}
+	if cp.ChainingHash != inclusion.Batch.ChainingHash {
+		f.status.Err = fmt.Errorf("zellular: primary checkpoint %d has chaining hash %s, network has %s",
+			cp.Index, cp.ChainingHash, inclusion.Batch.ChainingHash)
+		return f.status.Err
+	}
+	f.status.Matched = cp
+	f.status.Err = nil
+	return nil
+}
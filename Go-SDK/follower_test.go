@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFollowerVerifiesCheckpointAtItsIndex(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f := z.NewFollower(nil)
+
+	// the primary lags behind the network, as it usually does
+	first := Checkpoint{Index: 1, ChainingHash: z.chain("", `["a"]`)}
+	f.status.Checkpoint = first
+	if err := f.verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := f.Status(); !status.Verified() || status.Matched != first || status.VerifiedIndex != 3 {
+		t.Fatalf("checkpoint behind the latest finalization not verified: %+v", status)
+	}
+
+	// later checkpoints keep the status until they are checked themselves
+	f.status.Checkpoint = Checkpoint{Index: 2, ChainingHash: "bogus"}
+	if !f.Status().Verified() {
+		t.Fatalf("unchecked later checkpoint cleared the status: %+v", f.Status())
+	}
+	if err := f.verify(ctx); err == nil || f.Status().Verified() {
+		t.Fatalf("mismatching checkpoint verified: %+v", f.Status())
+	}
+
+	f.status.Checkpoint = Checkpoint{Index: 2, ChainingHash: z.chain(first.ChainingHash, `["b"]`)}
+	if err := f.verify(ctx); err != nil || !f.Status().Verified() {
+		t.Fatalf("matching checkpoint not verified: %+v, %v", f.Status(), err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// UpdateGuards bound how much an operator set update may change the
+// current set before it is quarantined instead of applied. A zero field
+// disables its guard.
+type UpdateGuards struct {
+	// MaxStakeDelta is the largest relative change of the total stake, e.g.
+	// 0.3 for 30%
+	MaxStakeDelta float64
+	// MaxChurn is the largest number of operators added and removed,
+	// relative to the size of the current set
+	MaxChurn float64
+}
+
+// ErrQuarantined is returned for an operator set update held back by UpdateGuards
+var ErrQuarantined = errors.New("zellular: operator set update quarantined")
+
+// QuarantineEvent reports an operator set update held back by UpdateGuards
+type QuarantineEvent struct {
+	AppName    string
+	StakeDelta float64
+	Churn      float64
+	Reason     string
+	Time       time.Time
+}
+
+// EventKind implements Event
+func (e *QuarantineEvent) EventKind() string {
+	return "operator_set_quarantined"
+}
+
+// quarantine holds the last update rejected by the guards
+type quarantine struct {
+	mu        sync.Mutex
+	operators map[string]Operator
+}
+
+// WithUpdateGuards quarantines operator set updates that exceed guards;
+// they raise a QuarantineEvent and are only applied by AcceptQuarantined
+func WithUpdateGuards(guards UpdateGuards) Option {
+	return func(z *Zellular) {
+		z.guards = &guards
+	}
+}
+
+// RefreshOperators reloads the operator set from the configured source
+func (z *Zellular) RefreshOperators() error {
+	operators, err := z.discoverOperators()
+	if err != nil {
+		return err
+	}
+	return z.updateOperators(operators)
+}
+
+// updateOperators applies a refreshed operator set unless the guards
+// quarantine it
+func (z *Zellular) updateOperators(operators map[string]Operator) error {
+	if current := z.operators(); z.guards != nil && len(current) > 0 {
+		if reason := z.guards.check(current, operators); reason != "" {
+			z.quarantined.mu.Lock()
+			z.quarantined.operators = operators
+			z.quarantined.mu.Unlock()
+			z.emit(&QuarantineEvent{
+				AppName:    z.AppName,
+				StakeDelta: stakeDelta(current, operators),
+				Churn:      churn(current, operators),
+				Reason:     reason,
+				Time:       time.Now(),
+			})
+			return fmt.Errorf("%w: %s", ErrQuarantined, reason)
+		}
+	}
+	return z.setOperators(operators)
+}
+
+// AcceptQuarantined applies the last quarantined update after it has been
+// reviewed. It fails if there is none.
+func (z *Zellular) AcceptQuarantined() error {
+	z.quarantined.mu.Lock()
+	operators := z.quarantined.operators
+	z.quarantined.operators = nil
+	z.quarantined.mu.Unlock()
+	if operators == nil {
+		return errors.New("zellular: no quarantined operator set")
+	}
+	return z.setOperators(operators)
+}
+
+// check describes why next is too far from current, or returns ""
+func (g *UpdateGuards) check(current, next map[string]Operator) string {
+	if delta := stakeDelta(current, next); g.MaxStakeDelta > 0 && delta > g.MaxStakeDelta {
+		return fmt.Sprintf("total stake changed by %.1f%%, more than %.1f%%", 100*delta, 100*g.MaxStakeDelta)
+	}
+	if c := churn(current, next); g.MaxChurn > 0 && c > g.MaxChurn {
+		return fmt.Sprintf("operator churn of %.1f%% exceeds %.1f%%", 100*c, 100*g.MaxChurn)
+	}
+	return ""
+}
+
+// stakeDelta is the relative change of the total stake from current to next
+func stakeDelta(current, next map[string]Operator) float64 {
+	before, after := totalStake(current), totalStake(next)
+	if before == 0 {
+		return 0
+	}
+	return math.Abs(after-before) / before
+}
+
+// churn is the number of operators added and removed relative to len(current)
+func churn(current, next map[string]Operator) float64 {
+	if len(current) == 0 {
+		return 0
+	}
+	changed := 0
+	for id := range current {
+		if _, ok := next[id]; !ok {
+			changed++
+		}
+	}
+	for id := range next {
+		if _, ok := current[id]; !ok {
+			changed++
+		}
+	}
+	return float64(changed) / float64(len(current))
+}
+
+func totalStake(operators map[string]Operator) float64 {
+	total := 0.0
+	for _, operator := range operators {
+		total += operator.Stake
+	}
+	return total
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// TestOperatorRefreshRace swaps operator sets while signatures are
+// verified; run with -race
+func TestOperatorRefreshRace(t *testing.T) {
+	first := registryOperator("0x0000000000000000000000000000000000000001", testSecret)
+	second := registryOperator("0x0000000000000000000000000000000000000002", big.NewInt(7))
+	z := newZellular("app", "http://localhost:6001", 67)
+	if err := z.setOperators(map[string]Operator{first.ID: first}); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := decodeSignature(testSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			next := first
+			if i%2 == 0 {
+				next = second
+			}
+			z.quarantined.mu.Lock()
+			z.quarantined.operators = map[string]Operator{next.ID: next}
+			z.quarantined.mu.Unlock()
+			if err := z.AcceptQuarantined(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if _, err := z.checkSignature(nil, testDigest, &sig); err != nil {
+			t.Fatal(err)
+		}
+		z.LookupOperator(first.ID)
+		z.NetworkInfo()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/hex"
+	"io"
+	"sync"
+)
+
+// Hasher produces the incremental hashes used for batch and chaining
+// hashes. Payloads are written to the returned HashState as they are
+// read, so large batches never need a second copy in memory. The digest
+// must match the one the nodes compute.
+type Hasher interface {
+	New() HashState
+}
+
+// HashState is a hash in progress; every hash.Hash satisfies it
+type HashState interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+// xxHasher is XXH3-128, printed as the nodes' xxh128_hexdigest
+type xxHasher struct{}
+
+func (xxHasher) New() HashState {
+	return newXXH3()
+}
+
+// DefaultHasher is the hasher matching the Zellular nodes
+var DefaultHasher Hasher = xxHasher{}
+
+// WithHasher replaces the hasher used for batch and chaining hashes
+func WithHasher(hasher Hasher) Option {
+	return func(z *Zellular) {
+		z.hasher = hasher
+	}
+}
+
+// HashReader hashes everything read from r
+func HashReader(hasher Hasher, r io.Reader) (string, error) {
+	h := hasher.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// xxPool recycles XXH3 states for the default hasher
+var xxPool = sync.Pool{New: func() interface{} { return newXXH3() }}
+
+// hashStrings hashes the concatenation of parts without building it
+func hashStrings(hasher Hasher, parts ...string) string {
+	if _, ok := hasher.(xxHasher); ok {
+		d := xxPool.Get().(*xxh3State)
+		d.Reset()
+		for _, part := range parts {
+			d.WriteString(part)
+		}
+		var sum [16]byte
+		d.Sum(sum[:0])
+		xxPool.Put(d)
+		return hex.EncodeToString(sum[:])
+	}
+	h := hasher.New()
+	for _, part := range parts {
+		io.WriteString(h, part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// batchHash returns the hash of a batch payload
+func (z *Zellular) batchHash(payload string) string {
+	return hashStrings(z.hasher, payload)
+}
+
+// chain returns the chaining hash following previous after payload
+func (z *Zellular) chain(previous, payload string) string {
+	return z.chainHash(previous, z.batchHash(payload))
+}
+
+// chainHash returns the chaining hash following previous after a batch
+// whose hash is batchHash
+func (z *Zellular) chainHash(previous, batchHash string) string {
+	if tag := z.chainingTag(); tag != "" {
+		return hashStrings(z.hasher, tag, previous, batchHash)
+	}
+	return hashStrings(z.hasher, previous, batchHash)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// xxh128Vectors are xxh128_hexdigest outputs of the reference xxHash
+// implementation, the one the Python SDK binds to
+var xxh128Vectors = map[string]string{
+	"":         "99aa06d3014798d86001c324468d497f",
+	"a":        "a96faf705af16834e6c632b61e964e1f",
+	"abc":      "06b05ab6733a618578af5f94892f3950",
+	"zellular": "2a19a2b2f061750ff2beea80ed247e96",
+	"The quick brown fox jumps over the lazy dog": "ddd650205ca3e7fa24a1cc2e3a8a7651",
+}
+
+// xxh128Lengths are digests of patternInput(n), covering every size class
+// and the streaming buffer and block boundaries
+var xxh128Lengths = map[int]string{
+	1:     "495b62073ef70ca44c5cca45d0f4811f",
+	2:     "12b2847aa0de5aaaa7e250c97710ff27",
+	3:     "46f66cb93538156515f7093b173d005c",
+	4:     "7fefeeffb4d0eab3b987ca5d9241572a",
+	5:     "2fbb16712b4bf1d5752a86982353f4f3",
+	8:     "803c675a846cc6c256bb836ceb6d4baa",
+	9:     "365644c233ffe5c213af585c9bf5827d",
+	16:    "da917c385cc874c00d463cb04ceffbaf",
+	17:    "d443578f2c4e2fb495c34448580e19c8",
+	32:    "7e6bb3a654986c3373367e068a010989",
+	33:    "97fbf3ce109f139623a0b885a7dac302",
+	64:    "3515800f003ddbd064524fe2047012df",
+	65:    "7fd00229a220a25b3b589ad4300ec5d5",
+	96:    "0f5fdafe3c60a2ba6aaa14172a8f6957",
+	97:    "203fb0b6f9a8419aaedfb1405f779a9d",
+	128:   "22c34350373a38ae5b77925b2c683a12",
+	129:   "c4a7d8f7893f2090d6d9e73553568be1",
+	200:   "7d5b21c921158e644ec0706f02ef2a5b",
+	240:   "e29d70b8920fd24bc6ed4333f79384f8",
+	241:   "f91b3cb8ed0fa91a07525dbc14902c7f",
+	255:   "95bf93be894101733e64256196c9377b",
+	256:   "6e4ce5e0d8c8f76fa49b06aa88ab05e1",
+	257:   "3e09974b76a51e46745ab87b9ae166e5",
+	511:   "fa411b571397420d95cf4c9515361939",
+	512:   "9e63fd6205e2ed7c6abf1d9f4b155d16",
+	1024:  "f53a1b1e9f1efedfe2898655db7bc9ee",
+	1025:  "a906cca0f6e772a7134c652ba3d6fb9e",
+	2048:  "aceedd29e1caaad363a78a59658d80f4",
+	4096:  "92c9e665ac5b016d04a1779c9e7ddcd7",
+	10000: "1fe5c2876c36cbe36678c74cd91c57be",
+}
+
+func patternInput(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte((i*31 + 7) % 251)
+	}
+	return string(b)
+}
+
+func TestHashMatchesXXH128(t *testing.T) {
+	for input, want := range xxh128Vectors {
+		if got := hash(input); got != want {
+			t.Errorf("hash(%q) = %s, want %s", input, got, want)
+		}
+	}
+	for n, want := range xxh128Lengths {
+		if got := hash(patternInput(n)); got != want {
+			t.Errorf("hash of %d bytes = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestHashReaderStreams(t *testing.T) {
+	for n, want := range xxh128Lengths {
+		// io.Copy through a one byte buffer splits the writes everywhere
+		got, err := HashReader(DefaultHasher, &oneByteReader{strings.NewReader(patternInput(n))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("HashReader of %d bytes = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestHashStringsConcatenates(t *testing.T) {
+	input := patternInput(1025)
+	if got, want := hashStrings(DefaultHasher, input[:300], input[300:1000], input[1000:]), xxh128Lengths[1025]; got != want {
+		t.Fatalf("hashStrings = %s, want %s", got, want)
+	}
+}
+
+type oneByteReader struct{ r *strings.Reader }
+
+func (o *oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return o.r.Read(p)
+}
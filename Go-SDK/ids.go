@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NormalizeAddress validates an Ethereum address in any letter case and
+// returns it in the lowercase form the registry uses as operator ID
+func NormalizeAddress(address string) (string, error) {
+	return normalizeHex(address, 20, "address")
+}
+
+// NormalizeOperatorID validates a 32 byte operatorId and returns it lowercased
+func NormalizeOperatorID(operatorID string) (string, error) {
+	return normalizeHex(operatorID, 32, "operatorId")
+}
+
+func normalizeHex(s string, size int, kind string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return "", fmt.Errorf("zellular: %s %q lacks the 0x prefix", kind, s)
+	}
+	s = strings.ToLower(s[2:])
+	if len(s) != 2*size {
+		return "", fmt.Errorf("zellular: %s 0x%s must be %d bytes", kind, s, size)
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", fmt.Errorf("zellular: %s 0x%s is not hex", kind, s)
+	}
+	return "0x" + s, nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed case form of an address
+func ChecksumAddress(address string) (string, error) {
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	digits := normalized[2:]
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(digits))
+	sum := h.Sum(nil)
+
+	out := []byte(digits)
+	for i, c := range out {
+		nibble := sum[i/2] >> 4
+		if i%2 == 1 {
+			nibble = sum[i/2] & 0x0f
+		}
+		if c >= 'a' && c <= 'f' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out), nil
+}
+
+// ComputeOperatorID derives the operatorId the BLS registry assigns to a BN254 G1
+// public key: keccak256 of the 32 byte big-endian X and Y coordinates
+func ComputeOperatorID(g1X, g1Y string) (string, error) {
+	h := sha3.NewLegacyKeccak256()
+	for _, coordinate := range []string{g1X, g1Y} {
+		n, ok := new(big.Int).SetString(coordinate, 10)
+		if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+			return "", fmt.Errorf("zellular: invalid G1 coordinate %q", coordinate)
+		}
+		var buf [32]byte
+		n.FillBytes(buf[:])
+		h.Write(buf[:])
+	}
+	return "0x" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LookupOperator finds an operator by address in any letter case or by operatorId
+func (z *Zellular) LookupOperator(id string) (Operator, bool) {
+	return lookupOperator(z.operators(), id)
+}
+
+// lookupOperator is LookupOperator over a given operator set
+func lookupOperator(operators map[string]Operator, id string) (Operator, bool) {
+	if operator, ok := operators[id]; ok {
+		return operator, true
+	}
+	if address, err := NormalizeAddress(id); err == nil {
+		operator, ok := operators[address]
+		return operator, ok
+	}
+	if operatorID, err := NormalizeOperatorID(id); err == nil {
+		for _, operator := range operators {
+			if strings.EqualFold(operator.OperatorID, operatorID) {
+				return operator, true
+			}
+		}
+	}
+	return Operator{}, false
+}
+
+// OperatorIDForAddress converts an operator address to its operatorId
+func (z *Zellular) OperatorIDForAddress(address string) (string, bool) {
+	operator, ok := z.LookupOperator(address)
+	if !ok {
+		return "", false
+	}
+	return strings.ToLower(operator.OperatorID), true
+}
+
+// AddressForOperatorID converts an operatorId to the operator's address
+func (z *Zellular) AddressForOperatorID(operatorID string) (string, bool) {
+	operator, ok := z.LookupOperator(operatorID)
+	if !ok {
+		return "", false
+	}
+	return operator.ID, true
+}
@@ -0,0 +1,26 @@
+//go:build go1.23
+
+package main
+
+import (
+	"context"
+	"iter"
+)
+
+// Finalized returns the finalized batches following index after as an
+// iterator, so they can be consumed with
+//
+//	for batch, err := range z.Finalized(ctx, after) { ... }
+//
+// The iteration ends after the first error, including ctx being done.
+func (z *Zellular) Finalized(ctx context.Context, after int) iter.Seq2[Batch, error] {
+	return func(yield func(Batch, error) bool) {
+		stream := z.Stream(after)
+		for {
+			batch, err := stream.Next(ctx)
+			if !yield(batch, err) || err != nil {
+				return
+			}
+		}
+	}
+}
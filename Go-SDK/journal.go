@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultJournalSize is the number of events RecentEvents keeps by default
+const defaultJournalSize = 256
+
+// JournalEntry is an event kept by the journal
+type JournalEntry struct {
+	Time  time.Time `json:"time"`
+	Kind  string    `json:"kind"`
+	Event Event     `json:"event"`
+}
+
+// eventJournal is a ring buffer of the latest events
+type eventJournal struct {
+	mu      sync.Mutex
+	entries []JournalEntry
+	next    int
+	full    bool
+}
+
+func newEventJournal(size int) *eventJournal {
+	return &eventJournal{entries: make([]JournalEntry, size)}
+}
+
+func (j *eventJournal) add(event Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.entries) == 0 {
+		return
+	}
+	j.entries[j.next] = JournalEntry{Time: time.Now(), Kind: event.EventKind(), Event: event}
+	j.next = (j.next + 1) % len(j.entries)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+func (j *eventJournal) list() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+	return append(append([]JournalEntry(nil), j.entries[j.next:]...), j.entries[:j.next]...)
+}
+
+// WithEventJournal keeps the last size events for RecentEvents instead of
+// the default 256; zero disables the journal
+func WithEventJournal(size int) Option {
+	return func(z *Zellular) {
+		z.journal = newEventJournal(size)
+	}
+}
+
+// RecentEvents returns the latest events the client raised, oldest first:
+// failovers, retries, verification failures and operator set changes as
+// well as every event delivered to event handlers. It helps postmortems
+// when logging was too quiet at the time.
+func (z *Zellular) RecentEvents() []JournalEntry {
+	return z.journal.list()
+}
+
+// FailoverEvent reports the client switching to another base node
+type FailoverEvent struct {
+	From string
+	To   string
+}
+
+// EventKind implements Event
+func (e *FailoverEvent) EventKind() string {
+	return "failover"
+}
+
+// RetryEvent reports a throttled request being retried
+type RetryEvent struct {
+	URL     string
+	Attempt int
+	Delay   time.Duration
+}
+
+// EventKind implements Event
+func (e *RetryEvent) EventKind() string {
+	return "retry"
+}
+
+// VerificationFailedEvent reports a rejected signature or one that could
+// not be checked
+type VerificationFailedEvent struct {
+	AppName     string
+	MessageHash string
+	Status      VerificationStatus
+	Reason      string
+}
+
+// EventKind implements Event
+func (e *VerificationFailedEvent) EventKind() string {
+	return "verification_failed"
+}
+
+// OperatorsUpdatedEvent reports a new operator set being applied
+type OperatorsUpdatedEvent struct {
+	Operators int
+	Digest    string
+}
+
+// EventKind implements Event
+func (e *OperatorsUpdatedEvent) EventKind() string {
+	return "operators_updated"
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchInclusion is a single batch with the material to check, without a
+// streaming session, that it was finalized at its index: the chaining hash
+// before it, the batches up to the next finalization and that
+// finalization's proof
+type BatchInclusion struct {
+	Batch                Batch
+	PreviousChainingHash string
+	Following            []string
+	Proof                FinalityProof
+}
+
+// Verify recomputes the chain from the batch to the proof and checks the proof's signature
+func (b BatchInclusion) Verify(z *Zellular) (VerificationResult, error) {
+	chainingHash := z.chain(b.PreviousChainingHash, b.Batch.Payload)
+	if b.Batch.ChainingHash != "" && b.Batch.ChainingHash != chainingHash {
+		return VerificationResult{}.fail(InvalidSignature, "batch chaining hash does not match its predecessor"), nil
+	}
+	last := b.Batch.Payload
+	for _, payload := range b.Following {
+		chainingHash = z.chain(chainingHash, payload)
+		last = payload
+	}
+	switch {
+	case b.Batch.Index+len(b.Following) != b.Proof.Index:
+		return VerificationResult{}.fail(InvalidSignature, "proof does not cover the following batches"), nil
+	case z.batchHash(last) != b.Proof.Hash:
+		return VerificationResult{}.fail(InvalidSignature, "proof hash does not match the last batch"), nil
+	case chainingHash != b.Proof.ChainingHash:
+		return VerificationResult{}.fail(InvalidSignature, "proof chaining hash does not match the batches"), nil
+	}
+	return z.VerifyProof(b.Proof)
+}
+
+// GetBatch fetches the finalized batch at index with its inclusion material
+func (z *Zellular) GetBatch(ctx context.Context, index int) (*BatchInclusion, error) {
+	if index < 1 {
+		return nil, fmt.Errorf("zellular: invalid batch index %d", index)
+	}
+	// the page after index-2 opens with the chaining hash of batch index-1,
+	// which anchors batch index; batch 1 is anchored by the empty hash
+	after, skip := index-2, 1
+	if index == 1 {
+		after, skip = 0, 0
+	}
+	page, err := z.fetchFinalized(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil || len(page.Batches) <= skip {
+		return nil, fmt.Errorf("zellular: batch %d is not finalized", index)
+	}
+
+	inclusion := &BatchInclusion{Batch: Batch{Index: index, Payload: page.Batches[skip], Node: page.node}}
+	if skip == 1 {
+		inclusion.PreviousChainingHash = page.FirstChainingHash
+	}
+	inclusion.Batch.ChainingHash = z.chain(inclusion.PreviousChainingHash, inclusion.Batch.Payload)
+
+	following := page.Batches[skip+1:]
+	last := index
+	for {
+		for _, payload := range following {
+			if page.Finalized != nil && last >= page.Finalized.Index {
+				break
+			}
+			inclusion.Following = append(inclusion.Following, payload)
+			last++
+		}
+		if page.Finalized != nil && last >= page.Finalized.Index {
+			if last > page.Finalized.Index {
+				return nil, fmt.Errorf("zellular: batch %d is past the finalized index %d", index, page.Finalized.Index)
+			}
+			inclusion.Proof = *page.Finalized.proof(z.AppName)
+			if last == index {
+				inclusion.Batch.Proof = &inclusion.Proof
+			}
+			return inclusion, nil
+		}
+		if page, err = z.fetchFinalized(ctx, last); err != nil {
+			return nil, err
+		}
+		if page == nil || len(page.Batches) == 0 {
+			return nil, fmt.Errorf("zellular: no finalization found after batch %d", index)
+		}
+		following = page.Batches
+	}
+}
+
+// errFound stops a WAL replay once the wanted batch was seen
+var errFound = errors.New("found")
+
+// GetBatchByHash fetches the finalized batch whose payload hashes to
+// batchHash. The node API is indexed by position only, so the index is
+// looked up in the WAL first, which limits lookups to batches the client
+// has streamed.
+func (z *Zellular) GetBatchByHash(ctx context.Context, batchHash string) (*BatchInclusion, error) {
+	index := 0
+	err := z.Replay(0, func(batch Batch) error {
+		if z.batchHash(batch.Payload) == batchHash {
+			index = batch.Index
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, err
+	}
+	if index == 0 {
+		return nil, fmt.Errorf("zellular: no batch with hash %s in the write-ahead log", batchHash)
+	}
+	return z.GetBatch(ctx, index)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sha256Hasher stands in for a network hashing batches other than with xxh128
+type sha256Hasher struct{}
+
+func (sha256Hasher) New() HashState {
+	return sha256.New()
+}
+
+func TestGetBatchByHashUsesClientHasher(t *testing.T) {
+	wal, err := OpenWAL(filepath.Join(t.TempDir(), "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`}
+	z, _ := sandboxWith(t, payloads, WithHasher(sha256Hasher{}), WithWAL(wal))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := z.Stream(0)
+	for range payloads {
+		if _, err := stream.Next(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inclusion, err := z.GetBatchByHash(ctx, z.batchHash(`["b"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inclusion.Batch.Index != 2 || inclusion.Batch.Payload != `["b"]` {
+		t.Fatalf("inclusion = %+v", inclusion.Batch)
+	}
+}
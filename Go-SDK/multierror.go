@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OperatorResult is the outcome of one operator's part in a multi-node operation
+type OperatorResult struct {
+	OperatorID string
+	Socket     string
+	Duration   time.Duration
+	Err        error
+}
+
+// MultiError reports per-operator outcomes of a multi-node operation in
+// which at least one operator failed
+type MultiError struct {
+	Results []OperatorResult
+}
+
+// Error summarizes the failed operators
+func (m *MultiError) Error() string {
+	failed := m.Failed()
+	parts := make([]string, len(failed))
+	for i, result := range failed {
+		parts[i] = fmt.Sprintf("%s: %v", result.OperatorID, result.Err)
+	}
+	return fmt.Sprintf("zellular: %d of %d operators failed: %s", len(failed), len(m.Results), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes the individual errors to errors.Is and errors.As
+func (m *MultiError) Unwrap() []error {
+	var errs []error
+	for _, result := range m.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+// Failed returns the results of the operators that failed
+func (m *MultiError) Failed() []OperatorResult {
+	var failed []OperatorResult
+	for _, result := range m.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Succeeded returns the results of the operators that succeeded
+func (m *MultiError) Succeeded() []OperatorResult {
+	var succeeded []OperatorResult
+	for _, result := range m.Results {
+		if result.Err == nil {
+			succeeded = append(succeeded, result)
+		}
+	}
+	return succeeded
+}
+
+// fanOut runs fn against every operator concurrently and returns a
+// *MultiError if any of them failed
+func fanOut(ctx context.Context, operators map[string]Operator, fn func(context.Context, Operator) error) error {
+	results := make([]OperatorResult, 0, len(operators))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id, operator := range operators {
+		wg.Add(1)
+		go func(id string, operator Operator) {
+			defer wg.Done()
+			start := time.Now()
+			err := fn(ctx, operator)
+			mu.Lock()
+			results = append(results, OperatorResult{
+				OperatorID: id,
+				Socket:     operator.Socket,
+				Duration:   time.Since(start),
+				Err:        err,
+			})
+			mu.Unlock()
+		}(id, operator)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Err != nil {
+			return &MultiError{Results: results}
+		}
+	}
+	return nil
+}
+
+// LastFinalizedAll asks every operator for its last finalized index. The
+// indexes of the operators that answered are returned even when others
+// failed, in which case the error is a *MultiError. Operators behind the
+// index the quorum reached are reported as ReadRepairEvents.
+func (z *Zellular) LastFinalizedAll(ctx context.Context) (map[string]int, error) {
+	operators := z.operators()
+	indexes := make(map[string]int, len(operators))
+	var mu sync.Mutex
+	err := fanOut(ctx, withSockets(operators), func(ctx context.Context, operator Operator) error {
+		marker, err := z.fetchLastFinalized(ctx, operator.Socket)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		indexes[operator.ID] = marker.Index
+		mu.Unlock()
+		return nil
+	})
+	z.reportLagging(indexes)
+	return indexes, err
+}
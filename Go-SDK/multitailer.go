@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchHandler receives the finalized batches of one app in index order.
+// Unless the client was created WithoutVerification, a batch is handed over
+// only once its finalization has been verified, as with a BatchStream.
+type BatchHandler func(app string, index int, batch string) error
+
+// MultiTailer tails finalized batches for many apps concurrently. All apps
+// share one operator registry and connection pool, and a bounded pool of
+// workers serves them round-robin so a busy app cannot starve the others.
+type MultiTailer struct {
+	// Workers bounds the number of concurrent page fetches
+	Workers int
+	// PollInterval is how long an app waits before being polled again once caught up
+	PollInterval time.Duration
+	// Handler is called for every batch, an error stops the tailer
+	Handler BatchHandler
+	// MaxFailures is the number of fetches of one app failing in a row at
+	// which Run returns the last error. Earlier failures are retried on the
+	// app's next turn.
+	MaxFailures int
+
+	tails []*appTail
+}
+
+// appTail is the per-app position of a MultiTailer
+type appTail struct {
+	z     *Zellular
+	after int
+	chain verifiedChain
+	// failures counts the fetches that failed since the last one that succeeded
+	failures int
+}
+
+// NewMultiTailer creates a tailer reading each app in apps from the index it
+// maps to. Operators are fetched once and shared between all apps.
+func NewMultiTailer(baseURL string, thresholdPercent float64, apps map[string]int, handler BatchHandler, opts ...Option) *MultiTailer {
+	m := &MultiTailer{
+		Workers:      4,
+		PollInterval: time.Second,
+		Handler:      handler,
+		MaxFailures:  5,
+	}
+
+	var base *Zellular
+	for app, after := range apps {
+		if base == nil {
+			base = NewZellular(app, baseURL, thresholdPercent, opts...)
+			m.tails = append(m.tails, newAppTail(base, after))
+			continue
+		}
+		m.tails = append(m.tails, newAppTail(base.forApp(app), after))
+	}
+	return m
+}
+
+func newAppTail(z *Zellular, after int) *appTail {
+	return &appTail{z: z, after: after, chain: newVerifiedChain(z, after, "")}
+}
+
+// Run tails all apps until ctx is done, a handler returns an error, a batch
+// fails verification or an app cannot be fetched MaxFailures times in a row
+func (m *MultiTailer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// every tail is either queued here, being served or waiting on a timer,
+	// so sends to ready never block
+	ready := make(chan *appTail, len(m.tails))
+	for _, t := range m.tails {
+		ready <- t
+	}
+
+	workers := m.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	errc := make(chan error, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case t := <-ready:
+					n, err := m.step(ctx, t)
+					if err != nil {
+						select {
+						case errc <- err:
+						default:
+						}
+						cancel()
+						return
+					}
+					if n > 0 {
+						ready <- t
+						continue
+					}
+					time.AfterFunc(m.PollInterval, func() { ready <- t })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// step fetches one page for an app and hands its verified batches to the
+// handler. A failed fetch is retried on the next turn unless it is the
+// MaxFailures one in a row.
+func (m *MultiTailer) step(ctx context.Context, t *appTail) (int, error) {
+	page, err := t.z.fetchFinalized(ctx, t.after)
+	if err != nil {
+		t.failures++
+		if t.failures >= m.MaxFailures {
+			return 0, fmt.Errorf("zellular: fetching %s failed %d times in a row: %w", t.z.AppName, t.failures, err)
+		}
+		return 0, nil
+	}
+	t.failures = 0
+	if page == nil {
+		return 0, nil
+	}
+	for i, payload := range page.Batches {
+		t.after++
+		batch := Batch{Index: t.after, Payload: payload, Node: page.node}
+		if page.Finalized != nil && page.Finalized.Index == t.after {
+			batch.Proof = page.Finalized.proof(t.z.AppName)
+		}
+		first := ""
+		if i == 0 {
+			first = page.FirstChainingHash
+		}
+		verified, err := t.chain.add(batch, first)
+		if err != nil {
+			return 0, err
+		}
+		for _, batch := range verified {
+			if err := m.Handler(t.z.AppName, batch.Index, batch.Payload); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(page.Batches), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiTailerVerifiesBatches(t *testing.T) {
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`}
+	z, _ := sandboxWith(t, payloads)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var handled []string
+	done := errors.New("done")
+	m := &MultiTailer{Workers: 1, PollInterval: time.Millisecond, MaxFailures: 1, tails: []*appTail{newAppTail(z, 0)}}
+	m.Handler = func(app string, index int, batch string) error {
+		if index != len(handled)+1 {
+			t.Fatalf("batch %d handed out after %d", index, len(handled))
+		}
+		handled = append(handled, batch)
+		if len(handled) == len(payloads) {
+			return done
+		}
+		return nil
+	}
+	if err := m.Run(ctx); err != done {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiTailerRejectsTamperedBatch(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	sandbox.batches[1].payload = `["x"]`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m := &MultiTailer{Workers: 1, PollInterval: time.Millisecond, MaxFailures: 1, tails: []*appTail{newAppTail(z, 0)}}
+	m.Handler = func(app string, index int, batch string) error {
+		t.Fatalf("unverified batch %d was handed out", index)
+		return nil
+	}
+	if err := m.Run(ctx); err == nil || !strings.Contains(err.Error(), "chaining hash of batch 3") {
+		t.Fatalf("tampered batch was not rejected: %v", err)
+	}
+}
+
+func TestMultiTailerGivesUpAfterMaxFailures(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("unreachable")
+	})}
+	z := newZellular("app", "http://node", 67, WithHTTPClient(client))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m := &MultiTailer{Workers: 1, PollInterval: time.Millisecond, MaxFailures: 3, tails: []*appTail{newAppTail(z, 0)}}
+	m.Handler = func(app string, index int, batch string) error { return nil }
+	err := m.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "3 times in a row") {
+		t.Fatalf("failures were not reported: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "sort"
+
+// OperatorStake is an operator's stake and share of the total
+type OperatorStake struct {
+	OperatorID string
+	Stake      float64
+	// Share is the percentage of the total stake
+	Share float64
+}
+
+// NetworkInfo summarizes the operator set for status pages and risk monitoring
+type NetworkInfo struct {
+	TotalStake float64
+	Operators  int
+	// ActiveOperators have stake and a usable socket
+	ActiveOperators int
+	// Distribution lists operators by descending stake
+	Distribution []OperatorStake
+	// CaptureCoefficient is the fewest operators whose combined stake meets
+	// the threshold, i.e. who can finalize batches on their own
+	CaptureCoefficient int
+	// HaltCoefficient is the fewest operators whose combined stake exceeds
+	// 100 - threshold, i.e. who can stop finalization by not signing
+	HaltCoefficient int
+}
+
+// NetworkInfo computes the network overview from the current operator set
+func (z *Zellular) NetworkInfo() NetworkInfo {
+	operators := z.operators()
+	info := NetworkInfo{Operators: len(operators)}
+	for id, operator := range operators {
+		info.TotalStake += operator.Stake
+		if operator.Stake > 0 && operator.Socket != "" {
+			info.ActiveOperators++
+		}
+		info.Distribution = append(info.Distribution, OperatorStake{OperatorID: id, Stake: operator.Stake})
+	}
+	sort.Slice(info.Distribution, func(i, j int) bool {
+		a, b := info.Distribution[i], info.Distribution[j]
+		return a.Stake > b.Stake || a.Stake == b.Stake && a.OperatorID < b.OperatorID
+	})
+	for i := range info.Distribution {
+		if info.TotalStake > 0 {
+			info.Distribution[i].Share = 100 * info.Distribution[i].Stake / info.TotalStake
+		}
+	}
+	info.CaptureCoefficient = coalitionSize(info.Distribution, z.ThresholdPercent, true)
+	info.HaltCoefficient = coalitionSize(info.Distribution, 100-z.ThresholdPercent, false)
+	return info
+}
+
+// coalitionSize returns how many of the largest operators it takes to
+// reach (inclusive) or exceed percent of the stake, 0 if none can
+func coalitionSize(distribution []OperatorStake, percent float64, inclusive bool) int {
+	share := 0.0
+	for i, operator := range distribution {
+		share += operator.Share
+		if share > percent || inclusive && share >= percent {
+			return i + 1
+		}
+	}
+	return 0
+}
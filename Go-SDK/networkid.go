@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// NetworkMismatchError reports material produced on another Zellular network
+type NetworkMismatchError struct {
+	Expected string
+	Got      string
+}
+
+func (e *NetworkMismatchError) Error() string {
+	return fmt.Sprintf("zellular: expected network %q, got %q", e.Expected, e.Got)
+}
+
+// WithNetworkID binds the client to one Zellular network, e.g. "mainnet".
+// Pages of finalized batches must then declare that network, and proofs
+// must carry it in their signed message, so nothing finalized on a test
+// network is accepted in production. It also names the network of
+// DomainSeparation when that leaves it empty.
+func WithNetworkID(id string) Option {
+	return func(z *Zellular) {
+		z.networkID = id
+	}
+}
+
+// checkNetwork fails if got is not the client's network
+func (z *Zellular) checkNetwork(got string) error {
+	if got != z.networkID {
+		return &NetworkMismatchError{Expected: z.networkID, Got: got}
+	}
+	return nil
+}
+
+// foreignProof describes why a proof does not belong to the client's app
+// and network, or returns ""
+func (z *Zellular) foreignProof(p FinalityProof) string {
+	if p.AppName != z.AppName {
+		return "proof is for app " + p.AppName
+	}
+	if err := z.checkNetwork(p.Network); err != nil {
+		return err.Error()
+	}
+	return ""
+}
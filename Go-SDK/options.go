@@ -0,0 +1,46 @@
+package main
+
+import "net/http"
+
+// Option configures optional behaviour of a Zellular instance
+type Option func(*Zellular)
+
+// WithUserAgent prefixes the SDK User-Agent with the caller's own product token
+func WithUserAgent(product string) Option {
+	return func(z *Zellular) {
+		z.transport.userAgent = product + " " + DefaultUserAgent
+	}
+}
+
+// WithHeader adds a header (tenant ID, trace header, ...) to every outgoing request
+func WithHeader(key, value string) Option {
+	return func(z *Zellular) {
+		z.transport.headers.Add(key, value)
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for node and subgraph requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(z *Zellular) {
+		z.transport.client = client
+	}
+}
+
+// WithoutVerification makes GetFinalized and streams hand out batches
+// without checking them against their finalization record, for callers
+// that verify separately or trust the node
+func WithoutVerification() Option {
+	return func(z *Zellular) {
+		z.skipVerify = true
+	}
+}
+
+// WithConfirmationDepth makes streams release only batches at least depth
+// indices behind the latest finalized index. Batches are verified as they
+// arrive; the newest depth verified ones are held until later batches are
+// finalized.
+func WithConfirmationDepth(depth int) Option {
+	return func(z *Zellular) {
+		z.confirmationDepth = depth
+	}
+}
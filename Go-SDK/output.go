@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// batchPrinter writes batches in the format chosen with --format, reduced
+// to the values selected by --query
+type batchPrinter struct {
+	format string
+	query  []pathStep
+	table  *tabwriter.Writer
+}
+
+// newBatchPrinter validates the format and parses the query
+func newBatchPrinter(format, query string) (*batchPrinter, error) {
+	switch format {
+	case "raw", "json", "table":
+	default:
+		return nil, fmt.Errorf("unknown format %q, want json, raw or table", format)
+	}
+	steps, err := parsePath(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &batchPrinter{format: format, query: steps}
+	if format == "table" {
+		p.table = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	}
+	return p, nil
+}
+
+// print writes one batch
+func (p *batchPrinter) print(batch Batch) error {
+	if p.format == "raw" && p.query == nil {
+		_, err := fmt.Println(batch.Payload)
+		return err
+	}
+	var payload interface{}
+	if err := json.Unmarshal([]byte(batch.Payload), &payload); err != nil {
+		return fmt.Errorf("batch %d: payload is not JSON: %v", batch.Index, err)
+	}
+	values := []interface{}{payload}
+	if p.query != nil {
+		values = selectPath(payload, p.query)
+	}
+
+	switch p.format {
+	case "json":
+		line, err := json.Marshal(map[string]interface{}{"index": batch.Index, "values": values})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(line))
+		return err
+	case "table":
+		cells := []string{strconv.Itoa(batch.Index)}
+		for _, value := range values {
+			cells = append(cells, compactJSON(value))
+		}
+		fmt.Fprintln(p.table, strings.Join(cells, "\t"))
+		return p.table.Flush()
+	default:
+		for _, value := range values {
+			if _, err := fmt.Println(compactJSON(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// compactJSON renders strings bare and everything else as JSON
+func compactJSON(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// pathStep is one step of a jq-like path: a field, an array index or,
+// with all set, every element
+type pathStep struct {
+	field string
+	index int
+	all   bool
+	isKey bool
+}
+
+// parsePath parses paths such as .txs[0].amount or .[].id; an empty path selects nothing
+func parsePath(path string) ([]pathStep, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if path == "." {
+		return []pathStep{}, nil
+	}
+	var steps []pathStep
+	rest := path
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("query %q: unclosed [", path)
+			}
+			inner := rest[1:end]
+			if inner == "" {
+				steps = append(steps, pathStep{all: true})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("query %q: invalid index %q", path, inner)
+				}
+				steps = append(steps, pathStep{index: n})
+			}
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end > 0 {
+				steps = append(steps, pathStep{field: rest[:end], isKey: true})
+			}
+			rest = rest[end:]
+		default:
+			return nil, fmt.Errorf("query %q: expected . or [ at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// selectPath returns the values the path reaches in v; missing fields and
+// out of range indexes select nothing
+func selectPath(v interface{}, steps []pathStep) []interface{} {
+	if len(steps) == 0 {
+		return []interface{}{v}
+	}
+	step, rest := steps[0], steps[1:]
+	switch {
+	case step.isKey:
+		if object, ok := v.(map[string]interface{}); ok {
+			if field, ok := object[step.field]; ok {
+				return selectPath(field, rest)
+			}
+		}
+	case step.all:
+		var values []interface{}
+		switch container := v.(type) {
+		case []interface{}:
+			for _, element := range container {
+				values = append(values, selectPath(element, rest)...)
+			}
+		case map[string]interface{}:
+			for _, element := range container {
+				values = append(values, selectPath(element, rest)...)
+			}
+		}
+		return values
+	default:
+		if array, ok := v.([]interface{}); ok {
+			index := step.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return selectPath(array[index], rest)
+			}
+		}
+	}
+	return nil
+}
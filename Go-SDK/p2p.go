@@ -0,0 +1,123 @@
+//go:build libp2p
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// finalizedProtocol is the request-response protocol serving finalized pages
+const finalizedProtocol = protocol.ID("/zellular/finalized/1.0.0")
+
+// finalizedRequest asks a peer for the finalized batches of an app following index After
+type finalizedRequest struct {
+	AppName string `json:"app_name"`
+	After   int    `json:"after"`
+}
+
+// P2PTransport fetches finalized batches directly from operator peers over
+// libp2p, so operators need not expose public HTTP endpoints. It is
+// experimental and only built with the libp2p build tag.
+type P2PTransport struct {
+	// Timeout bounds a single request to one peer
+	Timeout time.Duration
+
+	host  host.Host
+	peers []peer.AddrInfo
+}
+
+// NewP2PTransport creates a dial-only libp2p host for the given peer multiaddrs
+// (e.g. /ip4/1.2.3.4/tcp/4001/p2p/12D3Koo...)
+func NewP2PTransport(peerAddrs []string) (*P2PTransport, error) {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return nil, err
+	}
+	t := &P2PTransport{Timeout: 10 * time.Second, host: h}
+	for _, addr := range peerAddrs {
+		info, err := peer.AddrInfoFromString(addr)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("zellular: invalid peer address %q: %w", addr, err)
+		}
+		t.peers = append(t.peers, *info)
+	}
+	return t, nil
+}
+
+// WithP2P fetches finalized batches through t instead of the base node's HTTP API
+func WithP2P(t *P2PTransport) Option {
+	return func(z *Zellular) {
+		z.pages = t
+	}
+}
+
+// fetchPage asks the peers in turn until one serves the page
+func (t *P2PTransport) fetchPage(ctx context.Context, appName string, after int) (*finalizedPage, error) {
+	if len(t.peers) == 0 {
+		return nil, errors.New("zellular: no libp2p peers configured")
+	}
+	var lastErr error
+	for _, info := range t.peers {
+		page, err := t.request(ctx, info, finalizedRequest{AppName: appName, After: after})
+		if err == nil {
+			return page, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// request performs one request-response exchange with a peer
+func (t *P2PTransport) request(ctx context.Context, info peer.AddrInfo, req finalizedRequest) (*finalizedPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	if err := t.host.Connect(ctx, info); err != nil {
+		return nil, err
+	}
+	stream, err := t.host.NewStream(ctx, info.ID, finalizedProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(stream).Encode(req); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	if err := stream.CloseWrite(); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+
+	var response struct {
+		Data *finalizedPage `json:"data"`
+	}
+	if err := json.NewDecoder(bufio.NewReader(stream)).Decode(&response); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	if response.Data != nil {
+		response.Data.node = info.ID.String()
+	}
+	return response.Data, nil
+}
+
+// Close shuts down the libp2p host
+func (t *P2PTransport) Close() error {
+	return t.host.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WithVerifyConcurrency caps how many signature checks run at once across
+// all goroutines using this instance. Pairings are CPU bound, so the cap
+// is roughly the number of cores verification may occupy; it defaults to
+// GOMAXPROCS.
+func WithVerifyConcurrency(n int) Option {
+	return func(z *Zellular) {
+		if n < 1 {
+			n = 1
+		}
+		z.verifySlots = make(chan struct{}, n)
+	}
+}
+
+// defaultVerifySlots is GOMAXPROCS slots
+func defaultVerifySlots() chan struct{} {
+	return make(chan struct{}, runtime.GOMAXPROCS(0))
+}
+
+// VerifyProofs verifies proofs in parallel within the verification cap and
+// returns their results in order; the error is the first verification
+// that could not be carried out
+func (z *Zellular) VerifyProofs(proofs []FinalityProof) ([]VerificationResult, error) {
+	results := make([]VerificationResult, len(proofs))
+	errs := make([]error, len(proofs))
+	workers := cap(z.verifySlots)
+	if workers > len(proofs) {
+		workers = len(proofs)
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = z.VerifyProof(proofs[i])
+			}
+		}()
+	}
+	for i := range proofs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
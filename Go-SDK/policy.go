@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// QuorumInput is what a QuorumPolicy decides on. Nonsigners have been
+// validated against Operators, and Signers are all the other operators.
+type QuorumInput struct {
+	Operators  map[string]Operator
+	Signers    []string
+	Nonsigners []string
+	Message    string
+}
+
+// QuorumPolicy decides whether a set of signers may finalize a message.
+// Evaluate returns nil to accept and an error describing the rejection otherwise.
+type QuorumPolicy interface {
+	Evaluate(input QuorumInput) error
+}
+
+// QuorumPolicyFunc adapts a function to a QuorumPolicy
+type QuorumPolicyFunc func(input QuorumInput) error
+
+// Evaluate calls f
+func (f QuorumPolicyFunc) Evaluate(input QuorumInput) error {
+	return f(input)
+}
+
+// StakeThreshold is the default policy: signers must hold at least Percent of the total stake
+type StakeThreshold struct {
+	Percent float64
+}
+
+// Evaluate accepts when the nonsigners hold no more than 100-Percent of the stake
+func (p StakeThreshold) Evaluate(input QuorumInput) error {
+	total, nonsigning := 0.0, 0.0
+	for _, operator := range input.Operators {
+		total += operator.Stake
+	}
+	for _, id := range input.Nonsigners {
+		nonsigning += input.Operators[id].Stake
+	}
+	if total == 0 || 100*nonsigning/total > 100-p.Percent {
+		return fmt.Errorf("signers hold %v of %v stake, below the %.2f%% threshold", total-nonsigning, total, p.Percent)
+	}
+	return nil
+}
+
+// RequireOperators accepts only when every listed operator signed
+type RequireOperators []string
+
+// Evaluate rejects if any required operator is among the nonsigners
+func (p RequireOperators) Evaluate(input QuorumInput) error {
+	for _, required := range p {
+		for _, nonsigner := range input.Nonsigners {
+			if nonsigner == required {
+				return fmt.Errorf("required operator %s did not sign", required)
+			}
+		}
+	}
+	return nil
+}
+
+// MinSigners accepts when at least N distinct operators signed
+type MinSigners int
+
+// Evaluate rejects when fewer than N operators signed
+func (p MinSigners) Evaluate(input QuorumInput) error {
+	if len(input.Signers) < int(p) {
+		return fmt.Errorf("%d operators signed, at least %d required", len(input.Signers), int(p))
+	}
+	return nil
+}
+
+// DualQuorum requires both StakePercent of the stake and at least
+// MinSigners distinct signers, so on small networks a single operator
+// holding most of the stake cannot finalize on its own
+type DualQuorum struct {
+	StakePercent float64
+	MinSigners   int
+}
+
+// Evaluate rejects when either threshold is not met
+func (p DualQuorum) Evaluate(input QuorumInput) error {
+	return AllOf{StakeThreshold{Percent: p.StakePercent}, MinSigners(p.MinSigners)}.Evaluate(input)
+}
+
+// WithDualQuorum additionally requires minSigners distinct signers on top
+// of the instance's stake threshold
+func WithDualQuorum(minSigners int) Option {
+	return func(z *Zellular) {
+		z.policy = QuorumPolicyFunc(func(input QuorumInput) error {
+			return DualQuorum{StakePercent: z.ThresholdPercent, MinSigners: minSigners}.Evaluate(input)
+		})
+	}
+}
+
+// AllOf accepts only when every policy accepts
+type AllOf []QuorumPolicy
+
+// Evaluate returns the first rejection
+func (p AllOf) Evaluate(input QuorumInput) error {
+	for _, policy := range p {
+		if err := policy.Evaluate(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithQuorumPolicy replaces the stake threshold with a custom quorum rule.
+// Combine it with StakeThreshold through AllOf to keep the threshold.
+func WithQuorumPolicy(policy QuorumPolicy) Option {
+	return func(z *Zellular) {
+		z.policy = policy
+	}
+}
+
+// quorumPolicy returns the configured policy or the stake threshold
+func (z *Zellular) quorumPolicy() QuorumPolicy {
+	if z.policy != nil {
+		return z.policy
+	}
+	return StakeThreshold{Percent: z.ThresholdPercent}
+}
+
+// quorumInput resolves validated nonsigners to operator IDs and derives the signers
+func (z *Zellular) quorumInput(message string, nonsigners []string) QuorumInput {
+	input := QuorumInput{Operators: z.operators(), Message: message}
+	excluded := make(map[string]bool, len(nonsigners))
+	for _, nonsigner := range nonsigners {
+		operator, _ := lookupOperator(input.Operators, nonsigner)
+		excluded[operator.ID] = true
+		input.Nonsigners = append(input.Nonsigners, operator.ID)
+	}
+	for id := range input.Operators {
+		if !excluded[id] {
+			input.Signers = append(input.Signers, id)
+		}
+	}
+	sort.Strings(input.Signers)
+	return input
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// poolStats publishes per-pool request, failure and failover counts as "zellular_endpoint_pools"
+var poolStats = expvar.NewMap("zellular_endpoint_pools")
+
+const (
+	// poolMaxFailures is how many consecutive failures mark an endpoint down
+	poolMaxFailures = 3
+	// poolCooldown is how long a down endpoint is skipped
+	poolCooldown = 30 * time.Second
+)
+
+// EndpointPool is a named group of base endpoints, such as a regional
+// gateway. Pools with a lower Priority are preferred.
+type EndpointPool struct {
+	Name      string
+	Priority  int
+	Endpoints []string
+}
+
+// endpointPools tracks endpoint health and picks the preferred healthy endpoint
+type endpointPools struct {
+	mu        sync.Mutex
+	pools     []EndpointPool
+	failures  map[string]int
+	downUntil map[string]time.Time
+}
+
+// WithEndpointPools reads from and submits to the given pools instead of a
+// single base node. The first healthy endpoint of the highest-priority pool
+// is used; after repeated failures an endpoint is skipped for a while and
+// traffic fails over to the next endpoint or pool, moving back once the
+// preferred pool is healthy again.
+func WithEndpointPools(pools ...EndpointPool) Option {
+	return func(z *Zellular) {
+		sorted := append([]EndpointPool(nil), pools...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority < sorted[j].Priority })
+		z.pools = &endpointPools{
+			pools:     sorted,
+			failures:  make(map[string]int),
+			downUntil: make(map[string]time.Time),
+		}
+		if _, endpoint := z.pools.pick(z.chooseNode); endpoint != "" {
+			z.BaseURL = endpoint
+		}
+	}
+}
+
+// pick returns the pool with the highest priority that has healthy
+// endpoints and the one of them choose picks, falling back to the first
+// endpoint when everything is down
+func (p *endpointPools) pick(choose func(endpoints []string) string) (string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, pool := range p.pools {
+		var healthy []string
+		for _, endpoint := range pool.Endpoints {
+			if now.After(p.downUntil[endpoint]) {
+				healthy = append(healthy, endpoint)
+			}
+		}
+		if len(healthy) > 0 {
+			return pool.Name, choose(healthy)
+		}
+	}
+	for _, pool := range p.pools {
+		if len(pool.Endpoints) > 0 {
+			return pool.Name, pool.Endpoints[0]
+		}
+	}
+	return "", ""
+}
+
+// poolOf returns the pool an endpoint belongs to
+func (p *endpointPools) poolOf(endpoint string) (EndpointPool, bool) {
+	for _, pool := range p.pools {
+		for _, e := range pool.Endpoints {
+			if e == endpoint {
+				return pool, true
+			}
+		}
+	}
+	return EndpointPool{}, false
+}
+
+// observe records the outcome of a request to endpoint
+func (p *endpointPools) observe(endpoint string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pool, ok := p.poolOf(endpoint)
+	if !ok {
+		return
+	}
+	poolStats.Add(pool.Name+" requests", 1)
+	if err == nil {
+		p.failures[endpoint] = 0
+		return
+	}
+	poolStats.Add(pool.Name+" failures", 1)
+	p.failures[endpoint]++
+	if p.failures[endpoint] >= poolMaxFailures {
+		p.failures[endpoint] = 0
+		p.downUntil[endpoint] = time.Now().Add(poolCooldown)
+	}
+}
+
+// breakers reports the breaker of every endpoint, in pool order
+func (p *endpointPools) breakers(now time.Time) []BreakerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var states []BreakerState
+	for _, pool := range p.pools {
+		for _, endpoint := range pool.Endpoints {
+			states = append(states, breakerState("pool", endpoint, p.failures, p.downUntil, now))
+		}
+	}
+	return states
+}
+
+// observeEndpoint records a request to endpoint and switches the base node
+// when it failed or a more preferred pool became healthy
+func (z *Zellular) observeEndpoint(endpoint string, err error) {
+	if z.pools == nil {
+		return
+	}
+	z.pools.observe(endpoint, err)
+	name, next := z.pools.pick(z.chooseNode)
+	if next == "" || next == endpoint {
+		return
+	}
+	current, _ := z.pools.poolOf(endpoint)
+	preferred, _ := z.pools.poolOf(next)
+	if err == nil && preferred.Priority >= current.Priority {
+		return
+	}
+	z.setBase(next)
+	poolStats.Add(name+" failovers", 1)
+}
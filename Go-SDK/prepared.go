@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// pairingLines are the Miller loop line evaluations precomputed for a fixed G2 point
+type pairingLines = [2][len(bn254.LoopCounter)]bn254.LineEvaluationAff
+
+// preparedKeys is how many signer sets a PreparedVerifier keeps lines for
+const preparedKeys = 64
+
+// PreparedVerifier verifies signatures like Zellular.VerifySignature but
+// precomputes the Miller loop lines of the G2 generator and of each signer
+// set's aggregated key once, then reuses them. Nonsigner sets repeat a lot
+// in practice, so high-throughput verifiers skip most of the G2 work; the
+// bench command reports the speedup on the local machine.
+type PreparedVerifier struct {
+	z         *Zellular
+	generator pairingLines
+
+	mu    sync.Mutex
+	apk   bn254.G2Affine
+	keys  map[string]*pairingLines
+	order []string
+}
+
+// Prepare returns a PreparedVerifier for z's operator set
+func (z *Zellular) Prepare() *PreparedVerifier {
+	_, _, _, g2 := bn254.Generators()
+	_, apk := z.OperatorSet()
+	return &PreparedVerifier{
+		z:         z,
+		generator: bn254.PrecomputeLines(g2),
+		apk:       apk,
+		keys:      make(map[string]*pairingLines),
+	}
+}
+
+// VerifySignature verifies a signature as Zellular.VerifySignature does
+func (p *PreparedVerifier) VerifySignature(message, signatureHex string, nonsigners []string) (VerificationResult, error) {
+	return p.z.verifySignature(message, signatureHex, nonsigners, p.check)
+}
+
+// VerifyProof verifies a finality proof as Zellular.VerifyProof does
+func (p *PreparedVerifier) VerifyProof(proof FinalityProof) (VerificationResult, error) {
+	if reason := p.z.foreignProof(proof); reason != "" {
+		return VerificationResult{}.fail(InvalidSignature, reason), nil
+	}
+	return p.VerifySignature(proof.Message(), proof.Signature, proof.Nonsigners)
+}
+
+// check is the signatureCheck using precomputed lines
+func (p *PreparedVerifier) check(nonsigners []string, message []byte, signature *bn254.G1Affine) (bool, error) {
+	h := p.z.messagePoint(message)
+	var negated bn254.G1Affine
+	negated.Neg(&h)
+	return bn254.PairingCheckFixedQ(
+		[]bn254.G1Affine{*signature, negated},
+		[]pairingLines{p.generator, *p.lines(nonsigners)},
+	)
+}
+
+// lines returns the precomputed lines of the signers' aggregated key,
+// dropping every cached set when the operator set changed
+func (p *PreparedVerifier) lines(nonsigners []string) *pairingLines {
+	key := signerSetKey(nonsigners)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, apk := p.z.OperatorSet(); !p.apk.Equal(&apk) {
+		p.apk = apk
+		p.keys = make(map[string]*pairingLines)
+		p.order = nil
+	}
+	if lines, ok := p.keys[key]; ok {
+		return lines
+	}
+
+	publicKey := p.z.signersPublicKey(nonsigners)
+	lines := bn254.PrecomputeLines(publicKey)
+	if len(p.order) == preparedKeys {
+		delete(p.keys, p.order[0])
+		p.order = p.order[1:]
+	}
+	p.keys[key] = &lines
+	p.order = append(p.order, key)
+	return &lines
+}
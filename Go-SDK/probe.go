@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProbeResult is the round trip time measured to one operator
+type ProbeResult struct {
+	OperatorID string
+	Socket     string
+	RTT        time.Duration
+	Err        error
+}
+
+// ProbeOperators measures the round trip time to every operator concurrently
+// and returns the results fastest first, unreachable operators last
+func (z *Zellular) ProbeOperators(ctx context.Context, timeout time.Duration) []ProbeResult {
+	operators := z.operators()
+	results := make([]ProbeResult, 0, len(operators))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for id, operator := range withSockets(operators) {
+		wg.Add(1)
+		go func(id, socket string) {
+			defer wg.Done()
+			result := ProbeResult{OperatorID: id, Socket: socket}
+			result.RTT, result.Err = z.probe(ctx, socket, timeout)
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(id, operator.Socket)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].RTT < results[j].RTT
+	})
+	return results
+}
+
+// probe times one request to the last finalized batch of an operator
+func (z *Zellular) probe(ctx context.Context, socket string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/node/%s/batches/finalized/last", socket, z.AppName)
+	start := time.Now()
+	resp, err := z.transport.do(withReadRole(ctx), http.MethodGet, url, "", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("zellular: probe of %s returned status %d", socket, resp.StatusCode)
+	}
+	return time.Since(start), nil
+}
+
+// SelectFastest switches the base node to the reachable operator with the lowest round trip time
+func (z *Zellular) SelectFastest(ctx context.Context, timeout time.Duration) (ProbeResult, error) {
+	results := z.ProbeOperators(ctx, timeout)
+	if len(results) == 0 || results[0].Err != nil {
+		return ProbeResult{}, errors.New("zellular: no reachable operator")
+	}
+	z.setBase(results[0].Socket)
+	return results[0], nil
+}
+
+// StartProbing re-probes the operators every interval and moves to the
+// fastest one, adapting to network changes until ctx is done
+func (z *Zellular) StartProbing(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := z.SelectFastest(ctx, timeout); err != nil {
+			z.errors.record(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NetworkProfile bundles the settings of one Zellular network
+type NetworkProfile struct {
+	Name string
+	// SubgraphURL is the BLS APK registry subgraph operators are loaded from
+	SubgraphURL string
+	// Discovery replaces the subgraph on networks without one
+	Discovery Discovery
+	// SocketDiscovery supplies the sockets of the registry's operators on
+	// networks whose nodes do not publish them
+	SocketDiscovery SocketDiscovery
+	// APKRegistry is the address of the BLS APK registry contract the
+	// operator set is checked against when the client is given an
+	// EthClient, empty to skip the check
+	APKRegistry string
+	// APKQuorum is the registry quorum of the network's operators
+	APKQuorum uint8
+	// NetworkID is the ID the network's nodes sign and declare, empty on
+	// networks whose nodes do not
+	NetworkID string
+	// DomainSeparation is nil on networks using the undifferentiated tags
+	DomainSeparation *DomainSeparation
+	// ThresholdPercent applies when NewZellular is given a zero threshold
+	ThresholdPercent float64
+}
+
+var (
+	profilesMu sync.RWMutex
+	// profiles holds the built-in and registered networks. There is no
+	// mainnet preset because no mainnet deployment has been published:
+	// there is no subgraph, registry address or network ID to bundle.
+	// RegisterNetwork adds it once they are. The testnet registry address
+	// is not published either, so its profile leaves the APK check off.
+	profiles = map[string]NetworkProfile{
+		"testnet": {
+			Name:             "testnet",
+			SubgraphURL:      subgraphURL,
+			ThresholdPercent: 67,
+		},
+		// local nodes are found over mDNS; their keys and stakes still come
+		// from the registry, e.g. WithDiscovery(SnapshotDiscovery{...})
+		"local": {
+			Name:             "local",
+			SocketDiscovery:  MDNSDiscovery{Service: "_zellular._tcp"},
+			NetworkID:        "local",
+			ThresholdPercent: 67,
+		},
+	}
+)
+
+// RegisterNetwork adds or replaces a profile selectable with WithNetwork
+func RegisterNetwork(profile NetworkProfile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[profile.Name] = profile
+}
+
+// LookupNetwork returns the profile registered under name
+func LookupNetwork(name string) (NetworkProfile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	profile, ok := profiles[name]
+	return profile, ok
+}
+
+// WithNetwork applies the settings of a named network profile such as
+// "testnet" or "local". Options given after it override its settings. A
+// profile with an APKRegistry checks the operator set on chain once the
+// client is given an EthClient with WithEthClient. An unknown name, or
+// "mainnet" until it is deployed, is recorded as an error and leaves the
+// client bound to a network ID no node declares, so it accepts nothing.
+func WithNetwork(name string) Option {
+	return func(z *Zellular) {
+		profile, ok := LookupNetwork(name)
+		if !ok && name == "mainnet" {
+			z.errors.record(errors.New("zellular: no mainnet deployment has been published; register its profile with RegisterNetwork"))
+			z.networkID = name
+			return
+		}
+		if !ok {
+			z.errors.record(fmt.Errorf("zellular: unknown network %q", name))
+			z.networkID = name
+			return
+		}
+		switch {
+		case profile.Discovery != nil:
+			z.discovery = profile.Discovery
+		case profile.SubgraphURL != "" && profile.SubgraphURL != subgraphURL:
+			z.discovery = SubgraphDiscovery{URL: profile.SubgraphURL}
+		}
+		if profile.SocketDiscovery != nil {
+			z.sockets = profile.SocketDiscovery
+		}
+		if profile.APKRegistry != "" {
+			z.apkCheck = &onChainAPK{eth: z.eth, registry: profile.APKRegistry, quorum: profile.APKQuorum}
+		}
+		z.networkID = profile.NetworkID
+		z.domain = profile.DomainSeparation
+		if z.ThresholdPercent == 0 {
+			z.ThresholdPercent = profile.ThresholdPercent
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// FinalityProof is the material needed to verify independently that a
+// batch was finalized: the signed finalization message fields plus the
+// aggregated signature and the operators that did not sign
+type FinalityProof struct {
+	AppName      string   `json:"app_name"`
+	Index        int      `json:"index"`
+	Hash         string   `json:"hash"`
+	ChainingHash string   `json:"chaining_hash"`
+	Signature    string   `json:"signature"`
+	Nonsigners   []string `json:"nonsigners"`
+	// Network is the network the batch was finalized on, empty on networks
+	// that do not sign it
+	Network string `json:"network,omitempty"`
+}
+
+// Message returns the finalization message the operators signed, encoded
+// exactly as the nodes do (Python json.dumps with sorted keys)
+func (p FinalityProof) Message() string {
+	if p.Network != "" {
+		return fmt.Sprintf(`{"app_name": %s, "chaining_hash": %s, "hash": %s, "index": %d, "network": %s, "state": "locked"}`,
+			pyQuote(p.AppName), pyQuote(p.ChainingHash), pyQuote(p.Hash), p.Index, pyQuote(p.Network))
+	}
+	return fmt.Sprintf(`{"app_name": %s, "chaining_hash": %s, "hash": %s, "index": %d, "state": "locked"}`,
+		pyQuote(p.AppName), pyQuote(p.ChainingHash), pyQuote(p.Hash), p.Index)
+}
+
+// VerifyProof checks the proof's signature against the operator set, with
+// the keys that were valid at the proof's index for operators that rotated
+func (z *Zellular) VerifyProof(p FinalityProof) (VerificationResult, error) {
+	if reason := z.foreignProof(p); reason != "" {
+		return VerificationResult{}.fail(InvalidSignature, reason), nil
+	}
+	if z.keys.differsAt(p.Index, z.operators()) {
+		return z.verifySignature(p.Message(), p.Signature, p.Nonsigners, z.checkSignatureAt(p.Index))
+	}
+	return z.VerifySignature(p.Message(), p.Signature, p.Nonsigners)
+}
+
+// pyQuote quotes s like Python's json.dumps with ensure_ascii
+func pyQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			switch {
+			case r < 0x20 || r == 0x7f:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			case r < 0x7f:
+				b.WriteRune(r)
+			case r > 0xffff:
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&b, `\u%04x\u%04x`, r1, r2)
+			default:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
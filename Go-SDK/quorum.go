@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NonsignerStake is the stake of one operator that did not sign
+type NonsignerStake struct {
+	ID    string  `json:"id"`
+	Stake float64 `json:"stake"`
+}
+
+// QuorumReport breaks down the stake behind a verification so quorum
+// failures can be debugged without recomputing the threshold math
+type QuorumReport struct {
+	TotalStake       float64          `json:"total_stake"`
+	SigningStake     float64          `json:"signing_stake"`
+	NonsignerStake   float64          `json:"nonsigner_stake"`
+	Nonsigners       []NonsignerStake `json:"nonsigners"`
+	SigningPercent   float64          `json:"signing_percent"`
+	ThresholdPercent float64          `json:"threshold_percent"`
+	Met              bool             `json:"met"`
+	// UnknownNonsigners lists nonsigners missing from the operator set or
+	// listed more than once; any of them makes the quorum unmet
+	UnknownNonsigners []string `json:"unknown_nonsigners,omitempty"`
+}
+
+// UnknownNonsignerError reports nonsigner IDs that are not in the operator
+// set or are repeated
+type UnknownNonsignerError struct {
+	IDs []string
+}
+
+func (e *UnknownNonsignerError) Error() string {
+	return "zellular: unknown or duplicate nonsigners: " + strings.Join(e.IDs, ", ")
+}
+
+// ValidateNonsigners checks that every nonsigner is a distinct member of the operator set
+func (z *Zellular) ValidateNonsigners(nonsigners []string) error {
+	var invalid []string
+	seen := make(map[string]bool, len(nonsigners))
+	for _, nonsigner := range nonsigners {
+		operator, ok := z.LookupOperator(nonsigner)
+		if !ok || seen[operator.ID] {
+			invalid = append(invalid, nonsigner)
+		}
+		seen[operator.ID] = true
+	}
+	if len(invalid) > 0 {
+		return &UnknownNonsignerError{IDs: invalid}
+	}
+	return nil
+}
+
+// String describes the report, largest nonsigners first
+func (r QuorumReport) String() string {
+	nonsigners := make([]string, len(r.Nonsigners))
+	for i, n := range r.Nonsigners {
+		nonsigners[i] = fmt.Sprintf("%s=%v", n.ID, n.Stake)
+	}
+	return fmt.Sprintf("signing stake %v of %v (%.2f%%, threshold %.2f%%), nonsigners: [%s]",
+		r.SigningStake, r.TotalStake, r.SigningPercent, r.ThresholdPercent, strings.Join(nonsigners, ", "))
+}
+
+// CheckQuorum computes whether the operators other than nonsigners hold
+// enough stake to meet the threshold. Unknown or repeated nonsigners are
+// reported instead of being counted, and fail the quorum.
+func (z *Zellular) CheckQuorum(nonsigners []string) QuorumReport {
+	report := QuorumReport{ThresholdPercent: z.ThresholdPercent}
+	for _, operator := range z.operators() {
+		report.TotalStake += operator.Stake
+	}
+	seen := make(map[string]bool, len(nonsigners))
+	for _, nonsigner := range nonsigners {
+		operator, ok := z.LookupOperator(nonsigner)
+		if !ok || seen[operator.ID] {
+			report.UnknownNonsigners = append(report.UnknownNonsigners, nonsigner)
+			continue
+		}
+		seen[operator.ID] = true
+		report.NonsignerStake += operator.Stake
+		report.Nonsigners = append(report.Nonsigners, NonsignerStake{ID: operator.ID, Stake: operator.Stake})
+	}
+	sort.SliceStable(report.Nonsigners, func(i, j int) bool {
+		return report.Nonsigners[i].Stake > report.Nonsigners[j].Stake
+	})
+
+	report.SigningStake = report.TotalStake - report.NonsignerStake
+	if report.TotalStake > 0 {
+		report.SigningPercent = 100 * report.SigningStake / report.TotalStake
+		report.Met = 100*report.NonsignerStake/report.TotalStake <= 100-z.ThresholdPercent
+	}
+	if len(report.UnknownNonsigners) > 0 {
+		report.Met = false
+	}
+	return report
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// usageStats publishes what each app submitted as "zellular_usage"
+var usageStats = expvar.NewMap("zellular_usage")
+
+// Quota is a node's rate limit as last advertised in its RateLimit headers.
+// Nodes publish no fee schedule; a metered network signals its limits
+// through these headers and 429 responses.
+type Quota struct {
+	Limit     int64     `json:"limit"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	// Observed is when the headers were received
+	Observed time.Time `json:"observed"`
+}
+
+// quotas holds the latest Quota per host
+var quotas sync.Map
+
+// observeQuota records the quota advertised by resp, if any
+func observeQuota(host string, resp *http.Response, now time.Time) {
+	q, ok := parseQuota(resp.Header, now)
+	if ok {
+		quotas.Store(host, q)
+	}
+}
+
+// parseQuota reads the standard or X- prefixed RateLimit headers; Reset is
+// given in seconds from now
+func parseQuota(header http.Header, now time.Time) (Quota, bool) {
+	get := func(name string) (int64, bool) {
+		value := header.Get("RateLimit-" + name)
+		if value == "" {
+			value = header.Get("X-RateLimit-" + name)
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		return n, err == nil
+	}
+	q := Quota{Observed: now}
+	limit, hasLimit := get("Limit")
+	remaining, hasRemaining := get("Remaining")
+	if !hasLimit && !hasRemaining {
+		return q, false
+	}
+	q.Limit, q.Remaining = limit, remaining
+	if reset, ok := get("Reset"); ok {
+		q.Reset = now.Add(time.Duration(reset) * time.Second)
+	}
+	return q, true
+}
+
+// Quota returns the rate limit last advertised by the base node
+func (z *Zellular) Quota() (Quota, bool) {
+	u, err := url.Parse(z.base())
+	if err != nil {
+		return Quota{}, false
+	}
+	q, ok := quotas.Load(u.Host)
+	if !ok {
+		return Quota{}, false
+	}
+	return q.(Quota), true
+}
+
+// QuotaExceededError is returned by Send when the node keeps refusing the
+// batch with 429 Too Many Requests
+type QuotaExceededError struct {
+	Node  string
+	Quota Quota
+	// RetryAfter is how long the node asked to wait, zero if it did not say
+	RetryAfter time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	msg := "zellular: quota of " + e.Node + " exceeded"
+	switch {
+	case e.RetryAfter > 0:
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	case !e.Quota.Reset.IsZero():
+		msg += ", resets at " + e.Quota.Reset.Format(time.RFC3339)
+	}
+	return msg
+}
+
+// quotaExceeded builds the QuotaExceededError of a 429 response
+func quotaExceeded(node string, resp *http.Response) *QuotaExceededError {
+	now := time.Now()
+	q, _ := parseQuota(resp.Header, now)
+	return &QuotaExceededError{Node: node, Quota: q, RetryAfter: parseRetryAfter(resp.Header, now)}
+}
+
+// Usage is what a client submitted since it was created
+type Usage struct {
+	Batches      int64 `json:"batches"`
+	Transactions int64 `json:"transactions"`
+	Bytes        int64 `json:"bytes"`
+}
+
+// usageCounter accumulates a client's Usage
+type usageCounter struct {
+	batches, transactions, bytes int64
+}
+
+// add records a submitted batch of app
+func (u *usageCounter) add(appName string, transactions, bytes int) {
+	atomic.AddInt64(&u.batches, 1)
+	atomic.AddInt64(&u.transactions, int64(transactions))
+	atomic.AddInt64(&u.bytes, int64(bytes))
+	usageStats.Add(appName+" batches", 1)
+	usageStats.Add(appName+" transactions", int64(transactions))
+	usageStats.Add(appName+" bytes", int64(bytes))
+}
+
+// usageLabel is the app name prefixed by the tenant, if any, in the usage metrics
+func (z *Zellular) usageLabel() string {
+	if z.tenant != "" {
+		return z.tenant + "/" + z.AppName
+	}
+	return z.AppName
+}
+
+// Usage reports what this client submitted for its app
+func (z *Zellular) Usage() Usage {
+	return Usage{
+		Batches:      atomic.LoadInt64(&z.usage.batches),
+		Transactions: atomic.LoadInt64(&z.usage.transactions),
+		Bytes:        atomic.LoadInt64(&z.usage.bytes),
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Receipt is an operator's signed acknowledgement of a submitted batch,
+// promising to sequence it between FromIndex and ToIndex
+type Receipt struct {
+	Operator  string `json:"operator"`
+	AppName   string `json:"app_name"`
+	BatchHash string `json:"batch_hash"`
+	FromIndex int    `json:"from_index"`
+	ToIndex   int    `json:"to_index"`
+	Signature string `json:"signature"`
+}
+
+// Message returns the string the operator signs
+func (r Receipt) Message() string {
+	return fmt.Sprintf(`{"app_name": %s, "batch_hash": %s, "from_index": %d, "operator": %s, "to_index": %d}`,
+		pyQuote(r.AppName), pyQuote(r.BatchHash), r.FromIndex, pyQuote(r.Operator), r.ToIndex)
+}
+
+// WithReceipts verifies the receipts nodes return on submission and keeps
+// them in store. Send does not fail when a node returns no receipt or an
+// invalid one; the latter is recorded in the error log. Receipts are kept
+// in the order they were received; pass Compactors to RunRetention to
+// bound them.
+func WithReceipts(store KVStore) Option {
+	return func(z *Zellular) {
+		z.receipts = store
+	}
+}
+
+// receiptKey is where the receipt of a batch is stored
+func receiptKey(appName, batchHash string) string {
+	return "receipts/" + appName + "/" + batchHash
+}
+
+// receiptMarks numbers the batch hashes of the receipts kept for z's app
+// in the order they were received
+func (z *Zellular) receiptMarks() watermarks {
+	return watermarks{store: z.receipts, prefix: "receipts/" + z.AppName + "/log/"}
+}
+
+// dropReceipt deletes the receipt numbered n and its log entry
+func (z *Zellular) dropReceipt(n int) error {
+	marks := z.receiptMarks()
+	key := marks.prefix + strconv.Itoa(n)
+	batchHash, err := z.receipts.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if batchHash != nil {
+		if err := z.receipts.Delete(receiptKey(z.AppName, string(batchHash))); err != nil {
+			return err
+		}
+	}
+	return z.receipts.Delete(key)
+}
+
+// VerifyReceipt checks the receipt's signature by its operator
+func (z *Zellular) VerifyReceipt(r Receipt) error {
+	operator, ok := z.operators()[r.Operator]
+	if !ok {
+		return fmt.Errorf("zellular: receipt signed by unknown operator %s", r.Operator)
+	}
+	signature, err := decodeSignature(r.Signature)
+	if err != nil {
+		return err
+	}
+	h := z.messagePoint([]byte(hash(r.Message())))
+	valid, err := verifyBLS(&operator.PublicKeyG2, &h, &signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("zellular: invalid receipt signature by operator %s", r.Operator)
+	}
+	return nil
+}
+
+// keepReceipt verifies and stores the receipt in a submission response, if any
+func (z *Zellular) keepReceipt(resp *http.Response, payload []byte) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data struct {
+			Receipt *Receipt `json:"receipt"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(body, &response) != nil || response.Data.Receipt == nil {
+		return nil
+	}
+	r := *response.Data.Receipt
+	if r.AppName != z.AppName || r.BatchHash != z.batchHash(string(payload)) {
+		return fmt.Errorf("zellular: receipt of operator %s is for another batch", r.Operator)
+	}
+	if err := z.VerifyReceipt(r); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := z.receipts.Put(receiptKey(z.AppName, r.BatchHash), data); err != nil {
+		return err
+	}
+	_, err = z.receiptMarks().append([]byte(r.BatchHash))
+	return err
+}
+
+// Receipt returns the stored receipt of the batch with batchHash
+func (z *Zellular) Receipt(batchHash string) (*Receipt, error) {
+	if z.receipts == nil {
+		return nil, errors.New("zellular: receipts are not enabled")
+	}
+	data, err := z.receipts.Get(receiptKey(z.AppName, batchHash))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("zellular: no receipt for batch %s", batchHash)
+	}
+	var r Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ReceiptViolation proves that an operator acknowledged a batch that was
+// not sequenced within the promised window: the finalized batches of the
+// window, chained from the hash before it to the inclusion proof of its
+// last batch, none of which is the acknowledged batch
+type ReceiptViolation struct {
+	Receipt              Receipt
+	PreviousChainingHash string
+	Window               []string
+	Last                 BatchInclusion
+}
+
+// Verify checks the receipt, that the window chains to a verified proof
+// and that the acknowledged batch is not in it
+func (v ReceiptViolation) Verify(z *Zellular) (VerificationResult, error) {
+	if err := z.VerifyReceipt(v.Receipt); err != nil {
+		return VerificationResult{}.fail(InvalidSignature, err.Error()), nil
+	}
+	if len(v.Window) != v.Receipt.ToIndex-v.Receipt.FromIndex+1 || v.Last.Batch.Index != v.Receipt.ToIndex {
+		return VerificationResult{}.fail(InvalidSignature, "window does not match the receipt"), nil
+	}
+	chainingHash := v.PreviousChainingHash
+	for _, payload := range v.Window {
+		if z.batchHash(payload) == v.Receipt.BatchHash {
+			return VerificationResult{}.fail(InvalidSignature, "batch was sequenced within the window"), nil
+		}
+		chainingHash = z.chain(chainingHash, payload)
+	}
+	if chainingHash != z.chain(v.Last.PreviousChainingHash, v.Last.Batch.Payload) {
+		return VerificationResult{}.fail(InvalidSignature, "window does not chain to its last batch"), nil
+	}
+	return v.Last.Verify(z)
+}
+
+// CheckReceipt returns the proof of a violation if the batch of r was not
+// sequenced within its window, nil if it was. The window must be finalized.
+func (z *Zellular) CheckReceipt(ctx context.Context, r Receipt) (*ReceiptViolation, error) {
+	if r.FromIndex < 1 || r.ToIndex < r.FromIndex {
+		return nil, fmt.Errorf("zellular: invalid receipt window %d-%d", r.FromIndex, r.ToIndex)
+	}
+	batches, err := z.fetchRange(ctx, r.FromIndex, r.ToIndex)
+	if err != nil {
+		return nil, err
+	}
+	v := &ReceiptViolation{Receipt: r}
+	for _, batch := range batches {
+		if z.batchHash(batch.Payload) == r.BatchHash {
+			return nil, nil
+		}
+		v.Window = append(v.Window, batch.Payload)
+	}
+	first, err := z.GetBatch(ctx, r.FromIndex)
+	if err != nil {
+		return nil, err
+	}
+	v.PreviousChainingHash = first.PreviousChainingHash
+	last, err := z.GetBatch(ctx, r.ToIndex)
+	if err != nil {
+		return nil, err
+	}
+	v.Last = *last
+	return v, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// signedReceipt is the receipt of payload signed by the operator with secret
+func signedReceipt(z *Zellular, operator string, secret *big.Int, payload string, from, to int) Receipt {
+	r := Receipt{Operator: operator, AppName: z.AppName, BatchHash: z.batchHash(payload), FromIndex: from, ToIndex: to}
+	h := z.messagePoint([]byte(hash(r.Message())))
+	var signature bn254.G1Affine
+	signature.ScalarMultiplication(&h, secret)
+	compressed := signature.Bytes()
+	r.Signature = hex.EncodeToString(compressed[:])
+	return r
+}
+
+// receiptResponse is a submission response carrying r
+func receiptResponse(r Receipt) *http.Response {
+	body, _ := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"receipt": r}})
+	return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(string(body)))}
+}
+
+func TestReceiptsUseClientHasher(t *testing.T) {
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`}
+	z, sandbox := sandboxWith(t, payloads, WithHasher(sha256Hasher{}), WithReceipts(NewMemoryKVStore()))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r := signedReceipt(z, sandboxOperator, sandbox.secret, `["b"]`, 1, 3)
+	if err := z.keepReceipt(receiptResponse(r), []byte(`["b"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := z.Receipt(r.BatchHash); err != nil {
+		t.Fatal(err)
+	}
+	violation, err := z.CheckReceipt(ctx, r)
+	if err != nil || violation != nil {
+		t.Fatalf("batch sequenced within its window reported as a violation: %+v, %v", violation, err)
+	}
+
+	missing := signedReceipt(z, sandboxOperator, sandbox.secret, `["x"]`, 1, 3)
+	violation, err = z.CheckReceipt(ctx, missing)
+	if err != nil || violation == nil {
+		t.Fatalf("missing batch not reported: %v", err)
+	}
+	if result, err := violation.Verify(z); err != nil || !result.Valid() {
+		t.Fatalf("violation does not verify: %+v, %v", result, err)
+	}
+}
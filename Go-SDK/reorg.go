@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReorgEvent reports that a node served a batch whose hash differs from the
+// one recorded earlier for the same index
+type ReorgEvent struct {
+	AppName      string
+	Index        int
+	StoredHash   string
+	ObservedHash string
+	// ResolvedHash is the hash a stake quorum of operators agrees on, empty
+	// if no quorum could be reached
+	ResolvedHash string
+	// Node is the node that served the observed batch
+	Node string
+	Time time.Time
+}
+
+// EventKind implements Event
+func (e *ReorgEvent) EventKind() string {
+	return "reorg"
+}
+
+// ReorgError stops a stream at a batch that conflicts with the stored hash
+// and was not confirmed by a quorum
+type ReorgError struct {
+	Event *ReorgEvent
+}
+
+func (e *ReorgError) Error() string {
+	if e.Event.ResolvedHash == "" {
+		return fmt.Sprintf("zellular: batch %d conflicts with its stored hash and no quorum agrees on it", e.Event.Index)
+	}
+	return fmt.Sprintf("zellular: %s served batch %d with hash %s, the quorum agrees on %s",
+		e.Event.Node, e.Event.Index, e.Event.ObservedHash, e.Event.ResolvedHash)
+}
+
+// WithHashIndex records the hash of every released batch in store and
+// checks later deliveries of the same index against it. The index grows by
+// one entry per batch; pass Compactors to RunRetention to bound it.
+func WithHashIndex(store KVStore) Option {
+	return func(z *Zellular) {
+		z.hashIndex = store
+	}
+}
+
+func hashIndexKey(appName string, index int) string {
+	return fmt.Sprintf("hashes/%s/%d", appName, index)
+}
+
+// hashMarks tracks the indexes the hash index holds for z's app
+func (z *Zellular) hashMarks() watermarks {
+	return watermarks{store: z.hashIndex, prefix: "hashes/" + z.AppName + "/"}
+}
+
+// dropHash deletes the hash stored for index
+func (z *Zellular) dropHash(index int) error {
+	return z.hashIndex.Delete(hashIndexKey(z.AppName, index))
+}
+
+// checkReorg compares a batch against the hash stored for its index. On a
+// conflict the truth is resolved from the operators: if the quorum sides
+// with the new batch the stored hash is replaced, otherwise a *ReorgError
+// is returned. Either way a ReorgEvent is emitted.
+func (z *Zellular) checkReorg(ctx context.Context, batch Batch) error {
+	if z.hashIndex == nil {
+		return nil
+	}
+	key := hashIndexKey(z.AppName, batch.Index)
+	observed := hash(batch.Payload)
+	stored, err := z.hashIndex.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		if err := z.hashIndex.Put(key, []byte(observed)); err != nil {
+			return err
+		}
+		return z.hashMarks().mark(batch.Index)
+	}
+	if err != nil || string(stored) == observed {
+		return err
+	}
+
+	event := &ReorgEvent{
+		AppName:      z.AppName,
+		Index:        batch.Index,
+		StoredHash:   string(stored),
+		ObservedHash: observed,
+		Node:         batch.Node,
+		Time:         time.Now(),
+	}
+	resolved, err := z.resolveBatchHash(ctx, batch.Index)
+	if err != nil {
+		z.errors.record(err)
+	}
+	event.ResolvedHash = resolved
+	z.emit(event)
+
+	if resolved != observed {
+		return &ReorgError{Event: event}
+	}
+	return z.hashIndex.Put(key, []byte(observed))
+}
+
+// resolveBatchHash asks every operator for the batch at index and returns
+// the hash held by operators with at least the threshold share of stake
+func (z *Zellular) resolveBatchHash(ctx context.Context, index int) (string, error) {
+	stakes := make(map[string]float64)
+	observed := make(map[string]string)
+	var mu sync.Mutex
+	operators := z.operators()
+	err := fanOut(ctx, withSockets(operators), func(ctx context.Context, operator Operator) error {
+		page, err := z.requestFinalizedFrom(ctx, operator.Socket, index-1, 1)
+		if err != nil {
+			return err
+		}
+		if page == nil || len(page.Batches) == 0 {
+			return fmt.Errorf("zellular: batch %d is not finalized", index)
+		}
+		mu.Lock()
+		stakes[hash(page.Batches[0])] += operator.Stake
+		observed[operator.ID] = hash(page.Batches[0])
+		mu.Unlock()
+		return nil
+	})
+
+	total := 0.0
+	for _, operator := range operators {
+		total += operator.Stake
+	}
+	for batchHash, stake := range stakes {
+		if total > 0 && 100*stake/total >= z.ThresholdPercent {
+			z.reportDivergent(index, batchHash, observed)
+			return batchHash, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("zellular: operators disagree on batch %d", index)
+	}
+	return "", err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// ReadRepairEvent reports an operator found serving stale or divergent data
+// during a cross-node read
+type ReadRepairEvent struct {
+	AppName    string
+	OperatorID string
+	Socket     string
+	// Problem is "lagging" or "divergent"
+	Problem string
+	// ExpectedIndex and ObservedIndex are set for lagging operators
+	ExpectedIndex int
+	ObservedIndex int
+	// Index, ExpectedHash and ObservedHash are set for divergent operators
+	Index        int
+	ExpectedHash string
+	ObservedHash string
+	Time         time.Time
+}
+
+// EventKind implements Event
+func (e *ReadRepairEvent) EventKind() string {
+	return "read_repair"
+}
+
+// reportLagging emits an event for every operator whose last finalized
+// index is below the index reached by the threshold share of stake
+func (z *Zellular) reportLagging(indexes map[string]int) {
+	operators := z.operators()
+	ids := make([]string, 0, len(indexes))
+	total := 0.0
+	for id := range indexes {
+		ids = append(ids, id)
+	}
+	for _, operator := range operators {
+		total += operator.Stake
+	}
+	sort.Slice(ids, func(i, j int) bool { return indexes[ids[i]] > indexes[ids[j]] })
+
+	quorumIndex, stake := 0, 0.0
+	for _, id := range ids {
+		stake += operators[id].Stake
+		if total > 0 && 100*stake/total >= z.ThresholdPercent {
+			quorumIndex = indexes[id]
+			break
+		}
+	}
+	for _, id := range ids {
+		if indexes[id] < quorumIndex {
+			z.emit(&ReadRepairEvent{
+				AppName:       z.AppName,
+				OperatorID:    id,
+				Socket:        operators[id].Socket,
+				Problem:       "lagging",
+				ExpectedIndex: quorumIndex,
+				ObservedIndex: indexes[id],
+				Time:          time.Now(),
+			})
+		}
+	}
+}
+
+// reportDivergent emits an event for every operator that served a batch
+// hash other than the one the quorum agreed on
+func (z *Zellular) reportDivergent(index int, expected string, observed map[string]string) {
+	for id, batchHash := range observed {
+		if batchHash != expected {
+			z.emit(&ReadRepairEvent{
+				AppName:      z.AppName,
+				OperatorID:   id,
+				Socket:       z.operators()[id].Socket,
+				Problem:      "divergent",
+				Index:        index,
+				ExpectedHash: expected,
+				ObservedHash: batchHash,
+				Time:         time.Now(),
+			})
+		}
+	}
+}
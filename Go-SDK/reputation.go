@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// latencyWeight is the smoothing factor of the latency moving average
+const latencyWeight = 0.2
+
+// OperatorScore is what has been observed about one operator
+type OperatorScore struct {
+	Requests int           `json:"requests"`
+	Failures int           `json:"failures"`
+	BadData  int           `json:"bad_data"`
+	Latency  time.Duration `json:"latency"`
+	LastSeen time.Time     `json:"last_seen"`
+}
+
+// Availability is the share of requests the operator answered
+func (s OperatorScore) Availability() float64 {
+	if s.Requests == 0 {
+		return 1
+	}
+	return float64(s.Requests-s.Failures) / float64(s.Requests)
+}
+
+// Score rates the operator between 0 and 1. Serving data that fails
+// verification weighs far more than being slow or unavailable.
+func (s OperatorScore) Score() float64 {
+	score := s.Availability()
+	if s.Latency > 0 {
+		score *= 1 / (1 + s.Latency.Seconds())
+	}
+	for i := 0; i < s.BadData; i++ {
+		score *= 0.1
+	}
+	return score
+}
+
+// Reputation tracks operator scores across restarts
+type Reputation struct {
+	mu     sync.Mutex
+	path   string
+	scores map[string]*OperatorScore
+}
+
+// LoadReputation loads scores persisted at path, starting empty if the file does not exist
+func LoadReputation(path string) (*Reputation, error) {
+	r := &Reputation{path: path, scores: make(map[string]*OperatorScore)}
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.scores); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WithReputation records every node request and every batch failing
+// verification in r, and weighs operators by their score wherever the
+// client chooses a node: balanced reads, endpoint pool failover and
+// rotation away from a censoring node
+func WithReputation(r *Reputation) Option {
+	return func(z *Zellular) {
+		z.reputation = r
+	}
+}
+
+func (r *Reputation) score(operatorID string) *OperatorScore {
+	s, ok := r.scores[operatorID]
+	if !ok {
+		s = &OperatorScore{}
+		r.scores[operatorID] = s
+	}
+	return s
+}
+
+// ObserveRequest records the outcome of a request to an operator
+func (r *Reputation) ObserveRequest(operatorID string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.score(operatorID)
+	s.Requests++
+	if err != nil {
+		s.Failures++
+		return
+	}
+	s.LastSeen = time.Now()
+	if s.Latency == 0 {
+		s.Latency = latency
+	} else {
+		s.Latency = time.Duration((1-latencyWeight)*float64(s.Latency) + latencyWeight*float64(latency))
+	}
+}
+
+// ObserveBadData records that an operator served data that failed verification
+func (r *Reputation) ObserveBadData(operatorID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.score(operatorID).BadData++
+}
+
+// Scores returns a copy of all scores
+func (r *Reputation) Scores() map[string]OperatorScore {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scores := make(map[string]OperatorScore, len(r.scores))
+	for id, s := range r.scores {
+		scores[id] = *s
+	}
+	return scores
+}
+
+// Save persists the scores
+func (r *Reputation) Save() error {
+	r.mu.Lock()
+	data, err := json.Marshal(r.scores)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(r.path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(r.path+".tmp", r.path)
+}
+
+// Select picks an operator with a usable socket at random weighted by score,
+// so unknown operators still get explored while well behaved ones are preferred
+func (r *Reputation) Select(operators map[string]Operator) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(operators))
+	weights := make([]float64, 0, len(operators))
+	total := 0.0
+	for id := range withSockets(operators) {
+		weight := 1.0
+		if s, ok := r.scores[id]; ok {
+			weight = s.Score()
+		}
+		ids = append(ids, id)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	if total == 0 {
+		return ids[rand.Intn(len(ids))]
+	}
+
+	pick := rand.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return ids[i]
+		}
+		pick -= weight
+	}
+	return ids[len(ids)-1]
+}
+
+// operatorAt returns the ID of the operator whose socket is url
+func (z *Zellular) operatorAt(url string) string {
+	for id, operator := range z.operators() {
+		if operator.Socket == url {
+			return id
+		}
+	}
+	return ""
+}
+
+// ReportBadData lowers the reputation of the operator serving the current base URL
+func (z *Zellular) ReportBadData() {
+	z.observeBadData(z.base())
+}
+
+// observeBadData lowers the reputation of the operator at socket, which
+// served data that failed verification
+func (z *Zellular) observeBadData(socket string) {
+	if z.reputation == nil {
+		return
+	}
+	if id := z.operatorAt(socket); id != "" {
+		z.reputation.ObserveBadData(id)
+	}
+}
+
+// chooseNode picks one of sockets, weighted by reputation when one is
+// configured and otherwise the first. Sockets that are not an operator's
+// are only picked when none is.
+func (z *Zellular) chooseNode(sockets []string) string {
+	if len(sockets) == 0 {
+		return ""
+	}
+	if z.reputation == nil {
+		return sockets[0]
+	}
+	candidates := make(map[string]Operator, len(sockets))
+	for _, socket := range sockets {
+		if id := z.operatorAt(socket); id != "" {
+			candidates[id] = Operator{ID: id, Socket: socket}
+		}
+	}
+	if id := z.reputation.Select(candidates); id != "" {
+		return candidates[id].Socket
+	}
+	return sockets[0]
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStreamReportsBadData(t *testing.T) {
+	reputation, err := LoadReputation(filepath.Join(t.TempDir(), "reputation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`}, WithReputation(reputation))
+	sandbox.batches[0].payload = `["x"]`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := z.Stream(0).Next(ctx); err == nil {
+		t.Fatal("tampered batch was not rejected")
+	}
+	if bad := reputation.Scores()[sandboxOperator].BadData; bad != 1 {
+		t.Fatalf("bad data count = %d", bad)
+	}
+}
+
+func TestBalancedReadsFollowReputation(t *testing.T) {
+	reputation, err := LoadReputation(filepath.Join(t.TempDir(), "reputation.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		reputation.ObserveBadData("bad")
+	}
+	operators := map[string]Operator{
+		"good": {ID: "good", Socket: "http://good"},
+		"bad":  {ID: "bad", Socket: "http://bad"},
+	}
+	b := &readBalancer{inflight: make(map[string]int), failures: make(map[string]int), downUntil: make(map[string]time.Time)}
+	for i := 0; i < 50; i++ {
+		socket := b.pick(operators, "", reputation)
+		b.done(socket, nil)
+		if socket != "http://good" {
+			t.Fatalf("pick %d chose %s", i, socket)
+		}
+	}
+}
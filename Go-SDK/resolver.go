@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/crypto/sha3"
+)
+
+// ensRegistry is the ENS registry address, the same on mainnet and testnets
+const ensRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// ensSocketKey is the ENS text record holding an operator's socket URL
+const ensSocketKey = "url"
+
+// SocketResolver resolves operator sockets published as ENS names or
+// hostnames. DNS answers are cached for their TTL (bounded by MinTTL and
+// MaxTTL) and used for dialing, so every request does not hit the resolver.
+type SocketResolver struct {
+	// Eth resolves ENS names; without it ENS sockets are left unresolved
+	Eth    *EthClient
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]resolvedHost
+}
+
+// resolvedHost is a cached DNS answer
+type resolvedHost struct {
+	addrs   []net.IP
+	expires time.Time
+}
+
+// NewSocketResolver creates a resolver, eth may be nil when ENS is not needed
+func NewSocketResolver(eth *EthClient) *SocketResolver {
+	return &SocketResolver{
+		Eth:    eth,
+		MinTTL: 5 * time.Second,
+		MaxTTL: time.Hour,
+		cache:  make(map[string]resolvedHost),
+	}
+}
+
+// WithSocketResolver resolves ENS sockets when operators are loaded and
+// dials nodes through r's DNS cache
+func WithSocketResolver(r *SocketResolver) Option {
+	return func(z *Zellular) {
+		z.resolver = r
+		client := *z.transport.client
+		base, ok := client.Transport.(*http.Transport)
+		if client.Transport == nil {
+			base, ok = http.DefaultTransport.(*http.Transport)
+		}
+		if !ok {
+			return
+		}
+		t := base.Clone()
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil || net.ParseIP(host) != nil {
+				return dialer.DialContext(ctx, network, address)
+			}
+			addrs, err := r.Lookup(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				var conn net.Conn
+				if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port)); err == nil {
+					return conn, nil
+				}
+			}
+			return nil, err
+		}
+		client.Transport = t
+		z.transport.client = &client
+	}
+}
+
+// Lookup returns the addresses of host, from cache while their TTL lasts
+func (r *SocketResolver) Lookup(ctx context.Context, host string) ([]net.IP, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+
+	addrs, ttl, err := lookupWithTTL(ctx, host)
+	if err != nil {
+		if ok {
+			// serve stale addresses rather than failing while DNS is down
+			return cached.addrs, nil
+		}
+		return nil, err
+	}
+	if ttl < r.MinTTL {
+		ttl = r.MinTTL
+	}
+	if r.MaxTTL > 0 && ttl > r.MaxTTL {
+		ttl = r.MaxTTL
+	}
+
+	r.mu.Lock()
+	r.cache[host] = resolvedHost{addrs: addrs, expires: time.Now().Add(ttl)}
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// Cached returns the cached addresses of host without resolving it
+func (r *SocketResolver) Cached(host string) []net.IP {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cache[host].addrs
+}
+
+// lookupWithTTL queries the system's nameservers for A and AAAA records and
+// returns the smallest TTL, falling back to the Go resolver without TTLs
+func lookupWithTTL(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(config.Servers) == 0 {
+		addrs, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		return addrs, 0, err
+	}
+
+	client := &dns.Client{Timeout: 5 * time.Second}
+	var addrs []net.IP
+	var ttl uint32
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), qtype)
+		for _, server := range config.Servers {
+			answer, _, err := client.ExchangeContext(ctx, msg, net.JoinHostPort(server, config.Port))
+			if err != nil || answer.Rcode != dns.RcodeSuccess {
+				continue
+			}
+			for _, rr := range answer.Answer {
+				switch record := rr.(type) {
+				case *dns.A:
+					addrs = append(addrs, record.A)
+				case *dns.AAAA:
+					addrs = append(addrs, record.AAAA)
+				default:
+					continue
+				}
+				if ttl == 0 || rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+			break
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, 0, fmt.Errorf("zellular: no addresses found for %s", host)
+	}
+	return addrs, time.Duration(ttl) * time.Second, nil
+}
+
+// ResolveENS returns the socket URL published in the "url" text record of an ENS name
+func (r *SocketResolver) ResolveENS(ctx context.Context, name string) (string, error) {
+	if r.Eth == nil {
+		return "", errors.New("zellular: no Ethereum client configured for ENS")
+	}
+	node := namehash(name)
+
+	// resolver(bytes32)
+	data, err := r.Eth.Call(ctx, ensRegistry, append([]byte{0x01, 0x78, 0xb8, 0xbf}, node...))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 32 {
+		return "", fmt.Errorf("zellular: ENS name %s has no resolver", name)
+	}
+	resolver := fmt.Sprintf("0x%x", data[12:32])
+	if strings.Trim(resolver[2:], "0") == "" {
+		return "", fmt.Errorf("zellular: ENS name %s has no resolver", name)
+	}
+
+	// text(bytes32,string)
+	call := []byte{0x59, 0xd1, 0xd4, 0x3c}
+	call = append(call, node...)
+	call = append(call, abiWord([]byte{0x40})...)
+	call = append(call, abiWord([]byte{byte(len(ensSocketKey))})...)
+	call = append(call, []byte(ensSocketKey)...)
+	call = append(call, make([]byte, 32-len(ensSocketKey))...)
+	data, err = r.Eth.Call(ctx, resolver, call)
+	if err != nil {
+		return "", err
+	}
+	return abiString(data)
+}
+
+// namehash computes the ENS node of a lowercase name
+func namehash(name string) []byte {
+	node := make([]byte, 32)
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := sha3.NewLegacyKeccak256()
+		label.Write([]byte(labels[i]))
+		h := sha3.NewLegacyKeccak256()
+		h.Write(node)
+		h.Write(label.Sum(nil))
+		node = h.Sum(nil)
+	}
+	return node
+}
+
+// resolveSockets replaces ENS sockets by the URLs they publish and warms the
+// DNS cache for hostname sockets
+func (r *SocketResolver) resolveSockets(ctx context.Context, operators map[string]Operator) {
+	for id, operator := range operators {
+		if operator.Socket == "" {
+			continue
+		}
+		host := socketHost(operator.Socket)
+		if strings.HasSuffix(host, ".eth") {
+			socket, err := r.ResolveENS(ctx, host)
+			if err == nil {
+				socket, err = NormalizeSocket(socket)
+			}
+			if err != nil {
+				log.Printf("zellular: resolving ENS socket of operator %s: %v", id, err)
+				operator.Socket = ""
+			} else {
+				operator.Socket = socket
+			}
+			operators[id] = operator
+			host = socketHost(operator.Socket)
+		}
+		if host != "" && net.ParseIP(host) == nil {
+			if _, err := r.Lookup(ctx, host); err != nil {
+				log.Printf("zellular: resolving socket of operator %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// socketHost extracts the host of a normalized socket
+func socketHost(socket string) string {
+	host := socket[strings.Index(socket, "://")+3:]
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// ResubmitEvent reports a batch being submitted again through another
+// operator after it was not finalized in time
+type ResubmitEvent struct {
+	BatchHash string
+	Node      string
+	Attempt   int
+}
+
+// EventKind implements Event
+func (e *ResubmitEvent) EventKind() string {
+	return "resubmit"
+}
+
+// SendUntilFinalized submits txs to the base node and, whenever the batch
+// is not finalized within delay, submits it again through the next
+// operator until it shows up in the finalized stream, which is returned.
+// Every copy carries the batch hash as its idempotency key unless the
+// call options set one. Nodes that do not deduplicate by that key may
+// sequence a slow original and its resubmission both; the first finalized
+// copy is returned.
+func (z *Zellular) SendUntilFinalized(ctx context.Context, txs interface{}, delay time.Duration) (Batch, error) {
+	submission, err := z.PrepareSubmission(ctx, txs)
+	if err != nil {
+		return Batch{}, err
+	}
+	batchHash := hash(string(submission.Payload))
+	opts := callOptions(ctx)
+	if opts.IdempotencyKey == "" {
+		opts.IdempotencyKey = batchHash
+	}
+
+	last, err := z.fetchLastFinalized(ctx, z.base())
+	if err != nil {
+		return Batch{}, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan Batch, 1)
+	errs := make(chan error, 1)
+	go func() {
+		err := z.watchFinalized(ctx, last.Index, time.Second, func(batch Batch) bool {
+			if hash(batch.Payload) != batchHash {
+				return false
+			}
+			found <- batch
+			return true
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	nodes := z.resubmitNodes(submission.Node)
+	accepted := false
+	var sendErr error
+	for attempt := 0; ; {
+		if attempt < len(nodes) {
+			opts.Node = nodes[attempt]
+			if attempt > 0 {
+				z.emit(&ResubmitEvent{BatchHash: batchHash, Node: opts.Node, Attempt: attempt})
+			}
+			attempt++
+			if sendErr = z.Send(WithCallOptions(ctx, opts), txs); sendErr == nil {
+				accepted = true
+			} else if attempt < len(nodes) {
+				continue
+			} else if !accepted {
+				return Batch{}, sendErr
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case batch := <-found:
+			timer.Stop()
+			return batch, nil
+		case err := <-errs:
+			timer.Stop()
+			return Batch{}, err
+		case <-ctx.Done():
+			timer.Stop()
+			return Batch{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// resubmitNodes lists first, then the other operators' sockets in a stable order
+func (z *Zellular) resubmitNodes(first string) []string {
+	var others []string
+	for _, operator := range withSockets(z.operators()) {
+		if operator.Socket != first {
+			others = append(others, operator.Socket)
+		}
+	}
+	sort.Strings(others)
+	return append([]string{first}, others...)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy bounds how much history a log-structured store such as
+// the WAL keeps. Zero fields are not enforced; the newest entries are
+// always the ones kept.
+type RetentionPolicy struct {
+	MaxAge   time.Duration
+	MaxCount int
+	MaxBytes int64
+}
+
+// Compactor is a store that can drop history according to a policy: the
+// WAL, and the reorg hash index and receipts store of a client, which
+// Compactors returns. The checkpoint store needs none, as it holds two
+// slots per app.
+type Compactor interface {
+	Compact(policy RetentionPolicy) error
+}
+
+// Compactors returns the stores of z that grow with every batch: its WAL,
+// hash index and receipts store, as far as they are enabled
+func (z *Zellular) Compactors() []Compactor {
+	var stores []Compactor
+	if z.wal != nil {
+		stores = append(stores, z.wal)
+	}
+	if z.hashIndex != nil {
+		stores = append(stores, indexCompactor{marks: z.hashMarks(), drop: z.dropHash})
+	}
+	if z.receipts != nil {
+		stores = append(stores, indexCompactor{marks: z.receiptMarks(), drop: z.dropReceipt})
+	}
+	return stores
+}
+
+// RunRetention compacts the given stores, such as those of Compactors,
+// every interval until ctx is done
+func RunRetention(ctx context.Context, interval time.Duration, policy RetentionPolicy, stores ...Compactor) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, store := range stores {
+				if err := store.Compact(policy); err != nil {
+					log.Printf("zellular: compaction failed: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// retainFrom returns how many of the oldest entries must be dropped so that
+// the remaining ones satisfy policy. times and sizes describe the entries
+// oldest first.
+func retainFrom(policy RetentionPolicy, times []time.Time, sizes []int64, now time.Time) int {
+	drop := 0
+	if policy.MaxCount > 0 && len(sizes) > policy.MaxCount {
+		drop = len(sizes) - policy.MaxCount
+	}
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge)
+		for drop < len(times) && times[drop].Before(cutoff) {
+			drop++
+		}
+	}
+	if policy.MaxBytes > 0 {
+		var total int64
+		for _, size := range sizes[drop:] {
+			total += size
+		}
+		for drop < len(sizes) && total > policy.MaxBytes {
+			total -= sizes[drop]
+			drop++
+		}
+	}
+	return drop
+}
+
+// watermarkMu serializes watermark updates within the process
+var watermarkMu sync.Mutex
+
+// watermarks are the lowest and highest numbers of the entries kept under
+// prefix in a KVStore. The store cannot list its keys, so the watermarks
+// are what lets retention find the entries to delete.
+type watermarks struct {
+	store  KVStore
+	prefix string
+}
+
+// get returns the watermark called name, false if none was set
+func (w watermarks) get(name string) (int, bool, error) {
+	data, err := w.store.Get(w.prefix + name)
+	if errors.Is(err, ErrNotFound) || (err == nil && data == nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	n, err := strconv.Atoi(string(data))
+	return n, err == nil, err
+}
+
+func (w watermarks) set(name string, n int) error {
+	return w.store.Put(w.prefix+name, []byte(strconv.Itoa(n)))
+}
+
+// mark widens the watermarks to cover entry n
+func (w watermarks) mark(n int) error {
+	watermarkMu.Lock()
+	defer watermarkMu.Unlock()
+	return w.widen(n)
+}
+
+func (w watermarks) widen(n int) error {
+	if low, ok, err := w.get("low"); err != nil {
+		return err
+	} else if !ok || n < low {
+		if err := w.set("low", n); err != nil {
+			return err
+		}
+	}
+	if high, ok, err := w.get("high"); err != nil {
+		return err
+	} else if !ok || n > high {
+		return w.set("high", n)
+	}
+	return nil
+}
+
+// append stores value as the entry after the highest one and returns its number
+func (w watermarks) append(value []byte) (int, error) {
+	watermarkMu.Lock()
+	defer watermarkMu.Unlock()
+	high, _, err := w.get("high")
+	if err != nil {
+		return 0, err
+	}
+	if err := w.store.Put(w.prefix+strconv.Itoa(high+1), value); err != nil {
+		return 0, err
+	}
+	return high + 1, w.widen(high + 1)
+}
+
+// trim calls drop for the entries below the newest keep and raises the low watermark past them
+func (w watermarks) trim(keep int, drop func(n int) error) error {
+	watermarkMu.Lock()
+	defer watermarkMu.Unlock()
+	low, ok, err := w.get("low")
+	if err != nil || !ok {
+		return err
+	}
+	high, _, err := w.get("high")
+	if err != nil {
+		return err
+	}
+	cutoff := high - keep + 1
+	for n := low; n < cutoff; n++ {
+		if err := drop(n); err != nil {
+			return err
+		}
+		if err := w.set("low", n+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexCompactor drops the oldest entries of a store numbered by
+// watermarks. Entries carry no time or size, so only MaxCount applies.
+type indexCompactor struct {
+	marks watermarks
+	drop  func(n int) error
+}
+
+// Compact keeps the newest policy.MaxCount entries
+func (c indexCompactor) Compact(policy RetentionPolicy) error {
+	if policy.MaxCount <= 0 {
+		return nil
+	}
+	return c.marks.trim(policy.MaxCount, c.drop)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetentionTrimsHashIndex(t *testing.T) {
+	store := NewMemoryKVStore()
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`, `["d"]`, `["e"]`}
+	z, _ := sandboxWith(t, payloads, WithHashIndex(store))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := z.Stream(0)
+	for range payloads {
+		if _, err := stream.Next(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range z.Compactors() {
+		if err := c.Compact(RetentionPolicy{MaxCount: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for index := 1; index <= len(payloads); index++ {
+		_, err := store.Get(hashIndexKey("app", index))
+		if kept := err == nil; kept != (index > 3) {
+			t.Fatalf("hash of batch %d kept = %v", index, kept)
+		}
+	}
+}
+
+func TestRetentionTrimsReceipts(t *testing.T) {
+	store := NewMemoryKVStore()
+	z := newZellular("app", "http://localhost:6001", 67, WithReceipts(store))
+	operator := registryOperator("0x0000000000000000000000000000000000000001", testSecret)
+	if err := z.setOperators(map[string]Operator{operator.ID: operator}); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`}
+	for i, payload := range payloads {
+		r := signedReceipt(z, operator.ID, testSecret, payload, i+1, i+5)
+		if err := z.keepReceipt(receiptResponse(r), []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range z.Compactors() {
+		if err := c.Compact(RetentionPolicy{MaxCount: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, payload := range payloads {
+		_, err := z.Receipt(z.batchHash(payload))
+		if kept := err == nil; kept != (i == len(payloads)-1) {
+			t.Fatalf("receipt of %s kept = %v", payload, kept)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimits publishes the rate limits observed per host as "zellular_rate_limits"
+var rateLimits = expvar.NewMap("zellular_rate_limits")
+
+// ErrRetryBudgetExhausted is returned when another attempt could not finish
+// before the caller's context deadline
+var ErrRetryBudgetExhausted = errors.New("zellular: retry budget exhausted")
+
+// Backoff schedules the delays between attempts of a request
+type Backoff struct {
+	MaxAttempts int
+	Initial     time.Duration
+	Max         time.Duration
+}
+
+// DefaultBackoff retries throttled requests twice
+var DefaultBackoff = Backoff{MaxAttempts: 3, Initial: 500 * time.Millisecond, Max: 30 * time.Second}
+
+// WithBackoff replaces the retry schedule for throttled requests
+func WithBackoff(b Backoff) Option {
+	return func(z *Zellular) {
+		z.transport.backoff = b
+	}
+}
+
+// delay returns the wait before the given retry (1 for the first retry),
+// with full jitter, unless the server asked for a specific delay
+func (b Backoff) delay(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if b.Max > 0 && retryAfter > b.Max {
+			return b.Max
+		}
+		return retryAfter
+	}
+	d := b.Initial << uint(retry-1)
+	if d <= 0 || (b.Max > 0 && d > b.Max) {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// latencyEstimate tracks a moving average of request durations used to
+// predict how long the next attempt will take
+type latencyEstimate struct {
+	mu      sync.Mutex
+	average time.Duration
+}
+
+func (l *latencyEstimate) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.average == 0 {
+		l.average = d
+		return
+	}
+	l.average = time.Duration((1-latencyWeight)*float64(l.average) + latencyWeight*float64(d))
+}
+
+func (l *latencyEstimate) get() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.average
+}
+
+// checkBudget fails fast when waiting delay and making one more attempt of
+// the expected duration would overrun the deadline of ctx
+func checkBudget(ctx context.Context, delay, expected time.Duration, attempt int) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	remaining := time.Until(deadline)
+	if needed := delay + expected; needed > remaining {
+		return fmt.Errorf("%w after %d attempts: next attempt needs %s, %s left", ErrRetryBudgetExhausted, attempt, needed, remaining)
+	}
+	return nil
+}
+
+// throttled reports whether a response asks the client to slow down
+func throttled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// observeRateLimit records the standard and X- prefixed rate-limit headers of a response
+func observeRateLimit(host string, resp *http.Response) {
+	for _, name := range []string{"Limit", "Remaining", "Reset"} {
+		value := resp.Header.Get("RateLimit-" + name)
+		if value == "" {
+			value = resp.Header.Get("X-RateLimit-" + name)
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			v := new(expvar.Int)
+			v.Set(n)
+			rateLimits.Set(host+" "+strings.ToLower(name), v)
+		}
+	}
+	if throttled(resp) {
+		rateLimits.Add(host+" throttled", 1)
+	}
+	observeQuota(host, resp, time.Now())
+}
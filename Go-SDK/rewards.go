@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RewardClaim is a rewards claim made by or for an earner
+type RewardClaim struct {
+	Earner      string
+	Recipient   string
+	Token       string
+	Amount      float64
+	BlockNumber int64
+	Time        time.Time
+}
+
+const rewardClaimsQuery = `query($earner: String!, $from: BigInt!, $to: BigInt!, $after: String!) {
+  rewardsClaimeds(where: {earner: $earner, blockTimestamp_gte: $from, blockTimestamp_lte: $to, id_gt: $after},
+    orderBy: id, orderDirection: asc, first: 1000) {
+    id earner recipient token claimedAmount blockNumber blockTimestamp
+  }
+}`
+
+// rewardClaimEntity is a rewardsClaimeds entity of the registry subgraph
+type rewardClaimEntity struct {
+	ID             string `json:"id"`
+	Earner         string `json:"earner"`
+	Recipient      string `json:"recipient"`
+	Token          string `json:"token"`
+	ClaimedAmount  string `json:"claimedAmount"`
+	BlockNumber    string `json:"blockNumber"`
+	BlockTimestamp string `json:"blockTimestamp"`
+}
+
+func (e rewardClaimEntity) claim() (RewardClaim, error) {
+	amount, err := weiToEther(e.ClaimedAmount)
+	if err != nil {
+		return RewardClaim{}, err
+	}
+	block, err := strconv.ParseInt(e.BlockNumber, 10, 64)
+	if err != nil {
+		return RewardClaim{}, err
+	}
+	timestamp, err := strconv.ParseInt(e.BlockTimestamp, 10, 64)
+	if err != nil {
+		return RewardClaim{}, err
+	}
+	return RewardClaim{
+		Earner:      e.Earner,
+		Recipient:   e.Recipient,
+		Token:       e.Token,
+		Amount:      amount,
+		BlockNumber: block,
+		Time:        time.Unix(timestamp, 0),
+	}, nil
+}
+
+// RewardClaims returns the rewards claimed for an operator address between from and to, oldest first
+func (z *Zellular) RewardClaims(ctx context.Context, operator string, from, to time.Time) ([]RewardClaim, error) {
+	earner, err := NormalizeAddress(operator)
+	if err != nil {
+		return nil, err
+	}
+	var claims []RewardClaim
+	for after := ""; ; {
+		var data struct {
+			RewardsClaimeds []rewardClaimEntity `json:"rewardsClaimeds"`
+		}
+		err := querySubgraph(ctx, z.transport, rewardClaimsQuery, map[string]interface{}{
+			"earner": earner,
+			"from":   strconv.FormatInt(from.Unix(), 10),
+			"to":     strconv.FormatInt(to.Unix(), 10),
+			"after":  after,
+		}, &data)
+		if err != nil {
+			return claims, err
+		}
+		for _, entity := range data.RewardsClaimeds {
+			claim, err := entity.claim()
+			if err != nil {
+				return claims, err
+			}
+			claims = append(claims, claim)
+		}
+		if len(data.RewardsClaimeds) < subgraphPageSize {
+			break
+		}
+		after = data.RewardsClaimeds[len(data.RewardsClaimeds)-1].ID
+	}
+	sort.SliceStable(claims, func(i, j int) bool {
+		return claims[i].BlockNumber < claims[j].BlockNumber
+	})
+	return claims, nil
+}
+
+// Earnings sums an operator's claimed rewards per token between from and to
+func (z *Zellular) Earnings(ctx context.Context, operator string, from, to time.Time) (map[string]float64, error) {
+	claims, err := z.RewardClaims(ctx, operator, from, to)
+	if err != nil {
+		return nil, err
+	}
+	earnings := make(map[string]float64)
+	for _, claim := range claims {
+		earnings[claim.Token] += claim.Amount
+	}
+	return earnings, nil
+}
+
+// CumulativeClaimed reads from the rewards coordinator contract the total
+// amount of token an earner has claimed, in the token's base units
+func CumulativeClaimed(ctx context.Context, eth *EthClient, coordinator, earner, token string) (*big.Int, error) {
+	call := abiSelector("cumulativeClaimed(address,address)")
+	for _, address := range []string{earner, token} {
+		word, err := abiAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		call = append(call, word...)
+	}
+	data, err := eth.Call(ctx, coordinator, call)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 32 {
+		return nil, fmt.Errorf("zellular: short cumulativeClaimed result")
+	}
+	return new(big.Int).SetBytes(data[:32]), nil
+}
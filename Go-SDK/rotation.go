@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// KeyRotationEvent reports an operator whose G2 public key changed
+type KeyRotationEvent struct {
+	AppName  string
+	Operator string
+	// EffectiveIndex is the first batch index signed with the new key
+	EffectiveIndex int
+	Time           time.Time
+}
+
+// EventKind implements Event
+func (e *KeyRotationEvent) EventKind() string {
+	return "key_rotation"
+}
+
+// keyEpoch is a public key valid from batch index from onwards
+type keyEpoch struct {
+	from int
+	key  bn254.G2Affine
+}
+
+// keyHistory keeps the past keys of operators that rotated keys, so that
+// proofs of batches signed before a rotation still verify
+type keyHistory struct {
+	mu     sync.RWMutex
+	epochs map[string][]keyEpoch
+}
+
+// record adds a key taking effect at index from, remembering previous as
+// the key before it if the operator has no history yet. It reports false
+// if key is already the operator's latest key.
+func (h *keyHistory) record(operator string, from int, previous, key bn254.G2Affine) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.epochs == nil {
+		h.epochs = make(map[string][]keyEpoch)
+	}
+	epochs := h.epochs[operator]
+	if len(epochs) == 0 {
+		epochs = append(epochs, keyEpoch{from: 0, key: previous})
+	} else if latest := epochs[len(epochs)-1].key; latest.Equal(&key) {
+		return false
+	}
+	epochs = append(epochs, keyEpoch{from: from, key: key})
+	sort.SliceStable(epochs, func(i, j int) bool { return epochs[i].from < epochs[j].from })
+	h.epochs[operator] = epochs
+	return true
+}
+
+// differsAt reports whether the key of any operator valid at index is not
+// its key in operators
+func (h *keyHistory) differsAt(index int, operators map[string]Operator) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for id := range h.epochs {
+		operator, ok := operators[id]
+		if !ok {
+			continue
+		}
+		if key, ok := h.at(id, index); ok && !key.Equal(&operator.PublicKeyG2) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAt returns the key of operator valid at index, false without history
+func (h *keyHistory) keyAt(operator string, index int) (bn254.G2Affine, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.at(operator, index)
+}
+
+// at is keyAt with h.mu held
+func (h *keyHistory) at(operator string, index int) (bn254.G2Affine, bool) {
+	epochs := h.epochs[operator]
+	i := sort.Search(len(epochs), func(i int) bool { return epochs[i].from > index })
+	if i == 0 {
+		return bn254.G2Affine{}, false
+	}
+	return epochs[i-1].key, true
+}
+
+// RecordKeyChange registers that operator signs with the G2 key given by
+// its registry coordinates from batch index effectiveIndex onwards, e.g.
+// from the registry's key update events. Proofs are then checked against
+// the keys valid at their index, whatever the current operator set holds.
+// Rotations noticed when the operator set is reloaded are recorded
+// automatically, effective after the latest finalized index seen then.
+func (z *Zellular) RecordKeyChange(operator string, effectiveIndex int, pubkeyG2X, pubkeyG2Y []string) error {
+	current, ok := z.LookupOperator(operator)
+	if !ok {
+		return fmt.Errorf("zellular: unknown operator %s", operator)
+	}
+	updated := Operator{ID: current.ID, PubkeyG2_X: pubkeyG2X, PubkeyG2_Y: pubkeyG2Y}
+	if err := updated.decodePublicKey(); err != nil {
+		return err
+	}
+	if z.keys.record(current.ID, effectiveIndex, current.PublicKeyG2, updated.PublicKeyG2) {
+		z.emit(&KeyRotationEvent{AppName: z.AppName, Operator: current.ID, EffectiveIndex: effectiveIndex, Time: time.Now()})
+	}
+	return nil
+}
+
+// trackRotations records the operators whose keys differ between the
+// current set and next
+func (z *Zellular) trackRotations(next map[string]Operator) {
+	effective := z.progress.latest() + 1
+	current := z.operators()
+	for id, operator := range next {
+		previous, ok := current[id]
+		if !ok || previous.PublicKeyG2.Equal(&operator.PublicKeyG2) {
+			continue
+		}
+		if z.keys.record(id, effective, previous.PublicKeyG2, operator.PublicKeyG2) {
+			z.emit(&KeyRotationEvent{AppName: z.AppName, Operator: id, EffectiveIndex: effective, Time: time.Now()})
+		}
+	}
+}
+
+// checkSignatureAt is a signatureCheck using the keys valid at batch index
+func (z *Zellular) checkSignatureAt(index int) signatureCheck {
+	return func(nonsigners []string, message []byte, signature *bn254.G1Affine) (bool, error) {
+		publicKey := z.signersPublicKeyAt(index, nonsigners)
+		h := z.messagePoint(message)
+		return verifyBLS(&publicKey, &h, signature)
+	}
+}
+
+// signersPublicKeyAt aggregates the keys valid at batch index of the
+// operators not in nonsigners
+func (z *Zellular) signersPublicKeyAt(index int, nonsigners []string) bn254.G2Affine {
+	operators := z.operators()
+	excluded := make(map[string]bool, len(nonsigners))
+	for _, nonsigner := range nonsigners {
+		if operator, ok := lookupOperator(operators, nonsigner); ok {
+			excluded[operator.ID] = true
+		}
+	}
+	var sum bn254.G2Jac
+	for id, operator := range operators {
+		if excluded[id] {
+			continue
+		}
+		key, ok := z.keys.keyAt(id, index)
+		if !ok {
+			key = operator.PublicKeyG2
+		}
+		sum.AddMixed(&key)
+	}
+	var publicKey bn254.G2Affine
+	publicKey.FromJacobian(&sum)
+	return publicKey
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+const (
+	// sandboxURL is the base URL of the sandbox node
+	sandboxURL = "http://sandbox.zellular.invalid"
+	// sandboxOperator is the ID of the sandbox's single operator
+	sandboxOperator = "0x0000000000000000000000000000000000000001"
+	// sandboxPageSize caps the batches in one finalized page
+	sandboxPageSize = 100
+)
+
+// Sandbox is an in-memory node with a single operator that finalizes every
+// submitted batch after Delay and signs it with a key generated on start.
+// It serves the node HTTP API to the client NewSandbox returns, so
+// application code runs against the real client before a devnet exists.
+type Sandbox struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	batches []sandboxBatch
+	secret  *big.Int
+	z       *Zellular
+}
+
+// sandboxBatch is a submitted batch and when it was submitted
+type sandboxBatch struct {
+	payload      string
+	submitted    time.Time
+	chainingHash string
+}
+
+// NewSandbox returns a client of app talking to a new Sandbox that
+// finalizes batches delay after they are sent. Options apply as for
+// NewZellular; those replacing the HTTP client or operator source must not
+// be used.
+func NewSandbox(appName string, delay time.Duration, opts ...Option) (*Zellular, *Sandbox, error) {
+	s := &Sandbox{delay: delay}
+	opts = append(opts, WithHTTPClient(&http.Client{Transport: s}))
+	z := newZellular(appName, sandboxURL, 67, opts...)
+	s.z = z
+
+	// a 248 bit scalar is below the group order and never zero in practice
+	secret := make([]byte, 31)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, nil, err
+	}
+	s.secret = new(big.Int).SetBytes(secret)
+
+	_, _, _, g2 := bn254.Generators()
+	var publicKey bn254.G2Affine
+	publicKey.ScalarMultiplication(&g2, s.secret)
+	operator := Operator{
+		ID:          sandboxOperator,
+		PubkeyG2_X:  []string{publicKey.X.A1.String(), publicKey.X.A0.String()},
+		PubkeyG2_Y:  []string{publicKey.Y.A1.String(), publicKey.Y.A0.String()},
+		Socket:      sandboxURL,
+		Stake:       1,
+		PublicKeyG2: publicKey,
+	}
+	if err := z.setOperators(map[string]Operator{operator.ID: operator}); err != nil {
+		return nil, nil, err
+	}
+	return z, s, nil
+}
+
+// RoundTrip implements http.RoundTripper by serving the node API
+func (s *Sandbox) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		defer req.Body.Close()
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := "/node/" + s.z.AppName + "/batches"
+	status, data := http.StatusNotFound, interface{}(nil)
+	switch path := req.URL.Path; {
+	case path == prefix && req.Method == http.MethodPut:
+		status = s.submit(body)
+	case path == prefix+"/finalized" && req.Method == http.MethodGet:
+		after, err := strconv.Atoi(req.URL.Query().Get("after"))
+		if err != nil {
+			status = http.StatusBadRequest
+			break
+		}
+		status, data = http.StatusOK, s.page(after)
+	case path == prefix+"/finalized/last" && req.Method == http.MethodGet:
+		status = http.StatusOK
+		if marker := s.marker(); marker != nil {
+			data = marker
+		}
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(encoded)),
+		ContentLength: int64(len(encoded)),
+		Request:       req,
+	}, nil
+}
+
+// submit appends a batch, which must be a JSON array
+func (s *Sandbox) submit(body []byte) int {
+	var txs []json.RawMessage
+	if err := json.Unmarshal(body, &txs); err != nil {
+		return http.StatusBadRequest
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := ""
+	if len(s.batches) > 0 {
+		previous = s.batches[len(s.batches)-1].chainingHash
+	}
+	payload := string(body)
+	s.batches = append(s.batches, sandboxBatch{
+		payload:      payload,
+		submitted:    time.Now(),
+		chainingHash: s.z.chain(previous, payload),
+	})
+	return http.StatusOK
+}
+
+// finalizedCount returns how many batches are finalized, with s.mu held
+func (s *Sandbox) finalizedCount() int {
+	cutoff := time.Now().Add(-s.delay)
+	n := 0
+	for n < len(s.batches) && !s.batches[n].submitted.After(cutoff) {
+		n++
+	}
+	return n
+}
+
+// page returns the finalized batches following after, nil if there are none
+func (s *Sandbox) page(after int) *finalizedPage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finalized := s.finalizedCount()
+	if after < 0 || after >= finalized {
+		return nil
+	}
+	end := finalized
+	if end-after > sandboxPageSize {
+		end = after + sandboxPageSize
+	}
+	page := &finalizedPage{Finalized: s.markerAt(finalized), FirstChainingHash: s.batches[after].chainingHash, Network: s.z.networkID}
+	for _, batch := range s.batches[after:end] {
+		page.Batches = append(page.Batches, batch.payload)
+	}
+	return page
+}
+
+// marker returns the marker of the latest finalized batch, nil before the first
+func (s *Sandbox) marker() *FinalizedRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if finalized := s.finalizedCount(); finalized > 0 {
+		return s.markerAt(finalized)
+	}
+	return nil
+}
+
+// markerAt signs the finalization of batch index, with s.mu held
+func (s *Sandbox) markerAt(index int) *FinalizedRecord {
+	batch := s.batches[index-1]
+	proof := FinalityProof{
+		AppName:      s.z.AppName,
+		Index:        index,
+		Hash:         s.z.batchHash(batch.payload),
+		ChainingHash: batch.chainingHash,
+		Network:      s.z.networkID,
+	}
+	h := s.z.messagePoint([]byte(hash(proof.Message())))
+	var signature bn254.G1Affine
+	signature.ScalarMultiplication(&h, s.secret)
+	compressed := signature.Bytes()
+	return &FinalizedRecord{
+		Index:                 index,
+		Hash:                  proof.Hash,
+		ChainingHash:          proof.ChainingHash,
+		Timestamp:             batch.submitted.Add(s.delay).Unix(),
+		Network:               proof.Network,
+		FinalizationSignature: hex.EncodeToString(compressed[:]),
+		Nonsigners:            []string{},
+	}
+}
+
+// Pending returns the number of submitted batches not finalized yet
+func (s *Sandbox) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches) - s.finalizedCount()
+}
@@ -0,0 +1,31 @@
+package main
+
+// SignatureScheme verifies finalization signatures for networks that do not
+// sign with aggregated BLS. The quorum policy is applied before Verify is
+// called, whatever the scheme.
+type SignatureScheme interface {
+	// Name identifies the scheme, e.g. "ecdsa"
+	Name() string
+	// Verify checks that signature is a valid signature of digest by every
+	// operator in signers, returning an error describing why it is not
+	Verify(signers []Operator, digest []byte, signature string) error
+}
+
+// WithSignatureScheme verifies signatures with scheme instead of BLS.
+// Operators then need no G2 public key, and the BLS specific
+// PreparedVerifier and VerifyMany are not available.
+func WithSignatureScheme(scheme SignatureScheme) Option {
+	return func(z *Zellular) {
+		z.scheme = scheme
+	}
+}
+
+// signerOperators returns every operator except the nonsigners
+func (z *Zellular) signerOperators(nonsigners []string) []Operator {
+	ids := z.quorumInput("", nonsigners).Signers
+	signers := make([]Operator, len(ids))
+	for i, id := range ids {
+		signers[i] = z.operators()[id]
+	}
+	return signers
+}
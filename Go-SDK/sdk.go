@@ -2,17 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"math/rand"
-	"net/http"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/cespare/xxhash"
-	"github.com/kilic/bls12-381" // Replace with the correct BLS library
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"golang.org/x/sync/singleflight"
 )
 
 // Operator struct holds operator data
@@ -25,7 +27,39 @@ type Operator struct {
 	PubkeyG2_Y  []string
 	Socket      string
 	Stake       float64
-	PublicKeyG2 bls12-381.G2Affine
+	PublicKeyG2 bn254.G2Affine
+}
+
+// UnmarshalJSON decodes a registry entry. The subgraph sends stakes as
+// decimal strings of wei, which are converted to ether; a numeric stake is
+// taken to be in ether already.
+func (o *Operator) UnmarshalJSON(data []byte) error {
+	type plain Operator
+	var entry struct {
+		plain
+		Stake json.RawMessage
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*o = Operator(entry.plain)
+	o.Stake = 0
+	switch {
+	case len(entry.Stake) == 0 || string(entry.Stake) == "null":
+	case entry.Stake[0] == '"':
+		var wei string
+		if err := json.Unmarshal(entry.Stake, &wei); err != nil {
+			return err
+		}
+		stake, err := weiToEther(wei)
+		if err != nil {
+			return err
+		}
+		o.Stake = stake
+	default:
+		return json.Unmarshal(entry.Stake, &o.Stake)
+	}
+	return nil
 }
 
 // QueryResponse struct holds the GraphQL response data
@@ -35,155 +69,481 @@ type QueryResponse struct {
 	} `json:"data"`
 }
 
-// Hash function using xxhash
+// Hash function using xxh128, matching the nodes
 func hash(input string) string {
-	h := xxhash.New()
-	h.Write([]byte(input))
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return hashStrings(DefaultHasher, input)
 }
 
 // Get operators by making a GraphQL query to the external API
 func getOperators() (map[string]Operator, error) {
-	subgraphURL := "https://api.studio.thegraph.com/query/85556/bls_apk_registry/version/latest"
-	query := `{"query": "query { operators { id operatorId pubkeyG1_X pubkeyG1_Y pubkeyG2_X pubkeyG2_Y socket stake }}"}`
+	return fetchOperators(defaultTransport)
+}
+
+const (
+	subgraphURL    = "https://api.studio.thegraph.com/query/85556/bls_apk_registry/version/latest"
+	operatorsQuery = `{"query": "query { operators { id operatorId pubkeyG1_X pubkeyG1_Y pubkeyG2_X pubkeyG2_Y socket stake }}"}`
+)
 
-	resp, err := http.Post(subgraphURL, "application/json", bytes.NewBuffer([]byte(query)))
+// operatorsGroup coalesces concurrent identical registry queries made through
+// the same transport into one in-flight request
+var operatorsGroup singleflight.Group
+
+// fetchOperators queries the subgraph for operators through the given transport.
+// Concurrent callers asking for the same query through the same transport share
+// a single request and each receive their own copy of the result. Callers with
+// other transports, and so other credentials or clients, query on their own.
+func fetchOperators(t *transport) (map[string]Operator, error) {
+	key := fmt.Sprintf("%p %s%s", t, subgraphURL, operatorsQuery)
+	v, err, _ := operatorsGroup.Do(key, func() (interface{}, error) {
+		return queryOperators(t, subgraphURL, operatorsQuery)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	shared := v.(map[string]Operator)
+	operators := make(map[string]Operator, len(shared))
+	for id, operator := range shared {
+		operators[id] = operator
 	}
+	return operators, nil
+}
 
-	var response QueryResponse
-	err = json.Unmarshal(body, &response)
+// queryOperators posts a GraphQL query to the subgraph and decodes the operators
+func queryOperators(t *transport, subgraphURL, query string) (map[string]Operator, error) {
+	entries, err := queryRegistry(t, subgraphURL, query)
 	if err != nil {
 		return nil, err
 	}
 
 	operators := make(map[string]Operator)
-	for _, operator := range response.Data.Operators {
-		operator.Stake = float64(int64(operator.Stake) / (10 ^ 18))
-
-		// Replace this section with actual BLS key handling logic
-		publicKeyG2 := bls12-381.G2Affine{} // Adjust with real BLS library methods
-
-		operator.PublicKeyG2 = publicKeyG2
+	for _, operator := range entries {
+		if err := operator.decodePublicKey(); err != nil {
+			return nil, err
+		}
+		// An unreachable operator still counts towards stake, it just can't be selected as a node
+		socket, err := NormalizeSocket(operator.Socket)
+		if err != nil {
+			log.Printf("zellular: ignoring socket of operator %s: %v", operator.ID, err)
+		}
+		operator.Socket = socket
 		operators[operator.ID] = operator
 	}
 
 	return operators, nil
 }
 
+// queryRegistry posts a GraphQL query to the subgraph and returns the
+// registry entries as they are, without decoding or validating them
+func queryRegistry(t *transport, subgraphURL, query string) ([]Operator, error) {
+	resp, err := t.post(subgraphURL, "application/json", bytes.NewBuffer([]byte(query)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response QueryResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data.Operators, nil
+}
+
 // Zellular struct holds the application and operator information
 type Zellular struct {
-	AppName            string
-	BaseURL            string
-	ThresholdPercent   float64
-	Operators          map[string]Operator
-	AggregatedPublicKey bls12-381.G2Affine
+	AppName          string
+	BaseURL          string
+	ThresholdPercent float64
+	// Operators and AggregatedPublicKey are replaced together under
+	// operatorsMu when the operator set is refreshed; read them through
+	// LookupOperator or OperatorSet when refreshes may run concurrently
+	Operators           map[string]Operator
+	AggregatedPublicKey bn254.G2Affine
+	operatorsMu         *sync.RWMutex
+
+	transport  *transport
+	wal        *WAL
+	progress   *progress
+	errors     *errorLog
+	stats      *statsRecorder
+	reputation *Reputation
+	discovery  Discovery
+	sockets    SocketDiscovery
+	pages      pageFetcher
+	strict     bool
+	auditSink  AuditSink
+	resolver   *SocketResolver
+	policy     QuorumPolicy
+	// confirmationDepth holds back the batches within this many indices of
+	// the latest finalized index
+	confirmationDepth int
+	hashIndex         KVStore
+	eventHandlers     []EventHandler
+	journal           *eventJournal
+	usage             *usageCounter
+	pools             *endpointPools
+	balancer          *readBalancer
+	minCoalition      int
+	hasher            Hasher
+	verifySlots       chan struct{}
+	pinnedDigest      string
+	apkCheck          *onChainAPK
+	eth               *EthClient
+	guards            *UpdateGuards
+	quarantined       *quarantine
+	keys              *keyHistory
+	scheme            SignatureScheme
+	domain            *DomainSeparation
+	networkID         string
+	maxBatchSize      int
+	// tenant labels the usage metrics of a Tenant's app clients
+	tenant      string
+	sendLimiter *tokenBucket
+	censorship  *CensorshipMonitor
+	receipts    KVStore
+	skipVerify  bool
+	// chunkSchemes verify data availability samples by commitment scheme
+	chunkSchemes map[string]ChunkScheme
+
+	adminPprof bool
+	baseMu     *sync.RWMutex
 }
 
 // NewZellular initializes a new Zellular instance
-func NewZellular(appName, baseURL string, thresholdPercent float64) *Zellular {
-	operators, _ := getOperators()
-	aggregatedPublicKey := bls12-381.G2Affine{} // Adjust this with real logic to aggregate G2 keys
+func NewZellular(appName, baseURL string, thresholdPercent float64, opts ...Option) *Zellular {
+	z := newZellular(appName, baseURL, thresholdPercent, opts...)
+	operators, err := z.discoverOperators()
+	if err == nil {
+		err = z.setOperators(operators)
+	}
+	if err != nil {
+		z.errors.record(err)
+	}
+	return z
+}
 
-	// Aggregate all operator public keys
-	for _, operator := range operators {
-		// Add each operator's G2 public key
-		aggregatedPublicKey.Add(&operator.PublicKeyG2)
+// newZellular applies the options to a Zellular without loading operators
+func newZellular(appName, baseURL string, thresholdPercent float64, opts ...Option) *Zellular {
+	z := &Zellular{
+		AppName:          appName,
+		BaseURL:          baseURL,
+		ThresholdPercent: thresholdPercent,
+		transport:        newTransport(),
+		progress:         &progress{},
+		errors:           &errorLog{},
+		stats:            newStatsRecorder(),
+		baseMu:           &sync.RWMutex{},
+		operatorsMu:      &sync.RWMutex{},
+		hasher:           DefaultHasher,
+		verifySlots:      defaultVerifySlots(),
+		quarantined:      &quarantine{},
+		keys:             &keyHistory{},
+		journal:          newEventJournal(defaultJournalSize),
+		usage:            &usageCounter{},
+	}
+	z.transport.onRetry = func(url string, attempt int, delay time.Duration) {
+		z.emit(&RetryEvent{URL: url, Attempt: attempt, Delay: delay})
+	}
+	for _, opt := range opts {
+		opt(z)
 	}
+	return z
+}
 
-	return &Zellular{
-		AppName:            appName,
-		BaseURL:            baseURL,
-		ThresholdPercent:   thresholdPercent,
-		Operators:          operators,
-		AggregatedPublicKey: aggregatedPublicKey,
+// setOperators replaces the operator set and recomputes the aggregated
+// public key, decoding the G2 keys of operators loaded from snapshots
+func (z *Zellular) setOperators(operators map[string]Operator) error {
+	if z.scheme == nil {
+		if err := decodePublicKeys(operators); err != nil {
+			return err
+		}
+	}
+	if err := z.checkPinned(operators); err != nil {
+		return err
 	}
+	if err := z.checkOnChainAPK(operators); err != nil {
+		return err
+	}
+	z.trackRotations(operators)
+	aggregated := aggregatePublicKeys(operators)
+	z.operatorsMu.Lock()
+	z.Operators = operators
+	z.AggregatedPublicKey = aggregated
+	z.operatorsMu.Unlock()
+	z.emit(&OperatorsUpdatedEvent{Operators: len(operators), Digest: OperatorSetDigest(operators)})
+	z.checkConcentration()
+	return nil
+}
+
+// OperatorSet returns the current operators and their aggregated public
+// key. The map is replaced on refresh and never modified, so it stays
+// consistent after the call returns.
+func (z *Zellular) OperatorSet() (map[string]Operator, bn254.G2Affine) {
+	z.operatorsMu.RLock()
+	defer z.operatorsMu.RUnlock()
+	return z.Operators, z.AggregatedPublicKey
+}
+
+// operators returns the current operator map
+func (z *Zellular) operators() map[string]Operator {
+	operators, _ := z.OperatorSet()
+	return operators
+}
+
+// base returns the node currently used for reads and submissions
+func (z *Zellular) base() string {
+	z.baseMu.RLock()
+	defer z.baseMu.RUnlock()
+	return z.BaseURL
 }
 
-// VerifySignature verifies the BLS signature
-func (z *Zellular) VerifySignature(message, signatureHex string, nonsigners []string) bool {
-	totalStake := 0.0
-	for _, operator := range z.Operators {
-		totalStake += operator.Stake
+// setBase switches the node used for reads and submissions, ending the
+// sticky session with the previous one
+func (z *Zellular) setBase(url string) {
+	z.baseMu.Lock()
+	previous := z.BaseURL
+	if previous != url {
+		z.transport.sessions.forget(previous)
 	}
+	z.BaseURL = url
+	z.baseMu.Unlock()
+	if previous != url {
+		z.emit(&FailoverEvent{From: previous, To: url})
+	}
+}
+
+// forApp returns a Zellular for another app sharing this instance's
+// operators, aggregated key, connection pool and write-ahead log. The WAL
+// keys entries by app, and the app gets a journal of its own.
+func (z *Zellular) forApp(appName string) *Zellular {
+	z.operatorsMu.RLock()
+	c := *z
+	z.operatorsMu.RUnlock()
+	c.AppName = appName
+	c.progress = &progress{}
+	c.errors = &errorLog{}
+	c.stats = newStatsRecorder()
+	c.usage = &usageCounter{}
+	c.journal = newEventJournal(len(z.journal.entries))
+	c.baseMu = &sync.RWMutex{}
+	c.operatorsMu = &sync.RWMutex{}
+	return &c
+}
 
-	nonsignersStake := 0.0
-	for _, nonsigner := range nonsigners {
-		nonsignersStake += z.Operators[nonsigner].Stake
+// VerifySignature verifies the BLS signature of message by all operators
+// except the nonsigners. The result tells why a signature was rejected; an
+// error is only returned when verification could not be carried out.
+func (z *Zellular) VerifySignature(message, signatureHex string, nonsigners []string) (VerificationResult, error) {
+	return z.verifySignature(message, signatureHex, nonsigners, z.checkSignature)
+}
+
+// signatureCheck verifies a decoded signature over a message hash by all
+// operators except the nonsigners
+type signatureCheck func(nonsigners []string, message []byte, signature *bn254.G1Affine) (bool, error)
+
+// checkSignature is the signatureCheck aggregating the signers' key on every call
+func (z *Zellular) checkSignature(nonsigners []string, message []byte, signature *bn254.G1Affine) (bool, error) {
+	publicKey := z.signersPublicKey(nonsigners)
+	h := z.messagePoint(message)
+	return verifyBLS(&publicKey, &h, signature)
+}
+
+// verifySignature runs the nonsigner, quorum and signature checks of
+// VerifySignature with the given pairing check
+func (z *Zellular) verifySignature(message, signatureHex string, nonsigners []string, check signatureCheck) (result VerificationResult, err error) {
+	defer func(start time.Time) { z.stats.verified(time.Since(start)) }(time.Now())
+
+	result.Quorum = z.CheckQuorum(nonsigners)
+	defer func() {
+		record := AuditRecord{
+			MessageHash:    hash(message),
+			Signature:      signatureHex,
+			Nonsigners:     nonsigners,
+			TotalStake:     result.Quorum.TotalStake,
+			NonsignerStake: result.Quorum.NonsignerStake,
+			Accepted:       err == nil && result.Valid(),
+			Reason:         result.Reason,
+		}
+		if err != nil {
+			record.Reason = err.Error()
+		}
+		z.audit(record)
+		if !record.Accepted {
+			z.emit(&VerificationFailedEvent{AppName: z.AppName, MessageHash: record.MessageHash, Status: result.Status, Reason: record.Reason})
+		}
+	}()
+
+	if err := z.ValidateNonsigners(nonsigners); err != nil {
+		return result.fail(UnknownNonsigner, err.Error()), nil
+	}
+	if err := z.quorumPolicy().Evaluate(z.quorumInput(message, nonsigners)); err != nil {
+		return result.fail(ThresholdNotMet, "quorum not met: "+err.Error()+"; "+result.Quorum.String()), nil
 	}
 
-	if 100*nonsignersStake/totalStake > (100 - z.ThresholdPercent) {
-		return false
+	if z.scheme != nil {
+		z.verifySlots <- struct{}{}
+		schemeErr := z.scheme.Verify(z.signerOperators(nonsigners), []byte(hash(message)), signatureHex)
+		<-z.verifySlots
+		if schemeErr != nil {
+			return result.fail(InvalidSignature, z.scheme.Name()+": "+schemeErr.Error()), nil
+		}
+		return result, nil
 	}
 
-	// Subtract nonsigners' public keys
-	publicKey := z.AggregatedPublicKey
-	for _, nonsigner := range nonsigners {
-		publicKey.Sub(&z.Operators[nonsigner].PublicKeyG2)
+	signature, err := decodeSignature(signatureHex)
+	if err != nil {
+		return result.fail(InvalidSignature, "malformed signature: "+err.Error()), nil
 	}
 
-	// Decode signature and verify (using real BLS verification)
-	messageHash := hash(message)
-	signature := bls12-381.Signature{} // Replace this with the actual BLS signature decoding
-	return signature.Verify(&publicKey, []byte(messageHash))
+	z.verifySlots <- struct{}{}
+	valid, err := check(nonsigners, []byte(hash(message)), &signature)
+	<-z.verifySlots
+	if err != nil {
+		return result, fmt.Errorf("zellular: verifying signature: %w", err)
+	}
+	if !valid {
+		return result.fail(InvalidSignature, "signature does not match the signers' public key"), nil
+	}
+	return result, nil
 }
 
-// GetFinalized retrieves finalized batches from the backend
-func (z *Zellular) GetFinalized(after int, chainingHash *string) ([]string, error) {
-	var res []string
-	index := after
-	if chainingHash == nil {
-		index = after - 1
-	}
+// FinalizedResult is the run of batches GetFinalizedFrom returns, ending
+// with the next finalized batch
+type FinalizedResult struct {
+	Batches []string
+	// Index and ChainingHash are those of the last batch, the position to
+	// resume from in the next GetFinalizedFrom call
+	Index        int
+	ChainingHash string
+	// Record is the finalization of the last batch
+	Record *FinalizedRecord
+}
 
+// GetFinalizedFrom retrieves the batches after index after up to the next
+// finalized one. chainingHash is the chaining hash of batch after, as
+// returned in the previous result when resuming; batches are chained from
+// it and must match the finalization unless the client was created
+// WithoutVerification. A fresh start passes after 0 and the empty hash,
+// which anchors batch 1. An empty hash with a later index trusts the
+// chaining hash the node reports for the first batch instead.
+func (z *Zellular) GetFinalizedFrom(ctx context.Context, after int, chainingHash string) (*FinalizedResult, error) {
+	result := &FinalizedResult{Index: after, ChainingHash: chainingHash}
+	anchored := after == 0 || chainingHash != ""
 	for {
-		url := fmt.Sprintf("%s/node/%s/batches/finalized?after=%d", z.BaseURL, z.AppName, index)
-		resp, err := http.Get(url)
+		page, err := z.fetchFinalized(ctx, result.Index)
 		if err != nil {
 			return nil, err
 		}
-		defer resp.Body.Close()
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-
-		var data map[string]interface{}
-		err = json.Unmarshal(body, &data)
-		if err != nil || data["data"] == nil {
+		if page == nil || len(page.Batches) == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
 			continue
 		}
 
-		batches := data["data"].(map[string]interface{})["batches"].([]interface{})
-		finalized := data["data"].(map[string]interface{})["finalized"].(map[string]interface{})
-
-		for _, batch := range batches {
-			batchStr := fmt.Sprintf("%v", batch)
-			res = append(res, batchStr)
-			index++
-			if finalized != nil && index == int(finalized["index"].(float64)) {
-				chainingHashStr := chainingHash
-				if chainingHash != nil {
-					*chainingHash = hash(*chainingHash + hash(batchStr))
-				} else {
-					chainingHashStr = &batchStr
+		for i, batch := range page.Batches {
+			if anchored {
+				result.ChainingHash = z.chain(result.ChainingHash, batch)
+				if i == 0 && page.FirstChainingHash != "" && page.FirstChainingHash != result.ChainingHash {
+					z.observeBadData(page.node)
+					return nil, fmt.Errorf("zellular: batch %d does not chain to the given chaining hash", result.Index+1)
 				}
-				return res, nil
+			} else if i == 0 && page.FirstChainingHash != "" {
+				result.ChainingHash, anchored = page.FirstChainingHash, true
 			}
+			result.Batches = append(result.Batches, batch)
+			result.Index++
+
+			record := page.Finalized
+			if record == nil || result.Index != record.Index {
+				continue
+			}
+			if !z.skipVerify {
+				if err := z.verifyFinalized(record, batch, result.ChainingHash, anchored, page.node); err != nil {
+					return nil, err
+				}
+			}
+			if !anchored {
+				result.ChainingHash = record.ChainingHash
+			}
+			result.Record = record
+			return result, nil
 		}
 	}
 }
 
+// GetFinalized retrieves finalized batches from the backend. With a nil
+// chainingHash the batches start at index after; otherwise they follow
+// index after, chainingHash is its chaining hash and is updated to that
+// of the last batch returned. New code should use GetFinalizedFrom.
+func (z *Zellular) GetFinalized(after int, chainingHash *string) ([]string, error) {
+	from, anchor := after-1, ""
+	if chainingHash != nil {
+		from, anchor = after, *chainingHash
+	}
+	result, err := z.GetFinalizedFrom(context.Background(), from, anchor)
+	if err != nil {
+		return nil, err
+	}
+	if chainingHash != nil {
+		*chainingHash = result.ChainingHash
+	}
+	return result.Batches, nil
+}
+
+// verifyFinalized checks that the last batch fetched from node and, when
+// anchored, its chaining hash match the record and that the record's
+// signature verifies
+func (z *Zellular) verifyFinalized(record *FinalizedRecord, last, chained string, anchored bool, node string) error {
+	return z.verifyProven(Batch{Index: record.Index, Payload: last, ChainingHash: chained, Node: node, Proof: record.proof(z.AppName)}, anchored)
+}
+
+// verifyProven checks a batch against the finality proof it carries: its
+// hash, its chaining hash when anchored, and the proof's signature. A batch
+// failing the checks counts against the reputation of the node serving it.
+func (z *Zellular) verifyProven(batch Batch, anchored bool) error {
+	proof := batch.Proof
+	if z.batchHash(batch.Payload) != proof.Hash {
+		z.observeBadData(batch.Node)
+		return fmt.Errorf("zellular: batch %d does not match the hash of its finalization", batch.Index)
+	}
+	if anchored && batch.ChainingHash != proof.ChainingHash {
+		z.observeBadData(batch.Node)
+		return fmt.Errorf("zellular: chaining hash of batch %d does not match its finalization", batch.Index)
+	}
+	result, err := z.VerifyProof(*proof)
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		z.observeBadData(batch.Node)
+		return fmt.Errorf("zellular: finalization of batch %d failed verification: %s", batch.Index, result.Reason)
+	}
+	return nil
+}
+
+// VerifyRecord verifies the aggregated signature of a finalized record
+func (z *Zellular) VerifyRecord(record *FinalizedRecord) (VerificationResult, error) {
+	return z.VerifyProof(record.Proof(z.AppName))
+}
+
 // Main function demonstrates the Zellular implementation
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	operators, err := getOperators()
 	if err != nil {
 		log.Fatalf("Error getting operators: %v", err)
@@ -193,22 +553,23 @@ func main() {
 	fmt.Println("Base URL:", baseURL)
 
 	verifier := NewZellular("simple_app", baseURL, 67)
-	batches, err := verifier.GetFinalized(0, nil)
+	result, err := verifier.GetFinalizedFrom(context.Background(), 0, "")
 	if err != nil {
 		log.Fatalf("Error getting finalized batches: %v", err)
 	}
+	fmt.Printf("Verified up to batch %d, chaining hash %s\n", result.Index, result.ChainingHash)
 
-	for i, batch := range batches {
-		fmt.Printf("Batch %d: %s\n", i, batch)
+	for i, batch := range result.Batches {
+		fmt.Printf("Batch %d: %s\n", i+1, batch)
 	}
 }
 
 // Utility to select a random operator
 func randomOperator(operators map[string]Operator) string {
 	keys := make([]string, 0, len(operators))
-	for key := range operators {
+	for key := range withSockets(operators) {
 		keys = append(keys, key)
 	}
 	rand.Seed(time.Now().UnixNano())
 	return keys[rand.Intn(len(keys))]
-}
\ No newline at end of file
+}
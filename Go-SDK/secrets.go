@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/subtle"
+	"runtime"
+)
+
+// Secret holds key material or credentials. It owns its bytes: callers must
+// not keep or modify the slice they created it from, and should Destroy it
+// once it is no longer needed. It prints as "[redacted]".
+type Secret struct {
+	b []byte
+}
+
+// NewSecret takes ownership of b without copying it
+func NewSecret(b []byte) *Secret {
+	return &Secret{b: b}
+}
+
+// Bytes returns the secret without copying it; the result is only valid
+// until Destroy
+func (s *Secret) Bytes() []byte {
+	return s.b
+}
+
+// Equal compares the secret with b in constant time
+func (s *Secret) Equal(b []byte) bool {
+	return secretEqual(s.b, b)
+}
+
+// Destroy zeroes the secret
+func (s *Secret) Destroy() {
+	wipe(s.b)
+	s.b = nil
+}
+
+// String implements fmt.Stringer without revealing the secret
+func (s *Secret) String() string {
+	return "[redacted]"
+}
+
+// GoString implements fmt.GoStringer without revealing the secret
+func (s *Secret) GoString() string {
+	return "[redacted]"
+}
+
+// secretEqual compares secrets in time independent of their contents
+func secretEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// wipe zeroes b; KeepAlive stops the compiler from eliding the writes
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Submission is a batch as Send submits it
+type Submission struct {
+	// Node is the base URL of the node the batch goes to
+	Node    string
+	URL     string
+	Payload []byte
+	// Transactions is the number of transactions in the batch
+	Transactions int
+}
+
+// WithMaxBatchSize rejects batches whose encoding exceeds size bytes
+// before they are submitted
+func WithMaxBatchSize(size int) Option {
+	return func(z *Zellular) {
+		z.maxBatchSize = size
+	}
+}
+
+// PrepareSubmission encodes and validates txs as Send does and returns
+// what it would submit, without contacting any node. The batch must be a
+// non-empty JSON array within the WithMaxBatchSize limit.
+func (z *Zellular) PrepareSubmission(ctx context.Context, txs interface{}) (*Submission, error) {
+	payload, count, err := encodeBatch(txs, z.maxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := nodeFor(ctx, z.base())
+	return &Submission{
+		Node:         baseURL,
+		URL:          fmt.Sprintf("%s/node/%s/batches", baseURL, z.AppName),
+		Payload:      payload,
+		Transactions: count,
+	}, nil
+}
+
+// encodeBatch encodes txs as a non-empty JSON array of at most maxSize
+// bytes, no limit when maxSize is 0, and returns its number of elements
+func encodeBatch(txs interface{}, maxSize int) ([]byte, int, error) {
+	payload, err := json.Marshal(txs)
+	if err != nil {
+		return nil, 0, err
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(payload, &elements); err != nil {
+		return nil, 0, fmt.Errorf("zellular: a batch must encode to a JSON array of transactions: %w", err)
+	}
+	if len(elements) == 0 {
+		return nil, 0, fmt.Errorf("zellular: empty batch")
+	}
+	if maxSize > 0 && len(payload) > maxSize {
+		return nil, 0, fmt.Errorf("zellular: batch of %d bytes exceeds the %d byte limit", len(payload), maxSize)
+	}
+	return payload, len(elements), nil
+}
+
+// Send submits a batch of transactions to the base node for sequencing.
+// With the DryRun call option it only runs PrepareSubmission.
+func (z *Zellular) Send(ctx context.Context, txs interface{}) error {
+	submission, err := z.PrepareSubmission(ctx, txs)
+	if err != nil || callOptions(ctx).DryRun {
+		return err
+	}
+	if z.sendLimiter != nil {
+		if err := z.sendLimiter.wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	baseURL, payload := submission.Node, submission.Payload
+	resp, err := z.transport.do(withSubmitRole(ctx), http.MethodPut, submission.URL, "application/json", bytes.NewReader(payload))
+	z.observeEndpoint(baseURL, err)
+	if err != nil {
+		z.errors.record(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err := quotaExceeded(baseURL, resp)
+		z.errors.record(err)
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("zellular: sending batch to %s failed with status %d", baseURL, resp.StatusCode)
+		z.errors.record(err)
+		return err
+	}
+	if z.receipts != nil {
+		if err := z.keepReceipt(resp, payload); err != nil {
+			z.errors.record(err)
+		}
+	}
+	z.stats.submitted(string(payload))
+	z.usage.add(z.usageLabel(), submission.Transactions, len(payload))
+	if z.censorship != nil {
+		z.censorship.submitted(baseURL, payload)
+	}
+	return nil
+}
+
+// canonicalJSON re-encodes a JSON document with sorted keys and no
+// insignificant whitespace so equal batches compare equal
+func canonicalJSON(payload string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(payload), &v); err != nil {
+		return payload
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return payload
+	}
+	return string(out)
+}
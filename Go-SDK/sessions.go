@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// SessionHeader is the header a node may return with an affinity token to
+// be sent back on later requests
+const SessionHeader = "Zellular-Session"
+
+// sessions remembers the affinity cookies and tokens each node handed out
+type sessions struct {
+	mu      sync.Mutex
+	cookies map[string][]*http.Cookie
+	tokens  map[string]string
+}
+
+// WithStickySessions returns each node's session cookies and
+// Zellular-Session token on later requests to it, until the client fails
+// over to another node
+func WithStickySessions() Option {
+	return func(z *Zellular) {
+		z.transport.sessions = &sessions{
+			cookies: make(map[string][]*http.Cookie),
+			tokens:  make(map[string]string),
+		}
+	}
+}
+
+// apply attaches the session of the request's host
+func (s *sessions) apply(req *http.Request) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cookie := range s.cookies[req.URL.Host] {
+		req.AddCookie(cookie)
+	}
+	if token := s.tokens[req.URL.Host]; token != "" {
+		req.Header.Set(SessionHeader, token)
+	}
+}
+
+// capture stores the session a response hands out
+func (s *sessions) capture(host string, resp *http.Response) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		s.cookies[host] = cookies
+	}
+	if token := resp.Header.Get(SessionHeader); token != "" {
+		s.tokens[host] = token
+	}
+}
+
+// forget drops the session of the node at baseURL
+func (s *sessions) forget(baseURL string) {
+	if s == nil {
+		return
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cookies, u.Host)
+	delete(s.tokens, u.Host)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/cespare/xxhash"
+)
+
+// ShardKey extracts the partitioning key from a batch
+type ShardKey func(batch Batch) (string, error)
+
+// ShardOf maps a key to one of n shards. The mapping is stable across
+// processes, so every instance agrees on which shard owns a key.
+func ShardOf(key string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(xxhash.Sum64String(key) % uint64(n))
+}
+
+// Sharding splits an app's stream across Shards instances. Each instance
+// consumes the whole stream but handles only the batches whose key falls in
+// its Shard; since every key is owned by exactly one instance, which sees
+// that key's batches in index order, per-key ordering is preserved.
+type Sharding struct {
+	Key    ShardKey
+	Shards int
+	Shard  int
+}
+
+// Owns reports whether batch belongs to this instance's shard
+func (s Sharding) Owns(batch Batch) (bool, error) {
+	key, err := s.Key(batch)
+	if err != nil {
+		return false, err
+	}
+	return ShardOf(key, s.Shards) == s.Shard, nil
+}
+
+// Handler wraps handler so it only sees this shard's batches; the others
+// are passed over and the checkpoint still advances past them
+func (s Sharding) Handler(handler Handler) Handler {
+	return func(ctx context.Context, batch Batch) error {
+		owned, err := s.Owns(batch)
+		if err != nil || !owned {
+			return err
+		}
+		return handler(ctx, batch)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+)
+
+// SignedState is a VerifierState signed by the key of a trusted exporter,
+// letting verifiers without subgraph or eth access bootstrap from it
+type SignedState struct {
+	// State is the JSON encoded VerifierState, signed as is
+	State     json.RawMessage `json:"state"`
+	Signature []byte          `json:"signature"`
+}
+
+// ExportSignedState is ExportState signed with key
+func (z *Zellular) ExportSignedState(key ed25519.PrivateKey) ([]byte, error) {
+	state, err := z.ExportState()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignedState{State: state, Signature: ed25519.Sign(key, state)})
+}
+
+// ImportSignedState is ImportState for a snapshot that must be signed by trusted
+func (z *Zellular) ImportSignedState(data []byte, trusted ed25519.PublicKey) error {
+	state, err := openSignedState(data, trusted)
+	if err != nil {
+		return err
+	}
+	return z.ImportState(state)
+}
+
+// openSignedState checks the signature of a SignedState and returns the state
+func openSignedState(data []byte, trusted ed25519.PublicKey) ([]byte, error) {
+	var signed SignedState
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(trusted, signed.State, signed.Signature) {
+		return nil, errors.New("zellular: snapshot is not signed by the trusted key")
+	}
+	return signed.State, nil
+}
+
+// SignedSnapshotDiscovery loads the operator set from a file written by
+// ExportSignedState, rejecting it unless it is signed by Key
+type SignedSnapshotDiscovery struct {
+	Path string
+	Key  ed25519.PublicKey
+}
+
+// Discover reads the snapshot and checks its signature
+func (d SignedSnapshotDiscovery) Discover(ctx context.Context) (map[string]Operator, error) {
+	data, err := ioutil.ReadFile(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	stateData, err := openSignedState(data, d.Key)
+	if err != nil {
+		return nil, err
+	}
+	var state VerifierState
+	if err := json.Unmarshal(stateData, &state); err != nil {
+		return nil, err
+	}
+	return state.operatorSet(), nil
+}
@@ -0,0 +1,342 @@
+//go:build snark
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
+)
+
+// FinalityCircuit proves two things only: that BatchHashes chain from
+// StartChainingHash to EndChainingHash with the nodes' xxh128 chaining, and
+// that Signature is a BLS signature by SignersKey over MessagePoint. Hashes
+// are the 128-bit digests the hex strings encode. The circuit does not
+// derive SignersKey from the operators and nonsigners, nor sum their stake:
+// the verifier aggregates the key, maps the finality message of
+// EndChainingHash to MessagePoint and checks the stake threshold itself.
+type FinalityCircuit struct {
+	StartChainingHash frontend.Variable   `gnark:",public"`
+	BatchHashes       []frontend.Variable `gnark:",public"`
+	EndChainingHash   frontend.Variable   `gnark:",public"`
+	MessagePoint      sw_bn254.G1Affine   `gnark:",public"`
+	SignersKey        sw_bn254.G2Affine   `gnark:",public"`
+	Signature         sw_bn254.G1Affine
+}
+
+// Define implements frontend.Circuit
+func (c *FinalityCircuit) Define(api frontend.API) error {
+	x := xxh128{api: api}
+	chained := c.StartChainingHash
+	for _, batchHash := range c.BatchHashes {
+		chained = x.chain(chained, batchHash)
+	}
+	api.AssertIsEqual(chained, c.EndChainingHash)
+
+	pairing, err := sw_bn254.NewPairing(api)
+	if err != nil {
+		return err
+	}
+	_, _, _, g2 := bn254.Generators()
+	var negated bn254.G2Affine
+	negated.Neg(&g2)
+	generator := sw_bn254.NewG2Affine(negated)
+	// e(signature, g2) == e(message, key)
+	return pairing.PairingCheck(
+		[]*sw_bn254.G1Affine{&c.Signature, &c.MessagePoint},
+		[]*sw_bn254.G2Affine{&generator, &c.SignersKey},
+	)
+}
+
+// xxh128 computes XXH3-128 chaining hashes in a circuit, with 64-bit words
+// and 128-bit digests held in single field elements. A chaining hash input
+// is the 64 hex digits of two digests, so only the 17 to 128 byte branch of
+// xxh3Sum128 is needed, unrolled for 64 bytes.
+type xxh128 struct {
+	api frontend.API
+}
+
+// chain returns the chaining hash following previous after batchHash: the
+// xxh128 of the 32 hex digits of both
+func (x xxh128) chain(previous, batchHash frontend.Variable) frontend.Variable {
+	in := append(x.hexDigits(previous), x.hexDigits(batchHash)...)
+	read := func(i int) frontend.Variable {
+		return x.lane(in[i : i+8])
+	}
+	mix16 := func(i, secret int) frontend.Variable {
+		return x.fold(x.xor(read(i), secretWord(secret)), x.xor(read(i+8), secretWord(secret+8)))
+	}
+	mix32 := func(acc *[2]frontend.Variable, a, b, secret int) {
+		acc[0] = x.xor(x.add(acc[0], mix16(a, secret)), x.add(read(b), read(b+8)))
+		acc[1] = x.xor(x.add(acc[1], mix16(b, secret+16)), x.add(read(a), read(a+8)))
+	}
+
+	n := uint64(len(in))
+	acc := [2]frontend.Variable{n * xxPrime64_1, 0}
+	mix32(&acc, 16, 32, 32)
+	mix32(&acc, 0, 48, 0)
+	h := x.add(x.add(x.mul(acc[0], uint64(xxPrime64_1)), x.mul(acc[1], uint64(xxPrime64_4))), n*xxPrime64_2)
+	hi := x.neg(x.avalanche(h))
+	lo := x.avalanche(x.add(acc[0], acc[1]))
+	return x.api.Add(x.api.Mul(hi, new(big.Int).Lsh(big.NewInt(1), 64)), lo)
+}
+
+// secretWord is the little-endian word of the default secret at offset i
+func secretWord(i int) uint64 {
+	return xxRead64(xxh3Secret[:], i)
+}
+
+func (x xxh128) avalanche(h frontend.Variable) frontend.Variable {
+	h = x.xor(h, x.shr(h, 37))
+	h = x.mul(h, uint64(0x165667919E3779F9))
+	return x.xor(h, x.shr(h, 32))
+}
+
+// hexDigits returns the ASCII lowercase hex digits of a 128-bit v, most
+// significant first
+func (x xxh128) hexDigits(v frontend.Variable) []frontend.Variable {
+	bits := x.api.ToBinary(v, 128)
+	digits := make([]frontend.Variable, 32)
+	for i := range digits {
+		b := bits[4*(31-i) : 4*(31-i)+4]
+		nibble := x.api.FromBinary(b...)
+		// a nibble of 10 or more has bit 3 and bit 1 or 2 set
+		letter := x.api.Mul(b[3], x.api.Sub(x.api.Add(b[1], b[2]), x.api.Mul(b[1], b[2])))
+		digits[i] = x.api.Add(nibble, 48, x.api.Mul(letter, 39))
+	}
+	return digits
+}
+
+// lane reads 8 bytes as a little-endian word
+func (x xxh128) lane(digits []frontend.Variable) frontend.Variable {
+	var v frontend.Variable = 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		v = x.api.Add(x.api.Mul(v, 256), digits[i])
+	}
+	return v
+}
+
+// low returns v, known to fit n bits, modulo 2^64
+func (x xxh128) low(v frontend.Variable, n int) frontend.Variable {
+	return x.api.FromBinary(x.api.ToBinary(v, n)[:64]...)
+}
+
+func (x xxh128) add(a, b frontend.Variable) frontend.Variable {
+	return x.low(x.api.Add(a, b), 65)
+}
+
+func (x xxh128) mul(a, b frontend.Variable) frontend.Variable {
+	return x.low(x.api.Mul(a, b), 128)
+}
+
+// fold is xxh3Fold64: the xor of the halves of the 128-bit product
+func (x xxh128) fold(a, b frontend.Variable) frontend.Variable {
+	bits := x.api.ToBinary(x.api.Mul(a, b), 128)
+	return x.xor(x.api.FromBinary(bits[:64]...), x.api.FromBinary(bits[64:]...))
+}
+
+// neg returns -v modulo 2^64
+func (x xxh128) neg(v frontend.Variable) frontend.Variable {
+	return x.low(x.api.Sub(new(big.Int).Lsh(big.NewInt(1), 64), v), 65)
+}
+
+func (x xxh128) shr(v frontend.Variable, s int) frontend.Variable {
+	return x.api.FromBinary(x.api.ToBinary(v, 64)[s:]...)
+}
+
+func (x xxh128) xor(a, b frontend.Variable) frontend.Variable {
+	as, bs := x.api.ToBinary(a, 64), x.api.ToBinary(b, 64)
+	bits := make([]frontend.Variable, 64)
+	for i := range bits {
+		bits[i] = x.api.Sub(x.api.Add(as[i], bs[i]), x.api.Mul(2, as[i], bs[i]))
+	}
+	return x.api.FromBinary(bits...)
+}
+
+// SNARKProver proves ranges of a fixed number of batches with Groth16 over
+// BN254, so the proofs are cheap to check on Ethereum. A checker still
+// has to aggregate the signers' key and check their stake, which the
+// circuit leaves out. The keys come from a local setup that is only fit
+// for testing; production use needs keys from a trusted setup ceremony.
+type SNARKProver struct {
+	Batches int
+
+	ccs constraint.ConstraintSystem
+	pk  groth16.ProvingKey
+	vk  groth16.VerifyingKey
+}
+
+// NewSNARKProver compiles the circuit for ranges of batches batches and
+// runs its setup, which takes minutes
+func NewSNARKProver(batches int) (*SNARKProver, error) {
+	if batches < 1 {
+		return nil, errors.New("zellular: a proven range needs at least one batch")
+	}
+	circuit := &FinalityCircuit{BatchHashes: make([]frontend.Variable, batches)}
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		return nil, err
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		return nil, err
+	}
+	return &SNARKProver{Batches: batches, ccs: ccs, pk: pk, vk: vk}, nil
+}
+
+// VerifyingKey returns the key succinct proofs are checked with; a
+// Solidity verifier is generated with its ExportSolidity method
+func (p *SNARKProver) VerifyingKey() groth16.VerifyingKey {
+	return p.vk
+}
+
+// SuccinctProof shows that BatchHashes are the batches following the one
+// with StartChainingHash, up to the finalization in Finality, and that the
+// finality signature is valid for the signers' key
+type SuccinctProof struct {
+	Finality          FinalityProof `json:"finality"`
+	StartChainingHash string        `json:"start_chaining_hash"`
+	BatchHashes       []string      `json:"batch_hashes"`
+	SNARK             []byte        `json:"snark"`
+}
+
+// ProveRange proves the p.Batches batches after index after, which must
+// end at a finalized batch. Ranges start after batch 1 or later, since the
+// first batch is chained from an empty hash.
+func (z *Zellular) ProveRange(ctx context.Context, p *SNARKProver, after int) (*SuccinctProof, error) {
+	if err := z.snarkCompatible(); err != nil {
+		return nil, err
+	}
+	if after < 1 {
+		return nil, errors.New("zellular: proven ranges start after batch 1 or later")
+	}
+	batches, err := z.fetchRange(ctx, after+1, after+p.Batches)
+	if err != nil {
+		return nil, err
+	}
+	last := batches[len(batches)-1]
+	if last.Proof == nil {
+		return nil, fmt.Errorf("zellular: batch %d is not finalized by a proof; ranges must end at one", last.Index)
+	}
+	first, err := z.GetBatch(ctx, after+1)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &SuccinctProof{Finality: *last.Proof, StartChainingHash: first.PreviousChainingHash}
+	chained := proof.StartChainingHash
+	for _, batch := range batches {
+		batchHash := z.batchHash(batch.Payload)
+		proof.BatchHashes = append(proof.BatchHashes, batchHash)
+		chained = z.chainHash(chained, batchHash)
+	}
+	if chained != proof.Finality.ChainingHash {
+		return nil, fmt.Errorf("zellular: batches after %d do not chain to the proof of batch %d", after, last.Index)
+	}
+
+	signature, err := decodeSignature(proof.Finality.Signature)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := z.snarkAssignment(proof, []byte(hash(proof.Finality.Message())))
+	if err != nil {
+		return nil, err
+	}
+	assignment.Signature = sw_bn254.NewG1Affine(signature)
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	snark, err := groth16.Prove(p.ccs, p.pk, witness)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := snark.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	proof.SNARK = buf.Bytes()
+	return proof, nil
+}
+
+// VerifySuccinct checks a succinct proof with vk instead of the pairing
+// and hashes it stands for. The nonsigner and quorum checks of VerifyProof
+// and the aggregation of the signers' key run outside the circuit as usual.
+func (z *Zellular) VerifySuccinct(vk groth16.VerifyingKey, proof *SuccinctProof) (VerificationResult, error) {
+	if err := z.snarkCompatible(); err != nil {
+		return VerificationResult{}, err
+	}
+	if z.scheme != nil {
+		return VerificationResult{}, errors.New("zellular: succinct proofs only cover BLS signatures")
+	}
+	if reason := z.foreignProof(proof.Finality); reason != "" {
+		return VerificationResult{}.fail(InvalidSignature, reason), nil
+	}
+	if n := len(proof.BatchHashes); n == 0 || proof.BatchHashes[n-1] != proof.Finality.Hash {
+		return VerificationResult{}.fail(InvalidSignature, "last batch hash does not match the finality proof"), nil
+	}
+
+	snark := groth16.NewProof(ecc.BN254)
+	if _, err := snark.ReadFrom(bytes.NewReader(proof.SNARK)); err != nil {
+		return VerificationResult{}.fail(InvalidSignature, "malformed snark: "+err.Error()), nil
+	}
+	check := func(nonsigners []string, message []byte, _ *bn254.G1Affine) (bool, error) {
+		assignment, err := z.snarkAssignment(proof, message)
+		if err != nil {
+			return false, err
+		}
+		witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField(), frontend.PublicOnly())
+		if err != nil {
+			return false, err
+		}
+		return groth16.Verify(snark, vk, witness) == nil, nil
+	}
+	return z.verifySignature(proof.Finality.Message(), proof.Finality.Signature, proof.Finality.Nonsigners, check)
+}
+
+// snarkAssignment fills the public inputs of the circuit for proof
+func (z *Zellular) snarkAssignment(proof *SuccinctProof, message []byte) (*FinalityCircuit, error) {
+	assignment := &FinalityCircuit{BatchHashes: make([]frontend.Variable, len(proof.BatchHashes))}
+	var err error
+	if assignment.StartChainingHash, err = parseDigest(proof.StartChainingHash); err != nil {
+		return nil, err
+	}
+	if assignment.EndChainingHash, err = parseDigest(proof.Finality.ChainingHash); err != nil {
+		return nil, err
+	}
+	for i, batchHash := range proof.BatchHashes {
+		if assignment.BatchHashes[i], err = parseDigest(batchHash); err != nil {
+			return nil, err
+		}
+	}
+	assignment.MessagePoint = sw_bn254.NewG1Affine(z.messagePoint(message))
+	assignment.SignersKey = sw_bn254.NewG2Affine(z.signersPublicKey(proof.Finality.Nonsigners))
+	return assignment, nil
+}
+
+// parseDigest decodes a 32 hex digit xxh128 digest
+func parseDigest(s string) (*big.Int, error) {
+	digest, ok := new(big.Int).SetString(s, 16)
+	if len(s) != 32 || !ok {
+		return nil, fmt.Errorf("zellular: %q is not an xxh128 digest", s)
+	}
+	return digest, nil
+}
+
+// snarkCompatible reports whether z chains batches the way the circuit does
+func (z *Zellular) snarkCompatible() error {
+	if z.hasher != DefaultHasher || z.chainingTag() != "" {
+		return errors.New("zellular: succinct proofs require the default hasher and untagged chaining")
+	}
+	return nil
+}
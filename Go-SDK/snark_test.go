@@ -0,0 +1,46 @@
+//go:build snark
+
+package main
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// chainCircuit checks the in-circuit chaining of one batch alone
+type chainCircuit struct {
+	Previous, BatchHash, Chained frontend.Variable
+}
+
+func (c *chainCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(xxh128{api: api}.chain(c.Previous, c.BatchHash), c.Chained)
+	return nil
+}
+
+func TestCircuitChainsLikeTheNodes(t *testing.T) {
+	z := newZellular("app", "http://localhost:6001", 67)
+	previous := z.batchHash(`["a"]`)
+	for _, payload := range []string{`["b"]`, `{"op": "transfer", "amount": 15}`, ""} {
+		batchHash := z.batchHash(payload)
+		chained := z.chainHash(previous, batchHash)
+
+		assignment := &chainCircuit{}
+		var err error
+		if assignment.Previous, err = parseDigest(previous); err != nil {
+			t.Fatal(err)
+		}
+		if assignment.BatchHash, err = parseDigest(batchHash); err != nil {
+			t.Fatal(err)
+		}
+		if assignment.Chained, err = parseDigest(chained); err != nil {
+			t.Fatal(err)
+		}
+		if err := test.IsSolved(&chainCircuit{}, assignment, ecc.BN254.ScalarField()); err != nil {
+			t.Fatalf("chaining %q: %v", payload, err)
+		}
+		previous = chained
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultSocketScheme is assumed for operator sockets registered without one
+var DefaultSocketScheme = "https"
+
+// NormalizeSocket parses an operator socket into a base URL of the form
+// scheme://host[:port], defaulting the scheme and rejecting sockets that
+// cannot possibly be reached
+func NormalizeSocket(socket string) (string, error) {
+	socket = strings.TrimSpace(socket)
+	if socket == "" {
+		return "", fmt.Errorf("zellular: empty socket")
+	}
+	if !strings.Contains(socket, "://") {
+		socket = DefaultSocketScheme + "://" + socket
+	}
+
+	u, err := url.Parse(socket)
+	if err != nil {
+		return "", fmt.Errorf("zellular: invalid socket %q: %w", socket, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("zellular: socket %q has unsupported scheme %q", socket, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("zellular: socket %q has no host", socket)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return "", fmt.Errorf("zellular: socket %q has an unspecified address", socket)
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("zellular: socket %q has invalid port %q", socket, port)
+		}
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" || strings.Trim(u.Path, "/") != "" {
+		return "", fmt.Errorf("zellular: socket %q must not have credentials, a path or a query", socket)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// withSockets returns the operators that have a usable socket
+func withSockets(operators map[string]Operator) map[string]Operator {
+	usable := make(map[string]Operator, len(operators))
+	for id, operator := range operators {
+		if operator.Socket != "" {
+			usable[id] = operator
+		}
+	}
+	return usable
+}
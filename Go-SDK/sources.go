@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SubgraphDiscovery loads operators from a subgraph deployment of the BLS
+// APK registry, e.g. a self-hosted mirror of the default one
+type SubgraphDiscovery struct {
+	URL string
+}
+
+// Discover queries the subgraph for the registered operators
+func (d SubgraphDiscovery) Discover(ctx context.Context) (map[string]Operator, error) {
+	return queryOperators(defaultTransport, d.URL, operatorsQuery)
+}
+
+// SnapshotDiscovery loads a static operator set from a file written by ExportState
+type SnapshotDiscovery struct {
+	Path string
+}
+
+// Discover reads the snapshot file
+func (d SnapshotDiscovery) Discover(ctx context.Context) (map[string]Operator, error) {
+	return loadSnapshot(d.Path)
+}
+
+// QuorumDiscovery queries several registry sources and only accepts an
+// operator set that at least Agreement of them return, so a single stale or
+// compromised source cannot change the keys or stakes signatures are
+// checked against. Sets are compared by OperatorSetDigest; sockets may
+// differ between sources and are taken from the first agreeing one.
+type QuorumDiscovery struct {
+	Sources []Discovery
+	// Agreement is the number of sources that must agree, a majority when zero
+	Agreement int
+}
+
+// Discover queries every source concurrently and returns the agreed set
+func (d QuorumDiscovery) Discover(ctx context.Context) (map[string]Operator, error) {
+	agreement := d.Agreement
+	if agreement == 0 {
+		agreement = len(d.Sources)/2 + 1
+	}
+
+	sets := make([]map[string]Operator, len(d.Sources))
+	errs := make([]error, len(d.Sources))
+	var wg sync.WaitGroup
+	for i, source := range d.Sources {
+		wg.Add(1)
+		go func(i int, source Discovery) {
+			defer wg.Done()
+			operators, err := source.Discover(ctx)
+			if err == nil {
+				err = decodePublicKeys(operators)
+			}
+			sets[i], errs[i] = operators, err
+		}(i, source)
+	}
+	wg.Wait()
+
+	votes := make(map[string][]int)
+	var failures []string
+	for i, operators := range sets {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("source %d: %v", i, errs[i]))
+			continue
+		}
+		digest := OperatorSetDigest(operators)
+		votes[digest] = append(votes[digest], i)
+	}
+	for _, sources := range votes {
+		if len(sources) >= agreement {
+			return sets[sources[0]], nil
+		}
+	}
+
+	var tally []string
+	for digest, sources := range votes {
+		tally = append(tally, fmt.Sprintf("%s from sources %v", digest, sources))
+	}
+	sort.Strings(tally)
+	msg := fmt.Sprintf("zellular: fewer than %d of %d registry sources agree on the operator set", agreement, len(d.Sources))
+	if len(tally) > 0 {
+		msg += "; got " + strings.Join(tally, ", ")
+	}
+	if len(failures) > 0 {
+		msg += "; " + strings.Join(failures, ", ")
+	}
+	return nil, fmt.Errorf("%s", msg)
+}
+
+// decodePublicKeys decodes the G2 keys of operators that have not been decoded yet
+func decodePublicKeys(operators map[string]Operator) error {
+	for id, operator := range operators {
+		if operator.PublicKeyG2.IsInfinity() {
+			if err := operator.decodePublicKey(); err != nil {
+				return err
+			}
+			operators[id] = operator
+		}
+	}
+	return nil
+}
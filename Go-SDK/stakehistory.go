@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// subgraphPageSize is the most entities the subgraph returns per query
+const subgraphPageSize = 1000
+
+// StakeUpdate is an operator's stake as of a registry update
+type StakeUpdate struct {
+	OperatorID  string
+	Stake       float64
+	BlockNumber int64
+	Time        time.Time
+}
+
+// The stake queries page by entity ID with id_gt, which the subgraph seeks
+// to directly, rather than with skip, which it caps and rescans every page
+const stakeHistoryQuery = `query($operator: String!, $from: BigInt!, $to: BigInt!, $after: String!) {
+  operatorStakeUpdates(where: {operator: $operator, blockTimestamp_gte: $from, blockTimestamp_lte: $to, id_gt: $after},
+    orderBy: id, orderDirection: asc, first: 1000) {
+    id operator stake blockNumber blockTimestamp
+  }
+}`
+
+const stakesAtQuery = `query($at: BigInt!, $after: String!) {
+  operatorStakeUpdates(where: {blockTimestamp_lte: $at, id_gt: $after},
+    orderBy: id, orderDirection: asc, first: 1000) {
+    id operator stake blockNumber blockTimestamp
+  }
+}`
+
+// stakeUpdateEntity is an operatorStakeUpdates entity of the registry subgraph
+type stakeUpdateEntity struct {
+	ID             string `json:"id"`
+	Operator       string `json:"operator"`
+	Stake          string `json:"stake"`
+	BlockNumber    string `json:"blockNumber"`
+	BlockTimestamp string `json:"blockTimestamp"`
+}
+
+func (e stakeUpdateEntity) update() (StakeUpdate, error) {
+	stake, err := weiToEther(e.Stake)
+	if err != nil {
+		return StakeUpdate{}, err
+	}
+	block, err := strconv.ParseInt(e.BlockNumber, 10, 64)
+	if err != nil {
+		return StakeUpdate{}, err
+	}
+	timestamp, err := strconv.ParseInt(e.BlockTimestamp, 10, 64)
+	if err != nil {
+		return StakeUpdate{}, err
+	}
+	return StakeUpdate{OperatorID: e.Operator, Stake: stake, BlockNumber: block, Time: time.Unix(timestamp, 0)}, nil
+}
+
+// queryStakeUpdates pages through a stake update query and returns the
+// updates in block order
+func (z *Zellular) queryStakeUpdates(ctx context.Context, query string, variables map[string]interface{}) ([]StakeUpdate, error) {
+	var updates []StakeUpdate
+	for after := ""; ; {
+		variables["after"] = after
+		var data struct {
+			OperatorStakeUpdates []stakeUpdateEntity `json:"operatorStakeUpdates"`
+		}
+		if err := querySubgraph(ctx, z.transport, query, variables, &data); err != nil {
+			return nil, err
+		}
+		for _, entity := range data.OperatorStakeUpdates {
+			update, err := entity.update()
+			if err != nil {
+				return nil, err
+			}
+			updates = append(updates, update)
+		}
+		if len(data.OperatorStakeUpdates) < subgraphPageSize {
+			break
+		}
+		after = data.OperatorStakeUpdates[len(data.OperatorStakeUpdates)-1].ID
+	}
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].BlockNumber < updates[j].BlockNumber
+	})
+	return updates, nil
+}
+
+// StakeHistory returns the stake updates of an operator between from and to, oldest first
+func (z *Zellular) StakeHistory(ctx context.Context, operatorID string, from, to time.Time) ([]StakeUpdate, error) {
+	return z.queryStakeUpdates(ctx, stakeHistoryQuery, map[string]interface{}{
+		"operator": operatorID,
+		"from":     strconv.FormatInt(from.Unix(), 10),
+		"to":       strconv.FormatInt(to.Unix(), 10),
+	})
+}
+
+// StakesAt reconstructs every operator's stake as of t, for checking
+// thresholds as they stood at a past epoch
+func (z *Zellular) StakesAt(ctx context.Context, t time.Time) (map[string]float64, error) {
+	updates, err := z.queryStakeUpdates(ctx, stakesAtQuery, map[string]interface{}{
+		"at": strconv.FormatInt(t.Unix(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	stakes := make(map[string]float64)
+	for _, update := range updates {
+		stakes[update.OperatorID] = update.Stake
+	}
+	return stakes, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// progress is the position of the last batch released to the application
+type progress struct {
+	mu              sync.Mutex
+	index           int
+	chainingHash    string
+	latestFinalized int
+}
+
+func (p *progress) set(index int, chainingHash string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.index, p.chainingHash = index, chainingHash
+}
+
+// observe records the latest finalized index reported by a node
+func (p *progress) observe(latestFinalized int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if latestFinalized > p.latestFinalized {
+		p.latestFinalized = latestFinalized
+	}
+}
+
+func (p *progress) latest() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latestFinalized
+}
+
+func (p *progress) get() (int, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.index, p.chainingHash
+}
+
+// VerifierState is a compact snapshot from which a new verifier can bootstrap
+// instead of replaying history
+type VerifierState struct {
+	AppName      string             `json:"app_name"`
+	Index        int                `json:"index"`
+	ChainingHash string             `json:"chaining_hash"`
+	Operators    []OperatorSnapshot `json:"operators"`
+	ConfigDigest string             `json:"config_digest"`
+}
+
+// OperatorSnapshot is the serializable part of an Operator
+type OperatorSnapshot struct {
+	ID         string   `json:"id"`
+	OperatorID string   `json:"operator_id"`
+	PubkeyG1_X []string `json:"pubkey_g1_x"`
+	PubkeyG1_Y []string `json:"pubkey_g1_y"`
+	PubkeyG2_X []string `json:"pubkey_g2_x"`
+	PubkeyG2_Y []string `json:"pubkey_g2_y"`
+	Socket     string   `json:"socket"`
+	Stake      float64  `json:"stake"`
+	// Addresses are the resolved addresses of the socket, only set in admin output
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// operator restores the Operator described by the snapshot
+func (s OperatorSnapshot) operator() Operator {
+	return Operator{
+		ID:         s.ID,
+		OperatorID: s.OperatorID,
+		PubkeyG1_X: s.PubkeyG1_X,
+		PubkeyG1_Y: s.PubkeyG1_Y,
+		PubkeyG2_X: s.PubkeyG2_X,
+		PubkeyG2_Y: s.PubkeyG2_Y,
+		Socket:     s.Socket,
+		Stake:      s.Stake,
+	}
+}
+
+// operatorSet restores the operators of the snapshot
+func (s VerifierState) operatorSet() map[string]Operator {
+	operators := make(map[string]Operator, len(s.Operators))
+	for _, snapshot := range s.Operators {
+		operators[snapshot.ID] = snapshot.operator()
+	}
+	return operators
+}
+
+// configDigest identifies the settings that must match between the exporting
+// and importing verifiers
+func (z *Zellular) configDigest() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%v|%s", z.AppName, z.ThresholdPercent, subgraphURL)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExportState snapshots the last released position and the operator set
+func (z *Zellular) ExportState() ([]byte, error) {
+	index, chainingHash := z.progress.get()
+	state := VerifierState{
+		AppName:      z.AppName,
+		Index:        index,
+		ChainingHash: chainingHash,
+		ConfigDigest: z.configDigest(),
+	}
+	for _, operator := range z.operators() {
+		state.Operators = append(state.Operators, OperatorSnapshot{
+			ID:         operator.ID,
+			OperatorID: operator.OperatorID,
+			PubkeyG1_X: operator.PubkeyG1_X,
+			PubkeyG1_Y: operator.PubkeyG1_Y,
+			PubkeyG2_X: operator.PubkeyG2_X,
+			PubkeyG2_Y: operator.PubkeyG2_Y,
+			Socket:     operator.Socket,
+			Stake:      operator.Stake,
+		})
+	}
+	sort.Slice(state.Operators, func(i, j int) bool {
+		return state.Operators[i].ID < state.Operators[j].ID
+	})
+	return json.Marshal(state)
+}
+
+// ImportState bootstraps from a snapshot produced by a trusted peer's
+// ExportState. The snapshot must have been taken with the same app and
+// configuration. Use Resume to continue streaming from its position.
+func (z *Zellular) ImportState(data []byte) error {
+	var state VerifierState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.ConfigDigest != z.configDigest() {
+		return fmt.Errorf("zellular: state of %s was exported with a different configuration", state.AppName)
+	}
+
+	if err := z.setOperators(state.operatorSet()); err != nil {
+		return err
+	}
+	z.progress.set(state.Index, state.ChainingHash)
+	return nil
+}
+
+// Resume returns a stream continuing from the last released or imported position
+func (z *Zellular) Resume() *BatchStream {
+	index, chainingHash := z.progress.get()
+	if index == 0 || chainingHash != "" {
+		return z.StreamFrom(index, chainingHash)
+	}
+	return z.Stream(index)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultStatsWindow is the sliding window Stats reports over
+const defaultStatsWindow = 5 * time.Minute
+
+// Stats summarizes finality throughput and latency over a sliding window
+type Stats struct {
+	Window time.Duration `json:"window"`
+	// BatchesPerSecond is the rate of finalized batches released to the application
+	BatchesPerSecond float64 `json:"batches_per_second"`
+	// AvgFinalizationLatency is the mean time from Send until the batch was finalized
+	AvgFinalizationLatency time.Duration `json:"avg_finalization_latency"`
+	// VerifyP50, VerifyP90 and VerifyP99 are signature verification time percentiles
+	VerifyP50 time.Duration `json:"verify_p50"`
+	VerifyP90 time.Duration `json:"verify_p90"`
+	VerifyP99 time.Duration `json:"verify_p99"`
+}
+
+// sample is a duration observed at a point in time
+type sample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// statsRecorder collects the samples behind Stats
+type statsRecorder struct {
+	mu            sync.Mutex
+	window        time.Duration
+	releases      []time.Time
+	latencies     []sample
+	verifications []sample
+	pending       map[string]time.Time
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{window: defaultStatsWindow, pending: make(map[string]time.Time)}
+}
+
+// submitted remembers when one of our own batches was sent
+func (r *statsRecorder) submitted(payload string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[hash(canonicalJSON(payload))] = time.Now()
+}
+
+// finalized records a released batch and, if we sent it, its finalization latency
+func (r *statsRecorder) finalized(payload string) {
+	now := time.Now()
+	// canonicalizing is the costliest step of releasing a batch, so it is
+	// skipped unless one of our own batches is awaiting finalization
+	r.mu.Lock()
+	waiting := len(r.pending) > 0
+	r.mu.Unlock()
+	key := ""
+	if waiting {
+		key = hash(canonicalJSON(payload))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.releases = append(r.releases, now)
+	if sent, ok := r.pending[key]; waiting && ok {
+		delete(r.pending, key)
+		r.latencies = append(r.latencies, sample{at: now, duration: now.Sub(sent)})
+	}
+	r.prune(now)
+}
+
+// verified records the duration of one signature verification
+func (r *statsRecorder) verified(duration time.Duration) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verifications = append(r.verifications, sample{at: now, duration: duration})
+	r.prune(now)
+}
+
+// prune drops samples that fell out of the window, and submissions that
+// never showed up within it
+func (r *statsRecorder) prune(now time.Time) {
+	cutoff := now.Add(-r.window)
+	i := sort.Search(len(r.releases), func(i int) bool { return r.releases[i].After(cutoff) })
+	r.releases = r.releases[i:]
+	r.latencies = pruneSamples(r.latencies, cutoff)
+	r.verifications = pruneSamples(r.verifications, cutoff)
+	for key, sent := range r.pending {
+		if sent.Before(cutoff) {
+			delete(r.pending, key)
+		}
+	}
+}
+
+func pruneSamples(samples []sample, cutoff time.Time) []sample {
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].at.After(cutoff) })
+	return samples[i:]
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// Stats reports throughput and latency over the last few minutes
+func (z *Zellular) Stats() Stats {
+	r := z.stats
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(time.Now())
+
+	stats := Stats{
+		Window:           r.window,
+		BatchesPerSecond: float64(len(r.releases)) / r.window.Seconds(),
+	}
+	if len(r.latencies) > 0 {
+		var total time.Duration
+		for _, s := range r.latencies {
+			total += s.duration
+		}
+		stats.AvgFinalizationLatency = total / time.Duration(len(r.latencies))
+	}
+
+	durations := make([]time.Duration, len(r.verifications))
+	for i, s := range r.verifications {
+		durations[i] = s.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.VerifyP50 = percentile(durations, 0.50)
+	stats.VerifyP90 = percentile(durations, 0.90)
+	stats.VerifyP99 = percentile(durations, 0.99)
+	return stats
+}
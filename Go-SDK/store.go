@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a KVStore for missing keys
+var ErrNotFound = errors.New("zellular: key not found")
+
+// KVStore is the pluggable persistence used for checkpoints and other
+// small pieces of client state
+type KVStore interface {
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+}
+
+// CASStore is a KVStore that can atomically replace a value, as needed for
+// leader election. old is nil when the key is expected to be absent.
+// Stores backed by etcd or a database implement it with their native
+// transactions.
+type CASStore interface {
+	KVStore
+	CompareAndSwap(key string, old, new []byte) (bool, error)
+}
+
+// MemoryKVStore keeps values in memory, for tests and ephemeral consumers
+type MemoryKVStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryKVStore creates an empty in-memory store
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{values: make(map[string][]byte)}
+}
+
+// Get returns a copy of the value of key
+func (s *MemoryKVStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+// Put stores a copy of value
+func (s *MemoryKVStore) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes key
+func (s *MemoryKVStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// CompareAndSwap sets key to new if its value is old
+func (s *MemoryKVStore) CompareAndSwap(key string, old, new []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.values[key]
+	if ok != (old != nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+	s.values[key] = append([]byte(nil), new...)
+	return true, nil
+}
+
+// FileKVStore keeps one file per key below a directory, replaced atomically on Put
+type FileKVStore struct {
+	Dir string
+}
+
+func (s FileKVStore) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// Get reads the value of key
+func (s FileKVStore) Get(key string) ([]byte, error) {
+	value, err := ioutil.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Put durably writes value
+func (s FileKVStore) Put(key string, value []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Delete removes key
+func (s FileKVStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// staleLock is how old a FileKVStore lock file must be before it is
+// assumed to belong to a crashed process
+const staleLock = 10 * time.Second
+
+// lockRetry is the wait between attempts to take a busy FileKVStore lock
+const lockRetry = 5 * time.Millisecond
+
+// CompareAndSwap sets key to new if its value is old. Processes sharing
+// the directory serialise on a lock file next to the key; a busy lock is
+// waited for, and broken once stale, rather than reported as a mismatch.
+func (s FileKVStore) CompareAndSwap(key string, old, new []byte) (bool, error) {
+	lock := s.path(key) + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0o755); err != nil {
+		return false, err
+	}
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			if info, statErr := os.Stat(lock); statErr == nil && time.Since(info.ModTime()) > staleLock {
+				os.Remove(lock)
+				continue
+			}
+			time.Sleep(lockRetry)
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		file.Close()
+		break
+	}
+	defer os.Remove(lock)
+
+	current, err := s.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		current, err = nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if (current != nil) != (old != nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+	return true, s.Put(key, new)
+}
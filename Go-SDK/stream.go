@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// batchSequence numbers the batches streams release. It starts from the
+// wall clock in nanoseconds, so numbers keep increasing across restarts
+// unless the clock goes back.
+var batchSequence = uint64(time.Now().UnixNano())
+
+// Batch is a finalized batch together with its index
+type Batch struct {
+	Index   int
+	Payload string
+	// ChainingHash is the chaining hash after this batch, empty when the
+	// stream was started without a known chaining hash and no node has
+	// reported one since
+	ChainingHash string
+	// FinalizedAt is the finalization time reported with the page this batch
+	// came in, zero if the node did not report one
+	FinalizedAt time.Time
+	// ReceivedAt is when the client received the page holding the batch
+	ReceivedAt time.Time
+	// Sequence increases with every batch any stream of the process
+	// releases, so consumers can tell a redelivery after a restart from
+	// the original delivery and order deliveries across streams
+	Sequence uint64
+	// Node is the node that served the batch
+	Node string
+	// Proof is set on the batch the node's finalization signature covers
+	Proof *FinalityProof
+}
+
+// DeliveryLatency is the time from finalization to the client receiving the
+// batch, zero if the node did not report a finalization time
+func (b Batch) DeliveryLatency() time.Duration {
+	if b.FinalizedAt.IsZero() || b.ReceivedAt.IsZero() {
+		return 0
+	}
+	return b.ReceivedAt.Sub(b.FinalizedAt)
+}
+
+// BatchStream pulls finalized batches on demand. Nothing is fetched until
+// the caller asks for it, and of every page fetched over HTTP only the
+// first BufferSize batches are decoded, the rest being read and dropped as
+// the response streams in. Pages served over P2P are decoded whole before
+// they are cut to BufferSize. Pause stops the stream from handing out or
+// fetching batches until Resume, keeping its position and buffer.
+//
+// Unless the client was created WithoutVerification, a batch is handed out
+// only once the finalization a node signed for it or a later batch has
+// been verified: the signed batch's hash, its chaining hash when the stream
+// knows the chain, and the aggregated signature. Batches waiting for a
+// signed batch are held in addition to the buffer, and as a page carries
+// the signature of the batch finalized last, a stream replaying history
+// holds every batch it fetches until it reaches that batch. Memory is thus
+// bounded by BufferSize only once the stream has caught up.
+type BatchStream struct {
+	pauseGate
+
+	// BufferSize caps the number of batches kept between fetches
+	BufferSize int
+	// PollInterval is the wait between polls once the stream has caught up
+	PollInterval time.Duration
+	// Filter, when set, drops batches it does not select
+	Filter BatchFilter
+
+	z      *Zellular
+	after  int
+	chain  verifiedChain
+	buffer []Batch
+	head   int
+	// confirming holds verified batches within the confirmation depth of
+	// the latest finalized index
+	confirming []Batch
+	// released and releasedHash are the index and chaining hash of the
+	// last batch Next returned
+	released     int
+	releasedHash string
+}
+
+// Stream returns a stream of the finalized batches following index after.
+// Chaining hashes are known from the beginning, or once a node reports
+// one; use StreamFrom to resume with a known chaining hash.
+func (z *Zellular) Stream(after int) *BatchStream {
+	return z.StreamFrom(after, "")
+}
+
+// StreamFrom returns a stream resuming after index after whose chaining
+// hash is chainingHash, empty if unknown
+func (z *Zellular) StreamFrom(after int, chainingHash string) *BatchStream {
+	return &BatchStream{
+		BufferSize:   256,
+		PollInterval: time.Second,
+		z:            z,
+		after:        after,
+		chain:        newVerifiedChain(z, after, chainingHash),
+		released:     after,
+		releasedHash: chainingHash,
+	}
+}
+
+// Next returns the next finalized batch, blocking until one is available or ctx is done
+func (s *BatchStream) Next(ctx context.Context) (Batch, error) {
+	if err := s.wait(ctx); err != nil {
+		return Batch{}, err
+	}
+	for s.head == len(s.buffer) {
+		fetched := s.after
+		if err := s.fill(ctx); err != nil {
+			return Batch{}, err
+		}
+		if s.head < len(s.buffer) {
+			break
+		}
+		if s.after != fetched {
+			// the page only held batches awaiting a signed one or
+			// still within the confirmation depth
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return Batch{}, ctx.Err()
+		case <-time.After(s.PollInterval):
+		}
+	}
+
+	batch := s.buffer[s.head]
+	if err := s.z.checkReorg(ctx, batch); err != nil {
+		return Batch{}, err
+	}
+	if s.z.wal != nil {
+		if err := s.z.wal.record(s.z.AppName, s.z.batchHash(batch.Payload), batch); err != nil {
+			return Batch{}, err
+		}
+	}
+	batch.Sequence = atomic.AddUint64(&batchSequence, 1)
+	s.released, s.releasedHash = batch.Index, batch.ChainingHash
+	s.buffer[s.head] = Batch{}
+	s.head++
+	s.z.progress.set(batch.Index, batch.ChainingHash)
+	s.z.stats.finalized(batch.Payload)
+	return batch, nil
+}
+
+// Position returns the index of the last batch handed out by Next or
+// passed over by the Filter
+func (s *BatchStream) Position() int {
+	if s.head < len(s.buffer) {
+		return s.buffer[s.head].Index - 1
+	}
+	if len(s.confirming) > 0 {
+		return s.confirming[0].Index - 1
+	}
+	if len(s.chain.held) > 0 {
+		return s.chain.held[0].Index - 1
+	}
+	return s.after
+}
+
+// fill fetches the next page, decoding no more than BufferSize batches of it
+func (s *BatchStream) fill(ctx context.Context) error {
+	s.buffer, s.head = s.buffer[:0], 0
+	page, err := s.z.fetchFinalizedLimit(ctx, s.after, s.BufferSize)
+	if err != nil || page == nil {
+		s.confirm()
+		return err
+	}
+	received := time.Now()
+
+	for i, payload := range page.Batches {
+		s.after++
+		batch := Batch{Index: s.after, Payload: payload, Node: page.node, ReceivedAt: received}
+		if page.Finalized != nil && page.Finalized.Timestamp > 0 {
+			batch.FinalizedAt = time.Unix(page.Finalized.Timestamp, 0)
+		}
+		if page.Finalized != nil && page.Finalized.Index == s.after {
+			batch.Proof = page.Finalized.proof(s.z.AppName)
+		}
+		first := ""
+		if i == 0 {
+			first = page.FirstChainingHash
+		}
+		verified, err := s.chain.add(batch, first)
+		if err != nil {
+			return err
+		}
+		s.confirming = append(s.confirming, verified...)
+	}
+	s.confirm()
+	return nil
+}
+
+// confirm moves the verified batches at least the confirmation depth behind
+// the latest finalized index into the buffer
+func (s *BatchStream) confirm() {
+	confirmed := len(s.confirming)
+	if depth := s.z.confirmationDepth; depth > 0 {
+		latest := s.z.progress.latest()
+		confirmed = 0
+		for confirmed < len(s.confirming) && s.confirming[confirmed].Index <= latest-depth {
+			confirmed++
+		}
+	}
+	for _, batch := range s.confirming[:confirmed] {
+		if s.Filter != nil && !s.Filter(batch) {
+			continue
+		}
+		s.buffer = append(s.buffer, batch)
+	}
+	s.confirming = append(s.confirming[:0], s.confirming[confirmed:]...)
+}
+
+// verifiedChain chains batches in order and holds them back until a batch
+// carrying a finality proof verifies them, unless the client skips
+// verification. Once anchored, at index 0 or by a known or reported
+// chaining hash, the proof's chaining hash covers every held batch;
+// before that only the signed batch itself is checked.
+type verifiedChain struct {
+	z            *Zellular
+	chainingHash string
+	anchored     bool
+	held         []Batch
+}
+
+// newVerifiedChain starts a chain after index after whose chaining hash is
+// chainingHash, empty if unknown
+func newVerifiedChain(z *Zellular, after int, chainingHash string) verifiedChain {
+	return verifiedChain{z: z, chainingHash: chainingHash, anchored: after == 0 || chainingHash != ""}
+}
+
+// add chains the next batch and returns the batches it verifies. first is
+// the chaining hash a node reported for this batch, if any.
+func (c *verifiedChain) add(batch Batch, first string) ([]Batch, error) {
+	if c.anchored {
+		c.chainingHash = c.z.chain(c.chainingHash, batch.Payload)
+		if first != "" && first != c.chainingHash {
+			c.z.observeBadData(batch.Node)
+			return nil, fmt.Errorf("zellular: batch %d does not chain to the batches before it", batch.Index)
+		}
+	} else if first != "" {
+		c.chainingHash, c.anchored = first, true
+	}
+	if c.anchored {
+		batch.ChainingHash = c.chainingHash
+	}
+	c.held = append(c.held, batch)
+
+	if !c.z.skipVerify {
+		if batch.Proof == nil || batch.Proof.Index != batch.Index {
+			return nil, nil
+		}
+		if err := c.z.verifyProven(batch, c.anchored); err != nil {
+			return nil, err
+		}
+		if !c.anchored {
+			c.chainingHash, c.anchored = batch.Proof.ChainingHash, true
+			c.held[len(c.held)-1].ChainingHash = c.chainingHash
+		}
+	}
+	verified := c.held
+	c.held = nil
+	return verified, nil
+}
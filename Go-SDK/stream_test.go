@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sandboxWith(t *testing.T, payloads []string, opts ...Option) (*Zellular, *Sandbox) {
+	t.Helper()
+	z, sandbox, err := NewSandbox("app", 0, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range payloads {
+		if status := sandbox.submit([]byte(payload)); status != 200 {
+			t.Fatalf("sandbox rejected %s: %d", payload, status)
+		}
+	}
+	return z, sandbox
+}
+
+func TestStreamVerifiesBatches(t *testing.T) {
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`}
+	z, _ := sandboxWith(t, payloads)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := z.Stream(0)
+	chainingHash := ""
+	for i, payload := range payloads {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chainingHash = z.chain(chainingHash, payload)
+		if batch.Index != i+1 || batch.Payload != payload || batch.ChainingHash != chainingHash {
+			t.Fatalf("batch %d = %+v", i+1, batch)
+		}
+	}
+}
+
+func TestStreamRejectsTamperedBatch(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	sandbox.batches[1].payload = `["x"]`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := z.Stream(0).Next(ctx)
+	if err == nil || !strings.Contains(err.Error(), "chaining hash of batch 3") {
+		t.Fatalf("tampered batch was not rejected: %v", err)
+	}
+}
+
+func TestStreamWithoutVerification(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`}, WithoutVerification())
+	sandbox.batches[1].payload = `["x"]`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := z.Stream(0)
+	if _, err := stream.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := stream.Next(ctx)
+	if err != nil || batch.Payload != `["x"]` {
+		t.Fatalf("Next = %+v, %v", batch, err)
+	}
+}
+
+func TestStreamFromUnknownChainingHash(t *testing.T) {
+	z, _ := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// resuming without the chaining hash anchors the chain at the one the
+	// node reports for the first batch of the page
+	stream := z.Stream(1)
+	if _, err := stream.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := stream.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := z.chain(z.chain(z.chain("", `["a"]`), `["b"]`), `["c"]`)
+	if batch.Index != 3 || batch.ChainingHash != want {
+		t.Fatalf("batch = %+v", batch)
+	}
+}
+
+func TestDecodePageKeepsLimit(t *testing.T) {
+	body := `{"data": {"batches": ["a", "b", "c"], "first_chaining_hash": "h", "extra": 1}}`
+	page, err := (&Zellular{}).decodePage("url", strings.NewReader(body), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Batches) != 2 || page.Batches[1] != "b" || page.FirstChainingHash != "h" {
+		t.Fatalf("page = %+v", page)
+	}
+
+	_, err = (&Zellular{strict: true}).decodePage("url", strings.NewReader(body), 2)
+	if _, ok := err.(*SchemaError); !ok {
+		t.Fatalf("strict decoding accepted an unknown field: %v", err)
+	}
+
+	page, err = (&Zellular{}).decodePage("url", strings.NewReader(`{"data": null}`), 2)
+	if err != nil || page != nil {
+		t.Fatalf("null data = %+v, %v", page, err)
+	}
+}
+
+func TestStreamHoldsBackConfirmationDepth(t *testing.T) {
+	payloads := []string{`["a"]`, `["b"]`, `["c"]`, `["d"]`, `["e"]`}
+	z, sandbox := sandboxWith(t, payloads, WithConfirmationDepth(2))
+	stream := z.Stream(0)
+	stream.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 1; i <= 3; i++ {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batch.Index != i || batch.Payload != payloads[i-1] {
+			t.Fatalf("batch %d = %+v", i, batch)
+		}
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if batch, err := stream.Next(short); err == nil {
+		t.Fatalf("batch %d released within the confirmation depth", batch.Index)
+	}
+	if position := stream.Position(); position != 3 {
+		t.Fatalf("position = %d", position)
+	}
+
+	if status := sandbox.submit([]byte(`["f"]`)); status != 200 {
+		t.Fatalf("sandbox rejected a batch: %d", status)
+	}
+	batch, err := stream.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Index != 4 || batch.Payload != payloads[3] {
+		t.Fatalf("batch 4 = %+v", batch)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// querySubgraph posts a GraphQL query with variables to the registry
+// subgraph and decodes its data into out
+func querySubgraph(ctx context.Context, t *transport, query string, variables map[string]interface{}, out interface{}) error {
+	request, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	resp, err := t.do(ctx, http.MethodPost, subgraphURL, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("zellular: subgraph query failed: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(response.Data, out)
+}
+
+// weiToEther converts a decimal wei amount as returned by the subgraph
+func weiToEther(wei string) (float64, error) {
+	amount, ok := new(big.Float).SetString(wei)
+	if !ok {
+		return 0, fmt.Errorf("zellular: invalid amount %q", wei)
+	}
+	ether, _ := new(big.Float).Quo(amount, big.NewFloat(1e18)).Float64()
+	return ether, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOperatorDecodesWeiStake(t *testing.T) {
+	var response QueryResponse
+	body := `{"data": {"operators": [{"id": "0x01", "stake": "2500000000000000000"}, {"id": "0x02", "stake": 3}]}}`
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+	operators := response.Data.Operators
+	if len(operators) != 2 || operators[0].ID != "0x01" || operators[0].Stake != 2.5 || operators[1].Stake != 3 {
+		t.Fatalf("operators = %+v", operators)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestStakesAtPagesByID(t *testing.T) {
+	var cursors []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return nil, err
+		}
+		after := request.Variables["after"].(string)
+		cursors = append(cursors, after)
+
+		// the first page is full, the second holds the latest update of 0xa
+		var entities []string
+		if after == "" {
+			for i := 0; i < subgraphPageSize; i++ {
+				entities = append(entities, fmt.Sprintf(`{"id": "%04d", "operator": "0xa", "stake": "%d000000000000000000", "blockNumber": "%d", "blockTimestamp": "1"}`, i, i, 10+i))
+			}
+		} else {
+			entities = append(entities, `{"id": "9999", "operator": "0xa", "stake": "7000000000000000000", "blockNumber": "5000", "blockTimestamp": "1"}`)
+		}
+		body := `{"data": {"operatorStakeUpdates": [` + strings.Join(entities, ",") + `]}}`
+		return &http.Response{StatusCode: 200, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+
+	z := newZellular("app", "", 67, WithHTTPClient(client))
+	stakes, err := z.StakesAt(context.Background(), time.Unix(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cursors) != 2 || cursors[0] != "" || cursors[1] != fmt.Sprintf("%04d", subgraphPageSize-1) {
+		t.Fatalf("cursors = %v", cursors)
+	}
+	if stakes["0xa"] != 7 {
+		t.Fatalf("stake of 0xa = %v, want the latest update", stakes["0xa"])
+	}
+}
+
+func TestFetchOperatorsKeepsTransportsApart(t *testing.T) {
+	second := make(chan struct{})
+	respond := func(req *http.Request) *http.Response {
+		body := `{"data": {"operators": []}}`
+		return &http.Response{StatusCode: 200, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body)), Request: req}
+	}
+
+	// the first request is held until the second transport sends its own
+	first := newTransport()
+	first.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-second:
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("the second transport shared the first request")
+		}
+		return respond(req), nil
+	})}
+	other := newTransport()
+	other.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		close(second)
+		return respond(req), nil
+	})}
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := fetchOperators(first)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_, err := fetchOperators(other)
+		errs <- err
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tailCommand implements: zellular tail [-node URL] [-app APP] [-after N] [-ui] [-format F] [-query Q]
+func tailCommand(args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	node := flags.String("node", "", "base URL of the node, a random operator if empty")
+	appName := flags.String("app", "simple_app", "app name")
+	after := flags.Int("after", 0, "index to start after")
+	ui := flags.Bool("ui", false, "show a live dashboard instead of printing batches")
+	format := flags.String("format", "raw", "output format: json, raw or table")
+	query := flags.String("query", "", "jq-like path selecting values from each payload, e.g. .[].amount")
+	flags.Parse(args)
+	printer, err := newBatchPrinter(*format, *query)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	z, err := cliClient(*appName, *node)
+	if err != nil {
+		return err
+	}
+	stream := z.Stream(*after)
+	if *ui {
+		return runDashboard(ctx, z, stream)
+	}
+	for {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := printer.print(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// getCommand implements: zellular get [-node URL] [-app APP] [-format F] [-query Q] INDEX
+func getCommand(args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	node := flags.String("node", "", "base URL of the node, a random operator if empty")
+	appName := flags.String("app", "simple_app", "app name")
+	format := flags.String("format", "raw", "output format: json, raw or table")
+	query := flags.String("query", "", "jq-like path selecting values from the payload")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("get: a batch index is required")
+	}
+	index, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("get: invalid index %q", flags.Arg(0))
+	}
+	printer, err := newBatchPrinter(*format, *query)
+	if err != nil {
+		return err
+	}
+
+	z, err := cliClient(*appName, *node)
+	if err != nil {
+		return err
+	}
+	inclusion, err := z.GetBatch(context.Background(), index)
+	if err != nil {
+		return err
+	}
+	return printer.print(inclusion.Batch)
+}
+
+// cliClient creates a client for node, picking a random operator when node is empty
+func cliClient(appName, node string) (*Zellular, error) {
+	if node == "" {
+		operators, err := getOperators()
+		if err != nil {
+			return nil, err
+		}
+		node = operators[randomOperator(operators)].Socket
+	}
+	return NewZellular(appName, node, 67), nil
+}
+
+// dashboardRows is how many recent batches the dashboard lists
+const dashboardRows = 10
+
+// dashboard is the state shown by the tail UI
+type dashboard struct {
+	mu         sync.Mutex
+	recent     []Batch
+	statuses   map[int]string
+	verified   int
+	failed     int
+	operators  map[string]int
+	operatorAt time.Time
+	err        error
+}
+
+// runDashboard tails stream and redraws a terminal dashboard until ctx is done
+func runDashboard(ctx context.Context, z *Zellular, stream *BatchStream) error {
+	d := &dashboard{statuses: make(map[int]string)}
+	go d.follow(ctx, z, stream)
+	go d.watchOperators(ctx, z)
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		d.render(z)
+		select {
+		case <-ctx.Done():
+			fmt.Print("\x1b[?25h\n")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// follow pulls batches and verifies the finality proofs among them
+func (d *dashboard) follow(ctx context.Context, z *Zellular, stream *BatchStream) {
+	for {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			d.mu.Lock()
+			d.err = err
+			d.mu.Unlock()
+			return
+		}
+		status := ""
+		if batch.Proof != nil {
+			result, err := z.VerifyProof(*batch.Proof)
+			switch {
+			case err != nil:
+				status = "error: " + err.Error()
+			case result.Valid():
+				status = "verified"
+			default:
+				status = result.Status.String()
+			}
+		}
+
+		d.mu.Lock()
+		if status == "verified" {
+			d.verified++
+		} else if status != "" {
+			d.failed++
+		}
+		d.statuses[batch.Index] = status
+		d.recent = append(d.recent, batch)
+		if len(d.recent) > dashboardRows {
+			delete(d.statuses, d.recent[0].Index)
+			d.recent = d.recent[1:]
+		}
+		d.mu.Unlock()
+	}
+}
+
+// watchOperators refreshes the operators' last finalized indexes
+func (d *dashboard) watchOperators(ctx context.Context, z *Zellular) {
+	for {
+		indexes, _ := z.LastFinalizedAll(ctx)
+		d.mu.Lock()
+		d.operators, d.operatorAt = indexes, time.Now()
+		d.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// render redraws the whole screen
+func (d *dashboard) render(z *Zellular) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("\x1b[?25l\x1b[H\x1b[2J")
+	index, _ := z.progress.get()
+	latest := z.progress.latest()
+	fmt.Fprintf(&b, "zellular tail  app %s  node %s\n\n", z.AppName, z.base())
+	fmt.Fprintf(&b, "index %d  finalized %d  lag %d  proofs verified %d  failed %d\n\n",
+		index, latest, latest-index, d.verified, d.failed)
+
+	b.WriteString("recent batches\n")
+	for i := len(d.recent) - 1; i >= 0; i-- {
+		batch := d.recent[i]
+		fmt.Fprintf(&b, "  %8d  %-12s %s\n", batch.Index, d.statuses[batch.Index], truncate(batch.Payload, 60))
+	}
+
+	fmt.Fprintf(&b, "\noperators (as of %s)\n", d.operatorAt.Format(time.TimeOnly))
+	highest := 0
+	ids := make([]string, 0, len(d.operators))
+	for id, index := range d.operators {
+		ids = append(ids, id)
+		if index > highest {
+			highest = index
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(&b, "  %s  %8d  behind %d\n", id, d.operators[id], highest-d.operators[id])
+	}
+	if unreachable := len(z.operators()) - len(d.operators); unreachable > 0 && !d.operatorAt.IsZero() {
+		fmt.Fprintf(&b, "  %d operators unreachable\n", unreachable)
+	}
+	if d.err != nil {
+		fmt.Fprintf(&b, "\nstream stopped: %v\n", d.err)
+	}
+	fmt.Print(b.String())
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TenantConfig describes one tenant of a multi-tenant process
+type TenantConfig struct {
+	Name string
+	// Store holds the tenant's checkpoints and other state, namespaced
+	// under "tenants/<name>/"; tenants may share one store
+	Store KVStore
+	// ReadCredential and SubmitCredential replace the client's credentials
+	// for the tenant's requests, keeping reads and submissions apart as
+	// WithReadCredential and WithSubmitCredential do
+	ReadCredential   *Credential
+	SubmitCredential *Credential
+	// SendRate caps the batches per second the tenant submits across its
+	// apps, unlimited when zero
+	SendRate float64
+	// SendBurst is the number of batches that may be sent at once within SendRate
+	SendBurst int
+}
+
+// Tenant is a logical user of a shared client. Its app clients share the
+// operator registry and connection pool of the client it was created from,
+// but keep their own checkpoints, metrics labels, send rate, credentials,
+// event journal and write-ahead log entries.
+type Tenant struct {
+	TenantConfig
+
+	z         *Zellular
+	transport *transport
+	store     KVStore
+	limiter   *tokenBucket
+	mu        sync.Mutex
+	apps      map[string]*Zellular
+}
+
+// Tenant returns a tenant of z configured by cfg
+func (z *Zellular) Tenant(cfg TenantConfig) *Tenant {
+	t := &Tenant{TenantConfig: cfg, z: z, apps: make(map[string]*Zellular)}
+	tr := *z.transport
+	if cfg.ReadCredential != nil {
+		tr.credentials.read = cfg.ReadCredential
+	}
+	if cfg.SubmitCredential != nil {
+		tr.credentials.submit = cfg.SubmitCredential
+	}
+	t.transport = &tr
+	if cfg.Store != nil {
+		t.store = prefixedStore{store: cfg.Store, prefix: "tenants/" + cfg.Name + "/"}
+	}
+	if cfg.SendRate > 0 {
+		t.limiter = newTokenBucket(cfg.SendRate, cfg.SendBurst)
+	}
+	return t
+}
+
+// Client returns the tenant's client of appName
+func (t *Tenant) Client(appName string) *Zellular {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.apps[appName]; ok {
+		return c
+	}
+	c := t.z.forApp(appName)
+	c.transport = t.transport
+	c.tenant = t.Name
+	c.sendLimiter = t.limiter
+	t.apps[appName] = c
+	return c
+}
+
+// Store returns the tenant's namespaced view of its store, nil without one
+func (t *Tenant) Store() KVStore {
+	return t.store
+}
+
+// NewConsumer creates a consumer of the tenant's app checkpointing into the tenant's store
+func (t *Tenant) NewConsumer(appName string, handler Handler) *Consumer {
+	return t.Client(appName).NewConsumer(t.store, handler)
+}
+
+// prefixedStore confines keys to a namespace of a shared KVStore
+type prefixedStore struct {
+	store  KVStore
+	prefix string
+}
+
+func (s prefixedStore) Get(key string) ([]byte, error) {
+	return s.store.Get(s.prefix + key)
+}
+
+func (s prefixedStore) Put(key string, value []byte) error {
+	return s.store.Put(s.prefix+key, value)
+}
+
+func (s prefixedStore) Delete(key string) error {
+	return s.store.Delete(s.prefix + key)
+}
+
+// tokenBucket paces an operation to rate per second with bursts of burst
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
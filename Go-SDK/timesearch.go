@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrNoTimestamps is returned when the node does not report finalization times
+var ErrNoTimestamps = errors.New("zellular: node does not report finalization timestamps")
+
+// FindIndexAtTime returns the first finalized index whose finalization time
+// is at or after t, so a replay from t can start with Stream(index-1). The
+// time of an index is the finalization time the node reports with the page
+// starting at it, as in Batch.FinalizedAt. If everything was finalized
+// before t, the index after the last finalized batch is returned.
+func (z *Zellular) FindIndexAtTime(ctx context.Context, t time.Time) (int, error) {
+	last, err := z.fetchLastFinalized(ctx, z.base())
+	if err != nil {
+		return 0, err
+	}
+
+	var searchErr error
+	offset := sort.Search(last.Index, func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		at, err := z.finalizedAt(ctx, i+1)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return !at.Before(t)
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	return offset + 1, nil
+}
+
+// finalizedAt returns the finalization time reported with the page starting at index
+func (z *Zellular) finalizedAt(ctx context.Context, index int) (time.Time, error) {
+	page, err := z.fetchFinalized(ctx, index-1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if page == nil || len(page.Batches) == 0 {
+		return time.Time{}, fmt.Errorf("zellular: batch %d is not finalized", index)
+	}
+	if page.Finalized == nil || page.Finalized.Timestamp == 0 {
+		return time.Time{}, ErrNoTimestamps
+	}
+	return time.Unix(page.Finalized.Timestamp, 0), nil
+}
+
+// FetchBetween calls fn with the batches finalized at or after from and
+// before to, in index order. Each batch is handed out only once the
+// chaining hash from it to the next finalization proof matches that proof
+// and the proof's signature verifies, so the stream may read past to to
+// reach the proof covering the last batch of the window.
+func (z *Zellular) FetchBetween(ctx context.Context, from, to time.Time, fn func(Batch) error) error {
+	start, err := z.FindIndexAtTime(ctx, from)
+	if err != nil {
+		return err
+	}
+	end, err := z.FindIndexAtTime(ctx, to)
+	if err != nil {
+		return err
+	}
+	last := end - 1
+	if last < start {
+		return nil
+	}
+
+	anchor := ""
+	if start > 1 {
+		// the page after start-2 opens with the chaining hash of batch start-1
+		page, err := z.fetchFinalized(ctx, start-2)
+		if err != nil {
+			return err
+		}
+		if page == nil {
+			return fmt.Errorf("zellular: batch %d is not finalized", start-1)
+		}
+		anchor = page.FirstChainingHash
+	}
+
+	stream := z.StreamFrom(start-1, anchor)
+	var pending []Batch
+	for {
+		batch, err := stream.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if batch.Index <= last {
+			pending = append(pending, batch)
+		}
+		if batch.Proof == nil {
+			continue
+		}
+		if batch.Proof.ChainingHash != batch.ChainingHash {
+			return fmt.Errorf("zellular: chaining hash of batch %d does not match its proof", batch.Index)
+		}
+		result, err := z.VerifyProof(*batch.Proof)
+		if err != nil {
+			return err
+		}
+		if !result.Valid() {
+			return fmt.Errorf("zellular: proof of batch %d failed verification: %s", batch.Index, result.Reason)
+		}
+		for _, verified := range pending {
+			if err := fn(verified); err != nil {
+				return err
+			}
+		}
+		pending = pending[:0]
+		if batch.Index >= last {
+			return nil
+		}
+	}
+}
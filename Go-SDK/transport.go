@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// SDKVersion is the version of this SDK reported to nodes and the subgraph
+const SDKVersion = "0.1.0"
+
+// DefaultUserAgent identifies SDK traffic to operators
+var DefaultUserAgent = "zellular-go-sdk/" + SDKVersion + " (" + runtime.Version() + ")"
+
+// transport sends every outgoing HTTP request of the SDK, stamping it with
+// the User-Agent and any headers supplied by the caller
+type transport struct {
+	client    *http.Client
+	userAgent string
+	headers   http.Header
+	backoff   Backoff
+	latency   *latencyEstimate
+	sessions  *sessions
+	capture   *exchangeLog
+	// credentials are set per request after the caller headers
+	credentials credentials
+	// onRetry, when set, is called before a throttled request is retried
+	onRetry func(url string, attempt int, delay time.Duration)
+}
+
+// defaultTransport is used by package level helpers such as getOperators
+var defaultTransport = newTransport()
+
+func newTransport() *transport {
+	return &transport{
+		client:    http.DefaultClient,
+		userAgent: DefaultUserAgent,
+		headers:   make(http.Header),
+		backoff:   DefaultBackoff,
+		latency:   &latencyEstimate{},
+	}
+}
+
+// do sends a request with the configured User-Agent and headers. Throttled
+// requests (429/503) are retried following Retry-After or the backoff
+// schedule, as long as another attempt can finish before ctx's deadline.
+// A PUT submits a batch, which a node may have accepted despite the status,
+// so it is retried only when it carries an idempotency key.
+func (t *transport) do(ctx context.Context, method, url, contentType string, body io.Reader) (resp *http.Response, err error) {
+	ctx, cancel, arm := withTimeout(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+		} else {
+			arm(resp)
+		}
+	}()
+
+	req, err := t.newRequest(ctx, method, url, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	t.sessions.apply(req)
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := t.client.Do(req)
+		if t.capture != nil {
+			t.capture.record(req, resp, err, start)
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.latency.observe(time.Since(start))
+		observeRateLimit(req.URL.Host, resp)
+		t.sessions.capture(req.URL.Host, resp)
+		replayable := req.Body == nil || req.GetBody != nil
+		if req.Method == http.MethodPut && callOptions(ctx).IdempotencyKey == "" {
+			replayable = false
+		}
+		if !throttled(resp) || !replayable || attempt >= t.backoff.MaxAttempts {
+			return resp, nil
+		}
+
+		delay := t.backoff.delay(attempt, parseRetryAfter(resp.Header, time.Now()))
+		resp.Body.Close()
+		if err := checkBudget(ctx, delay, t.latency.get(), attempt); err != nil {
+			return nil, err
+		}
+		if t.onRetry != nil {
+			t.onRetry(url, attempt, delay)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// newRequest builds a request carrying the SDK User-Agent and caller headers
+func (t *transport) newRequest(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range t.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	t.credentials.apply(ctx, req)
+	req.Header.Set("User-Agent", t.userAgent)
+	if priority := callOptions(ctx).Priority.header(); priority != "" {
+		req.Header.Set("Priority", priority)
+	}
+	if key := callOptions(ctx).IdempotencyKey; key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+func (t *transport) get(url string) (*http.Response, error) {
+	return t.do(context.Background(), http.MethodGet, url, "", nil)
+}
+
+func (t *transport) post(url, contentType string, body io.Reader) (*http.Response, error) {
+	return t.do(context.Background(), http.MethodPost, url, contentType, body)
+}
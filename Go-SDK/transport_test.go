@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransportRetriesPutsOnlyWithIdempotencyKey(t *testing.T) {
+	calls := 0
+	tr := newTransport()
+	tr.backoff = Backoff{MaxAttempts: 3, Initial: time.Millisecond}
+	tr.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})}
+
+	for _, c := range []struct {
+		key   string
+		calls int
+	}{{"", 1}, {"batch-1", 3}} {
+		calls = 0
+		ctx := WithCallOptions(context.Background(), CallOptions{IdempotencyKey: c.key})
+		resp, err := tr.do(ctx, http.MethodPut, "http://node/node/app/batches", "application/json", strings.NewReader(`["a"]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if calls != c.calls {
+			t.Fatalf("key %q: %d attempts, want %d", c.key, calls, c.calls)
+		}
+	}
+}
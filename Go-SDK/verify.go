@@ -0,0 +1,51 @@
+package main
+
+// VerificationStatus is the outcome of a signature verification
+type VerificationStatus int
+
+const (
+	// Verified means the signature is valid and the threshold was met
+	Verified VerificationStatus = iota
+	// InvalidSignature means the signature is malformed or does not match
+	InvalidSignature
+	// ThresholdNotMet means the signers do not satisfy the quorum policy,
+	// by default that they hold too little stake
+	ThresholdNotMet
+	// UnknownNonsigner means a nonsigner is not in the operator set or is repeated
+	UnknownNonsigner
+)
+
+func (s VerificationStatus) String() string {
+	switch s {
+	case Verified:
+		return "verified"
+	case InvalidSignature:
+		return "invalid signature"
+	case ThresholdNotMet:
+		return "threshold not met"
+	case UnknownNonsigner:
+		return "unknown nonsigner"
+	default:
+		return "unknown"
+	}
+}
+
+// VerificationResult tells whether and why a signature was accepted
+type VerificationResult struct {
+	Status VerificationStatus
+	// Reason details a failure
+	Reason string
+	// Quorum is the stake breakdown the signature was checked against
+	Quorum QuorumReport
+}
+
+// Valid reports whether the signature was accepted
+func (r VerificationResult) Valid() bool {
+	return r.Status == Verified
+}
+
+func (r VerificationResult) fail(status VerificationStatus, reason string) VerificationResult {
+	r.Status = status
+	r.Reason = reason
+	return r
+}
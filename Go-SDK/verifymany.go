@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// pendingProof is a proof that passed the non-cryptographic checks and
+// awaits the aggregate pairing check
+type pendingProof struct {
+	index     int
+	signature bn254.G1Affine
+	hashed    bn254.G1Affine
+	signers   string
+}
+
+// VerifyMany verifies many proofs with one aggregate check. Each signature
+// and message hash is weighted by a random 128-bit scalar, the weighted
+// hashes are summed per signer set, and a single multi-pairing of one
+// pairing per distinct signer set plus one for the signatures replaces two
+// pairings per proof. If the aggregate check fails the proofs are verified
+// one by one so the results pinpoint the bad ones. Proofs of batches signed
+// with keys that have since rotated are always verified one by one, against
+// the keys valid at their index.
+func (z *Zellular) VerifyMany(proofs []FinalityProof) ([]VerificationResult, error) {
+	results := make([]VerificationResult, len(proofs))
+	var pending []pendingProof
+	var individually []int
+	operators := z.operators()
+	for i, proof := range proofs {
+		message := proof.Message()
+		if z.foreignProof(proof) != "" || z.keys.differsAt(proof.Index, operators) || z.ValidateNonsigners(proof.Nonsigners) != nil ||
+			z.quorumPolicy().Evaluate(z.quorumInput(message, proof.Nonsigners)) != nil {
+			individually = append(individually, i)
+			continue
+		}
+		signature, err := decodeSignature(proof.Signature)
+		if err != nil {
+			individually = append(individually, i)
+			continue
+		}
+		hashed := z.messagePoint([]byte(hash(message)))
+		pending = append(pending, pendingProof{index: i, signature: signature, hashed: hashed, signers: signerSetKey(proof.Nonsigners)})
+	}
+
+	if len(pending) > 0 {
+		start := time.Now()
+		z.verifySlots <- struct{}{}
+		valid, err := z.checkAggregate(pending, proofs)
+		<-z.verifySlots
+		z.stats.verified(time.Since(start))
+		if err != nil {
+			return results, err
+		}
+		for _, p := range pending {
+			if !valid {
+				individually = append(individually, p.index)
+				continue
+			}
+			proof := proofs[p.index]
+			results[p.index].Quorum = z.CheckQuorum(proof.Nonsigners)
+			z.audit(AuditRecord{
+				MessageHash:    hash(proof.Message()),
+				Signature:      proof.Signature,
+				Nonsigners:     proof.Nonsigners,
+				TotalStake:     results[p.index].Quorum.TotalStake,
+				NonsignerStake: results[p.index].Quorum.NonsignerStake,
+				Accepted:       true,
+				Reason:         "aggregate verification",
+			})
+		}
+	}
+
+	sort.Ints(individually)
+	for _, i := range individually {
+		var err error
+		if results[i], err = z.VerifyProof(proofs[i]); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// checkAggregate runs the randomized aggregate pairing check over pending proofs
+func (z *Zellular) checkAggregate(pending []pendingProof, proofs []FinalityProof) (bool, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	var signatures bn254.G1Jac
+	hashed := make(map[string]*bn254.G1Jac)
+	nonsigners := make(map[string][]string)
+	for _, p := range pending {
+		r, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return false, err
+		}
+		var weighted bn254.G1Affine
+		weighted.ScalarMultiplication(&p.signature, r)
+		signatures.AddMixed(&weighted)
+
+		weighted.ScalarMultiplication(&p.hashed, r)
+		sum, ok := hashed[p.signers]
+		if !ok {
+			sum = new(bn254.G1Jac)
+			hashed[p.signers] = sum
+			nonsigners[p.signers] = proofs[p.index].Nonsigners
+		}
+		sum.AddMixed(&weighted)
+	}
+
+	_, _, _, g2 := bn254.Generators()
+	var signature bn254.G1Affine
+	signature.FromJacobian(&signatures)
+	P := []bn254.G1Affine{signature}
+	Q := []bn254.G2Affine{g2}
+	for key, sum := range hashed {
+		var negated bn254.G1Affine
+		negated.FromJacobian(sum)
+		negated.Neg(&negated)
+		P = append(P, negated)
+		Q = append(Q, z.signersPublicKey(nonsigners[key]))
+	}
+	return bn254.PairingCheck(P, Q)
+}
+
+// signerSetKey identifies a set of nonsigners regardless of order
+func signerSetKey(nonsigners []string) string {
+	sorted := append([]string(nil), nonsigners...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// sandboxProofs returns the sandbox's finality proofs of batches 1 to n
+func sandboxProofs(z *Zellular, sandbox *Sandbox, n int) []FinalityProof {
+	var proofs []FinalityProof
+	for i := 1; i <= n; i++ {
+		proofs = append(proofs, *sandbox.markerAt(i).proof(z.AppName))
+	}
+	return proofs
+}
+
+func TestVerifyManyPinpointsBadProof(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	proofs := sandboxProofs(z, sandbox, 3)
+	results, err := z.VerifyMany(proofs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if !result.Valid() {
+			t.Fatalf("proof %d rejected: %s", i+1, result.Reason)
+		}
+	}
+
+	proofs[1].Hash = z.batchHash(`["x"]`)
+	if results, err = z.VerifyMany(proofs); err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Valid() != (i != 1) {
+			t.Fatalf("proof %d valid = %v", i+1, result.Valid())
+		}
+	}
+}
+
+// rotateSandboxKey makes the sandbox operator sign with a new key from batch from on
+func rotateSandboxKey(t *testing.T, z *Zellular, sandbox *Sandbox, from int) {
+	t.Helper()
+	secret := make([]byte, 31)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	sandbox.secret = new(big.Int).SetBytes(secret)
+	_, _, _, g2 := bn254.Generators()
+	var key bn254.G2Affine
+	key.ScalarMultiplication(&g2, sandbox.secret)
+	operator, _ := z.LookupOperator(sandboxOperator)
+	operator.PubkeyG2_X = []string{key.X.A1.String(), key.X.A0.String()}
+	operator.PubkeyG2_Y = []string{key.Y.A1.String(), key.Y.A0.String()}
+	operator.PublicKeyG2 = key
+	if err := z.RecordKeyChange(sandboxOperator, from, operator.PubkeyG2_X, operator.PubkeyG2_Y); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.setOperators(map[string]Operator{operator.ID: operator}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyManyUsesKeysAtIndex(t *testing.T) {
+	z, sandbox := sandboxWith(t, []string{`["a"]`, `["b"]`, `["c"]`})
+	rotateSandboxKey(t, z, sandbox, 3)
+
+	// every proof is signed with the new key, which batch 1 predates
+	proofs := sandboxProofs(z, sandbox, 3)
+	results, err := z.VerifyMany([]FinalityProof{proofs[0], proofs[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Valid() {
+		t.Fatal("proof of batch 1 verified with the key rotated in at batch 3")
+	}
+	if !results[1].Valid() {
+		t.Fatalf("proof of batch 3 rejected: %s", results[1].Reason)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoWAL is returned by Replay when the instance was created without a WAL
+var ErrNoWAL = errors.New("zellular: no write-ahead log configured")
+
+// WAL is an append-only local log of the batches handed to the application.
+// Streams log a batch only after verifying it, and every entry is synced to
+// disk before the batch is released, so state can be rebuilt with Replay
+// after a crash without refetching from the network. An entry torn by a
+// crash during Append is dropped when the log is opened again. Clients of
+// several apps may share a log: entries record the app of the stream that
+// logged them, and a client replays only its own app's entries.
+type WAL struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// walEntry is one line of the log
+type walEntry struct {
+	App     string `json:"app,omitempty"`
+	Index   int    `json:"index"`
+	Hash    string `json:"hash"`
+	Payload string `json:"payload"`
+	Time    int64  `json:"time,omitempty"`
+
+	Proof *FinalityProof `json:"proof,omitempty"`
+}
+
+// OpenWAL opens the log at path, creating it if needed
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncateTorn(path); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &WAL{path: path, file: file}, nil
+}
+
+// truncateTorn cuts the log after its last complete entry, so a partial
+// line left by a crash is not glued to the next one appended
+func truncateTorn(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	var chunk [4096]byte
+	end := info.Size()
+	for offset := end; offset > 0; {
+		n := int64(len(chunk))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := file.ReadAt(chunk[:n], offset); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			if complete := offset + int64(i) + 1; complete != end {
+				return os.Truncate(path, complete)
+			}
+			return nil
+		}
+	}
+	if end > 0 {
+		return os.Truncate(path, 0)
+	}
+	return nil
+}
+
+// WithWAL makes every batch released by a stream go through the log first
+func WithWAL(wal *WAL) Option {
+	return func(z *Zellular) {
+		z.wal = wal
+	}
+}
+
+// Append durably records a batch that belongs to no particular app. Its
+// entry is hashed with the default hasher.
+func (w *WAL) Append(batch Batch) error {
+	return w.record("", hash(batch.Payload), batch)
+}
+
+// record durably records a batch of app whose payload hashes to batchHash
+func (w *WAL) record(app, batchHash string, batch Batch) error {
+	line, err := json.Marshal(walEntry{
+		App:     app,
+		Index:   batch.Index,
+		Hash:    batchHash,
+		Payload: batch.Payload,
+		Time:    time.Now().Unix(),
+		Proof:   batch.Proof,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Replay calls fn for every logged batch with an index of at least from, in
+// log order, whatever its app. Entries are checked against the default
+// hasher; use Zellular.Replay for those logged by a client with its own. A
+// torn final entry is skipped, and entries appended while replaying, by fn
+// or anyone else, are not replayed.
+func (w *WAL) Replay(from int, fn func(Batch) error) error {
+	return w.replay(nil, hash, from, fn)
+}
+
+// replay is Replay restricted to the entries of *app when app is not nil,
+// checking payloads with hashOf. Entries logged without an app belong to
+// every app.
+func (w *WAL) replay(app *string, hashOf func(string) string, from int, fn func(Batch) error) error {
+	log, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	return readLines(log, func(line []byte) error {
+		var entry walEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if entry.Index < from || app != nil && entry.App != "" && entry.App != *app {
+			return nil
+		}
+		if hashOf(entry.Payload) != entry.Hash {
+			return errors.New("zellular: corrupted write-ahead log entry")
+		}
+		return fn(Batch{Index: entry.Index, Payload: entry.Payload, Proof: entry.Proof})
+	})
+}
+
+// snapshot opens the log limited to the entries it holds now, so it can be
+// read without w.mu held
+func (w *WAL) snapshot() (*walSnapshot, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &walSnapshot{Reader: io.LimitReader(file, info.Size()), file: file}, nil
+}
+
+// walSnapshot reads the log up to the size it had when it was opened. The
+// open file keeps the entries even if Compact replaces the log meanwhile.
+type walSnapshot struct {
+	io.Reader
+	file *os.File
+}
+
+// Close closes the snapshot's file
+func (s *walSnapshot) Close() error {
+	return s.file.Close()
+}
+
+// entries calls fn with every complete line of the log, without the newline
+func (w *WAL) entries(fn func(line []byte) error) error {
+	file, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return readLines(file, fn)
+}
+
+// readLines calls fn with every complete line of r, without the newline
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without its newline was torn by a crash
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(line[:len(line)-1]); err != nil {
+			return err
+		}
+	}
+}
+
+// Compact drops the oldest entries not allowed by policy. It reads only the
+// entries' times and sizes, then copies the kept tail of the log to a new
+// file that replaces it.
+func (w *WAL) Compact(policy RetentionPolicy) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var times []time.Time
+	var sizes []int64
+	err := w.entries(func(line []byte) error {
+		var entry struct {
+			Time int64 `json:"time"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		times = append(times, time.Unix(entry.Time, 0))
+		sizes = append(sizes, int64(len(line)+1))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	drop := retainFrom(policy, times, sizes, time.Now())
+	if drop == 0 {
+		return nil
+	}
+	var start, end int64
+	for i, size := range sizes {
+		if i < drop {
+			start += size
+		}
+		end += size
+	}
+
+	if err := w.copyRange(w.path+".compact", start, end); err != nil {
+		os.Remove(w.path + ".compact")
+		return err
+	}
+
+	if err := os.Rename(w.path+".compact", w.path); err != nil {
+		return err
+	}
+	w.file.Close()
+	w.file, err = os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	return err
+}
+
+// copyRange writes bytes [start, end) of the log to a synced file at path
+func (w *WAL) copyRange(path string, start, end int64) error {
+	src, err := os.Open(w.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, io.NewSectionReader(src, start, end-start)); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Close closes the underlying file
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Replay rebuilds application state from the configured WAL starting at
+// index from, with the batches logged by this client's app. Entries are
+// checked with the client's hasher.
+func (z *Zellular) Replay(from int, fn func(Batch) error) error {
+	if z.wal == nil {
+		return ErrNoWAL
+	}
+	return z.wal.replay(&z.AppName, z.batchHash, from, fn)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func replayed(t *testing.T, wal *WAL) []int {
+	t.Helper()
+	var indexes []int
+	if err := wal.Replay(0, func(batch Batch) error {
+		indexes = append(indexes, batch.Index)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return indexes
+}
+
+func TestWALDropsTornEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal")
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err := wal.Append(Batch{Index: i, Payload: `["tx"]`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wal.Close()
+
+	// a crash in the middle of appending the third entry
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"index":3,"hash":"`)
+	file.Close()
+
+	wal, err = OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if got := replayed(t, wal); len(got) != 2 {
+		t.Fatalf("replayed %v after a torn entry", got)
+	}
+	if err := wal.Append(Batch{Index: 3, Payload: `["tx"]`}); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed(t, wal); len(got) != 3 || got[2] != 3 {
+		t.Fatalf("replayed %v after appending past a torn entry", got)
+	}
+}
+
+func TestWALCompactKeepsNewest(t *testing.T) {
+	wal, err := OpenWAL(filepath.Join(t.TempDir(), "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	for i := 1; i <= 5; i++ {
+		if err := wal.Append(Batch{Index: i, Payload: `["tx"]`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wal.Compact(RetentionPolicy{MaxCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.Append(Batch{Index: 6, Payload: `["tx"]`}); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed(t, wal); len(got) != 3 || got[0] != 4 || got[2] != 6 {
+		t.Fatalf("replayed %v after compaction", got)
+	}
+}
+
+func TestWALKeepsAppsApart(t *testing.T) {
+	wal, err := OpenWAL(filepath.Join(t.TempDir(), "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	z := newZellular("a", "http://localhost:6001", 67)
+	WithWAL(wal)(z)
+	tenant := z.Tenant(TenantConfig{Name: "t"})
+	a, b := tenant.Client("a"), tenant.Client("b")
+	if err := wal.record("a", hash(`["a"]`), Batch{Index: 1, Payload: `["a"]`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wal.record("b", hash(`["b"]`), Batch{Index: 1, Payload: `["b"]`}); err != nil {
+		t.Fatal(err)
+	}
+
+	for client, want := range map[*Zellular]string{a: `["a"]`, b: `["b"]`} {
+		var payloads []string
+		if err := client.Replay(0, func(batch Batch) error {
+			payloads = append(payloads, batch.Payload)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(payloads) != 1 || payloads[0] != want {
+			t.Fatalf("app %s replayed %v", client.AppName, payloads)
+		}
+	}
+	if len(replayed(t, wal)) != 2 {
+		t.Fatal("Replay of the log skipped entries")
+	}
+}
+
+func TestWALReplayAllowsAppending(t *testing.T) {
+	wal, err := OpenWAL(filepath.Join(t.TempDir(), "wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	for i := 1; i <= 2; i++ {
+		if err := wal.Append(Batch{Index: i, Payload: `["tx"]`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan []int, 1)
+	go func() {
+		var indexes []int
+		err := wal.Replay(0, func(batch Batch) error {
+			indexes = append(indexes, batch.Index)
+			return wal.Append(Batch{Index: batch.Index + 2, Payload: `["tx"]`})
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- indexes
+	}()
+	select {
+	case got := <-done:
+		if len(got) != 2 || got[1] != 2 {
+			t.Fatalf("replayed %v while appending", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("appending from a replay callback deadlocked")
+	}
+	if got := replayed(t, wal); len(got) != 4 || got[3] != 4 {
+		t.Fatalf("replayed %v after appending during a replay", got)
+	}
+}
+
+func TestWALHashesWithClientHasher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal")
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	z, _ := sandboxWith(t, []string{`["a"]`}, WithHasher(sha256Hasher{}), WithWAL(wal))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := z.Stream(0).Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry walEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Hash != z.batchHash(`["a"]`) {
+		t.Fatalf("entry hash = %s, want the client's %s", entry.Hash, z.batchHash(`["a"]`))
+	}
+	if err := z.Replay(0, func(Batch) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
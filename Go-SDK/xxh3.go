@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// XXH3-128 with seed 0 and the default secret, the xxh128 the nodes and the
+// Python SDK hash batches with. The xxhash module only implements XXH64, so
+// this follows the reference implementation.
+
+const (
+	xxPrime32_1 = 0x9E3779B1
+	xxPrime32_2 = 0x85EBCA77
+	xxPrime32_3 = 0xC2B2AE3D
+	xxPrime64_1 = 0x9E3779B185EBCA87
+	xxPrime64_2 = 0xC2B2AE3D27D4EB4F
+	xxPrime64_3 = 0x165667B19E3779F9
+	xxPrime64_4 = 0x85EBCA77C2B2AE63
+	xxPrime64_5 = 0x27D4EB2F165667C5
+
+	xxh3StripeLen       = 64
+	xxh3MidSizeMax      = 240
+	xxh3BufferSize      = 256
+	xxh3StripesPerBlock = (len(xxh3Secret) - xxh3StripeLen) / 8
+	// offsets into the secret of the last stripe and of the merged accumulators
+	xxh3LastStripe = len(xxh3Secret) - xxh3StripeLen - 7
+	xxh3MergeLow   = 11
+	xxh3MergeHigh  = len(xxh3Secret) - xxh3StripeLen - 11
+)
+
+var xxh3Secret = [192]byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+var xxh3InitialAcc = [8]uint64{
+	xxPrime32_3, xxPrime64_1, xxPrime64_2, xxPrime64_3,
+	xxPrime64_4, xxPrime32_2, xxPrime64_5, xxPrime32_1,
+}
+
+func xxRead32(b []byte, i int) uint32 { return binary.LittleEndian.Uint32(b[i:]) }
+func xxRead64(b []byte, i int) uint64 { return binary.LittleEndian.Uint64(b[i:]) }
+
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= xxPrime64_2
+	h ^= h >> 29
+	h *= xxPrime64_3
+	return h ^ h>>32
+}
+
+func xxh3Avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= 0x165667919E3779F9
+	return h ^ h>>32
+}
+
+func xxh3Fold64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func xxh3Mix16(in []byte, secret int) uint64 {
+	return xxh3Fold64(xxRead64(in, 0)^xxRead64(xxh3Secret[:], secret), xxRead64(in, 8)^xxRead64(xxh3Secret[:], secret+8))
+}
+
+func xxh3Mix32(acc *[2]uint64, a, b []byte, secret int) {
+	acc[0] += xxh3Mix16(a, secret)
+	acc[0] ^= xxRead64(b, 0) + xxRead64(b, 8)
+	acc[1] += xxh3Mix16(b, secret+16)
+	acc[1] ^= xxRead64(a, 0) + xxRead64(a, 8)
+}
+
+// xxh3Sum128 returns the high and low halves of the XXH3-128 of at most
+// xxh3MidSizeMax bytes; longer inputs go through xxh3State
+func xxh3Sum128(in []byte) (hi, lo uint64) {
+	n := len(in)
+	s := xxh3Secret[:]
+	switch {
+	case n == 0:
+		return xxh64Avalanche(xxRead64(s, 80) ^ xxRead64(s, 88)), xxh64Avalanche(xxRead64(s, 64) ^ xxRead64(s, 72))
+	case n <= 3:
+		inLo := uint32(in[0])<<16 | uint32(in[n>>1])<<24 | uint32(in[n-1]) | uint32(n)<<8
+		inHi := bits.RotateLeft32(bits.ReverseBytes32(inLo), 13)
+		return xxh64Avalanche(uint64(inHi) ^ uint64(xxRead32(s, 8)^xxRead32(s, 12))),
+			xxh64Avalanche(uint64(inLo) ^ uint64(xxRead32(s, 0)^xxRead32(s, 4)))
+	case n <= 8:
+		keyed := (uint64(xxRead32(in, 0)) + uint64(xxRead32(in, n-4))<<32) ^ (xxRead64(s, 16) ^ xxRead64(s, 24))
+		hi, lo = bits.Mul64(keyed, xxPrime64_1+uint64(n)<<2)
+		hi += lo << 1
+		lo ^= hi >> 3
+		lo = (lo ^ lo>>35) * 0x9FB21C651E98DF25
+		return xxh3Avalanche(hi), lo ^ lo>>28
+	case n <= 16:
+		inHi := xxRead64(in, n-8)
+		mulHi, mulLo := bits.Mul64(xxRead64(in, 0)^inHi^(xxRead64(s, 32)^xxRead64(s, 40)), xxPrime64_1)
+		mulLo += uint64(n-1) << 54
+		inHi ^= xxRead64(s, 48) ^ xxRead64(s, 56)
+		mulHi += inHi + uint64(uint32(inHi))*(xxPrime32_2-1)
+		mulLo ^= bits.ReverseBytes64(mulHi)
+		hi, lo = bits.Mul64(mulLo, xxPrime64_2)
+		hi += mulHi * xxPrime64_2
+		return xxh3Avalanche(hi), xxh3Avalanche(lo)
+	}
+
+	acc := [2]uint64{uint64(n) * xxPrime64_1, 0}
+	if n <= 128 {
+		if n > 32 {
+			if n > 64 {
+				if n > 96 {
+					xxh3Mix32(&acc, in[48:], in[n-64:], 96)
+				}
+				xxh3Mix32(&acc, in[32:], in[n-48:], 64)
+			}
+			xxh3Mix32(&acc, in[16:], in[n-32:], 32)
+		}
+		xxh3Mix32(&acc, in, in[n-16:], 0)
+	} else {
+		i := 0
+		for ; i < 4; i++ {
+			xxh3Mix32(&acc, in[32*i:], in[32*i+16:], 32*i)
+		}
+		acc[0], acc[1] = xxh3Avalanche(acc[0]), xxh3Avalanche(acc[1])
+		for ; i < n/32; i++ {
+			xxh3Mix32(&acc, in[32*i:], in[32*i+16:], 3+32*(i-4))
+		}
+		xxh3Mix32(&acc, in[n-16:], in[n-32:], 136-17-16)
+	}
+	hi = acc[0]*xxPrime64_1 + acc[1]*xxPrime64_4 + uint64(n)*xxPrime64_2
+	return -xxh3Avalanche(hi), xxh3Avalanche(acc[0] + acc[1])
+}
+
+// xxh3State is a streaming XXH3-128. Inputs up to xxh3MidSizeMax bytes stay
+// in the buffer and are hashed in one shot; longer ones are consumed in
+// stripes keeping the last bytes buffered.
+type xxh3State struct {
+	acc      [8]uint64
+	buf      [xxh3BufferSize]byte
+	buffered int
+	stripes  int
+	total    uint64
+}
+
+func newXXH3() *xxh3State {
+	s := &xxh3State{}
+	s.Reset()
+	return s
+}
+
+func (s *xxh3State) Reset() {
+	s.acc = xxh3InitialAcc
+	s.buffered, s.stripes, s.total = 0, 0, 0
+}
+
+func (s *xxh3State) accumulate(stripe []byte, secret int) {
+	for i := 0; i < 8; i++ {
+		v := xxRead64(stripe, 8*i)
+		k := v ^ xxRead64(xxh3Secret[:], secret+8*i)
+		s.acc[i^1] += v
+		s.acc[i] += uint64(uint32(k)) * (k >> 32)
+	}
+}
+
+func (s *xxh3State) scramble() {
+	for i := range s.acc {
+		a := s.acc[i] ^ s.acc[i]>>47 ^ xxRead64(xxh3Secret[:], len(xxh3Secret)-xxh3StripeLen+8*i)
+		s.acc[i] = a * xxPrime32_1
+	}
+}
+
+// consume accumulates n stripes of in, scrambling at block boundaries
+func (s *xxh3State) consume(in []byte, n int) {
+	for i := 0; i < n; i++ {
+		s.accumulate(in[i*xxh3StripeLen:], s.stripes*8)
+		if s.stripes++; s.stripes == xxh3StripesPerBlock {
+			s.scramble()
+			s.stripes = 0
+		}
+	}
+}
+
+func (s *xxh3State) Write(p []byte) (int, error) {
+	n := len(p)
+	s.total += uint64(n)
+	if s.buffered+n <= xxh3BufferSize {
+		s.buffered += copy(s.buf[s.buffered:], p)
+		return n, nil
+	}
+
+	if s.buffered > 0 {
+		fill := copy(s.buf[s.buffered:], p)
+		p = p[fill:]
+		s.consume(s.buf[:], xxh3BufferSize/xxh3StripeLen)
+		s.buffered = 0
+	}
+	if len(p) > xxh3BufferSize {
+		consumed := 0
+		for len(p)-consumed > xxh3BufferSize {
+			s.consume(p[consumed:], xxh3BufferSize/xxh3StripeLen)
+			consumed += xxh3BufferSize
+		}
+		// the last consumed stripe completes a short final stripe in Sum128
+		copy(s.buf[xxh3BufferSize-xxh3StripeLen:], p[consumed-xxh3StripeLen:consumed])
+		p = p[consumed:]
+	}
+	s.buffered = copy(s.buf[:], p)
+	return n, nil
+}
+
+func (s *xxh3State) WriteString(str string) (int, error) {
+	return s.Write([]byte(str))
+}
+
+// Sum128 returns the high and low halves of the hash of everything written
+func (s *xxh3State) Sum128() (hi, lo uint64) {
+	if s.total <= xxh3MidSizeMax {
+		return xxh3Sum128(s.buf[:s.buffered])
+	}
+
+	d := *s
+	if d.buffered >= xxh3StripeLen {
+		d.consume(d.buf[:], (d.buffered-1)/xxh3StripeLen)
+		d.accumulate(d.buf[d.buffered-xxh3StripeLen:], xxh3LastStripe)
+	} else {
+		var last [xxh3StripeLen]byte
+		catchup := copy(last[:], s.buf[xxh3BufferSize-(xxh3StripeLen-d.buffered):])
+		copy(last[catchup:], s.buf[:s.buffered])
+		d.accumulate(last[:], xxh3LastStripe)
+	}
+	return d.merge(xxh3MergeHigh, ^(s.total * xxPrime64_2)), d.merge(xxh3MergeLow, s.total*xxPrime64_1)
+}
+
+func (s *xxh3State) merge(secret int, start uint64) uint64 {
+	for i := 0; i < 4; i++ {
+		start += xxh3Fold64(s.acc[2*i]^xxRead64(xxh3Secret[:], secret+16*i), s.acc[2*i+1]^xxRead64(xxh3Secret[:], secret+16*i+8))
+	}
+	return xxh3Avalanche(start)
+}
+
+// Sum appends the big-endian high then low halves, the canonical byte order
+// xxh128_hexdigest prints
+func (s *xxh3State) Sum(b []byte) []byte {
+	hi, lo := s.Sum128()
+	b = binary.BigEndian.AppendUint64(b, hi)
+	return binary.BigEndian.AppendUint64(b, lo)
+}